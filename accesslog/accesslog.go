@@ -0,0 +1,182 @@
+package accesslog
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"local/global"
+	"local/rotate"
+)
+
+// 访问日志的记录
+type Entry struct {
+	Method    string
+	Route     string // 匹配的路由，路径参数显示为:参数名
+	Path      string
+	Namespace string // 服务所在的命名空间
+	ServiceID string // 解码后的服务ID
+	Status    int
+	Latency   time.Duration
+	Bytes     int64 // 响应体的字节数
+	IP        string
+}
+
+// 路由的记录规则
+type rule struct {
+	pattern    string
+	level      zerolog.Level
+	sampleRate float64
+}
+
+// 当前的访问日志配置
+var state struct {
+	sync.RWMutex
+	enable     bool
+	logger     zerolog.Logger
+	level      zerolog.Level
+	sampleRate float64
+	rules      []rule
+	file       *rotate.File
+}
+
+// 解析记录级别，disabled表示不记录
+func parseLevel(level string) (zerolog.Level, error) {
+	switch strings.ToLower(level) {
+	case "", "info":
+		return zerolog.InfoLevel, nil
+	case "debug":
+		return zerolog.DebugLevel, nil
+	case "warn":
+		return zerolog.WarnLevel, nil
+	case "error":
+		return zerolog.ErrorLevel, nil
+	case "disabled":
+		return zerolog.Disabled, nil
+	}
+	return zerolog.NoLevel, errors.New("未知的访问日志级别：" + level + "，目前只支持debug|info|warn|error|disabled")
+}
+
+// 限制采样率在0~1之间，为0表示不记录
+func normalizeSampleRate(sampleRate global.Float) float64 {
+	switch {
+	case sampleRate < 0:
+		return 0
+	case sampleRate > 1:
+		return 1
+	}
+	return float64(sampleRate)
+}
+
+// 根据配置初始化访问日志
+func Init() error {
+	return Apply(global.Config())
+}
+
+// 应用访问日志的配置，配置无效或无法打开文件时保留原配置
+func Apply(c *global.ConfigType) error {
+	config := &c.AccessLog
+	level, err := parseLevel(config.Level)
+	if err != nil {
+		return err
+	}
+	rules := make([]rule, len(config.Routes))
+	for k := range config.Routes {
+		if rules[k].level, err = parseLevel(config.Routes[k].Level); err != nil {
+			return err
+		}
+		rules[k].pattern = config.Routes[k].Route
+		// 未设置采样率的路由使用默认的采样率
+		rules[k].sampleRate = normalizeSampleRate(config.SampleRate)
+		if config.Routes[k].SampleRate != nil {
+			rules[k].sampleRate = normalizeSampleRate(*config.Routes[k].SampleRate)
+		}
+	}
+
+	// 未设置文件路径时使用进程的logger
+	logger := log.Logger
+	var file *rotate.File
+	if config.Enable && config.FilePath != "" {
+		if file, err = rotate.Open(config.FilePath, config.FileMode, int64(config.MaxSize)*1024*1024, int(config.MaxBackups)); err != nil {
+			return err
+		}
+		var output io.Writer
+		switch config.Encode {
+		case "", "json":
+			output = file
+		case "console":
+			output = zerolog.ConsoleWriter{Out: file, NoColor: true, TimeFormat: zerolog.TimeFieldFormat}
+		default:
+			_ = file.Close()
+			return errors.New("从配置文件的accessLog.encode中获得了未知的参数，目前只支持json|console")
+		}
+		logger = zerolog.New(output).With().Timestamp().Logger()
+	}
+
+	state.Lock()
+	previous := state.file
+	state.enable = config.Enable
+	state.logger = logger
+	state.level = level
+	state.sampleRate = normalizeSampleRate(config.SampleRate)
+	state.rules = rules
+	state.file = file
+	state.Unlock()
+	if previous != nil {
+		return previous.Close()
+	}
+	return nil
+}
+
+// 记录访问日志，按路由的规则决定级别及采样，5xx错误总是以error级别记录
+func Write(entry Entry) {
+	state.RLock()
+	defer state.RUnlock()
+	if !state.enable {
+		return
+	}
+	level, sampleRate := state.level, state.sampleRate
+	for k := range state.rules {
+		if global.MatchPattern(state.rules[k].pattern, entry.Route) {
+			level, sampleRate = state.rules[k].level, state.rules[k].sampleRate
+			break
+		}
+	}
+	if entry.Status >= 500 {
+		level, sampleRate = zerolog.ErrorLevel, 1
+	}
+	if level == zerolog.Disabled || sampleRate <= 0 || (sampleRate < 1 && rand.Float64() >= sampleRate) {
+		return
+	}
+	event := state.logger.WithLevel(level).
+		Str("method", entry.Method).
+		Str("route", entry.Route).
+		Str("path", entry.Path)
+	if entry.ServiceID != "" {
+		event = event.Str("namespace", entry.Namespace).Str("service_id", entry.ServiceID)
+	}
+	event.Int("status", entry.Status).
+		Dur("latency", entry.Latency).
+		Int64("bytes", entry.Bytes).
+		Str("ip", entry.IP).
+		Msg("access")
+}
+
+// 关闭访问日志文件
+func Close() error {
+	state.Lock()
+	defer state.Unlock()
+	state.enable = false
+	if state.file == nil {
+		return nil
+	}
+	err := state.file.Close()
+	state.file = nil
+	return err
+}
@@ -0,0 +1,123 @@
+package accesslog
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"local/global"
+)
+
+// 使用临时文件应用配置，返回读取已记录日志的函数
+func applyConfig(t *testing.T, setup func(*global.ConfigType)) func() []map[string]interface{} {
+	dir, err := ioutil.TempDir("", "tsing-center-accesslog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	filePath := filepath.Join(dir, "access.log")
+	config := global.DefaultConfig()
+	config.AccessLog.Enable = true
+	config.AccessLog.FilePath = filePath
+	config.AccessLog.FileMode = 0600
+	setup(&config)
+	if err = Apply(&config); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = Close()
+		_ = os.RemoveAll(dir)
+	})
+	return func() (entries []map[string]interface{}) {
+		file, err := os.Open(filePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			entry := make(map[string]interface{})
+			if err = json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				t.Fatal(err)
+			}
+			entries = append(entries, entry)
+		}
+		return
+	}
+}
+
+func sampleRate(value global.Float) *global.Float {
+	return &value
+}
+
+func TestWrite(t *testing.T) {
+	read := applyConfig(t, func(config *global.ConfigType) {
+		config.AccessLog.Routes = []global.AccessLogRoute{
+			{Route: "/v1/services/:serviceID/select", Level: "debug"},
+			{Route: "/health*", Level: "disabled"},
+		}
+	})
+	Write(Entry{Method: "GET", Route: "/v1/services/:serviceID/select", Namespace: "default", ServiceID: "demo", Status: 200})
+	Write(Entry{Method: "GET", Route: "/health/ready", Status: 200})
+	Write(Entry{Method: "GET", Route: "/health/ready", Status: 503})
+	entries := read()
+	if len(entries) != 2 {
+		t.Fatalf("应记录2条日志，实际为%d条", len(entries))
+	}
+	if entries[0]["level"] != "debug" || entries[0]["service_id"] != "demo" {
+		t.Fatalf("应使用路由规则的级别：%v", entries[0])
+	}
+	if entries[1]["level"] != "error" || entries[1]["status"] != float64(503) {
+		t.Fatalf("5xx应总是以error级别记录：%v", entries[1])
+	}
+}
+
+func TestSampleRate(t *testing.T) {
+	read := applyConfig(t, func(config *global.ConfigType) {
+		config.AccessLog.SampleRate = 0
+		config.AccessLog.Routes = []global.AccessLogRoute{
+			{Route: "/v1/services", SampleRate: sampleRate(1)},
+			{Route: "/v1/namespaces"},
+			{Route: "/v1/services/:serviceID", SampleRate: sampleRate(0)},
+		}
+	})
+	for k := 0; k < 10; k++ {
+		Write(Entry{Method: "GET", Route: "/v1/services", Status: 200})
+		Write(Entry{Method: "GET", Route: "/v1/namespaces", Status: 200})
+		Write(Entry{Method: "GET", Route: "/v1/services/:serviceID", Status: 200})
+		Write(Entry{Method: "GET", Route: "/v1/members", Status: 200})
+	}
+	Write(Entry{Method: "GET", Route: "/v1/services/:serviceID", Status: 500})
+	entries := read()
+	if len(entries) != 11 {
+		t.Fatalf("采样率为0的路由不应记录，实际记录了%d条", len(entries))
+	}
+	for k := 0; k < 10; k++ {
+		if entries[k]["route"] != "/v1/services" {
+			t.Fatalf("只有采样率为1的路由应被记录：%v", entries[k])
+		}
+	}
+	if entries[10]["status"] != float64(500) {
+		t.Fatal("5xx不受采样率影响")
+	}
+}
+
+func TestNormalizeSampleRate(t *testing.T) {
+	for value, expected := range map[global.Float]float64{-1: 0, 0: 0, 0.25: 0.25, 1: 1, 2: 1} {
+		if actual := normalizeSampleRate(value); actual != expected {
+			t.Fatalf("采样率%v应为%v，实际为%v", value, expected, actual)
+		}
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	read := applyConfig(t, func(config *global.ConfigType) {
+		config.AccessLog.Level = "disabled"
+	})
+	Write(Entry{Method: "GET", Route: "/v1/services", Status: 200})
+	if entries := read(); len(entries) != 0 {
+		t.Fatalf("级别为disabled时不应记录：%v", entries)
+	}
+}
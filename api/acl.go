@@ -0,0 +1,188 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/dxvgef/tsing"
+
+	"local/engine"
+	"local/global"
+)
+
+// 请求的身份
+type principal struct {
+	name  string           // 身份名称，令牌的accessor_id、bootstrap或cert:证书身份
+	rules []global.ACLRule // 授权规则
+}
+
+// 拥有所有权限的规则，用于引导令牌及未启用ACL时的访问密钥
+var rootRules = []global.ACLRule{{Namespace: "*", Pattern: "*", Access: global.AccessAdmin}}
+
+// 未启用ACL时使用访问密钥验证的身份名称
+const secretPrincipal = "secret"
+
+// 请求上下文中保存身份的key
+type principalKey struct{}
+
+// 当前的访问密钥，重新加载配置文件时更新
+var apiSecret atomic.Value
+
+// 获取当前的访问密钥
+func currentSecret() string {
+	secret, _ := apiSecret.Load().(string)
+	return secret
+}
+
+// 从请求中获取访问密钥或ACL令牌，支持SECRET头信息和Authorization: Bearer
+func requestSecret(req *http.Request) string {
+	if secret := req.Header.Get("SECRET"); secret != "" {
+		return secret
+	}
+	if auth := req.Header.Get("Authorization"); len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:])
+	}
+	return ""
+}
+
+// 验证请求的身份，验证失败返回nil
+// 请求中传入了访问密钥或ACL令牌时以其为准，否则使用已验证的客户端证书的身份
+func authenticate(req *http.Request) *principal {
+	secret := requestSecret(req)
+	if secret == "" {
+		if p := certPrincipal(req); p != nil {
+			return p
+		}
+	}
+	// 未启用ACL时，使用访问密钥验证，通过后拥有所有权限
+	if !global.Config().API.ACL.Enable {
+		if subtle.ConstantTimeCompare(global.StrToBytes(secret), global.StrToBytes(currentSecret())) != 1 {
+			return nil
+		}
+		return &principal{name: secretPrincipal, rules: rootRules}
+	}
+	if secret == "" {
+		return nil
+	}
+	if subtle.ConstantTimeCompare(global.StrToBytes(secret), global.StrToBytes(global.Config().API.ACL.BootstrapToken)) == 1 {
+		return &principal{name: "bootstrap", rules: rootRules}
+	}
+	token := engine.FindToken(secret)
+	if token == nil {
+		return nil
+	}
+	return &principal{name: token.AccessorID, rules: token.Rules}
+}
+
+// 获取已验证的客户端证书的身份，优先使用证书的第一个DNS SAN，没有时使用CN
+func certIdentity(req *http.Request) string {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.PeerCertificates) == 0 {
+		return ""
+	}
+	cert := req.TLS.PeerCertificates[0]
+	if len(cert.DNSNames) > 0 {
+		return cert.DNSNames[0]
+	}
+	return cert.Subject.CommonName
+}
+
+// 使用客户端证书的身份验证请求，没有证书时返回nil
+func certPrincipal(req *http.Request) *principal {
+	identity := certIdentity(req)
+	// 身份中的*会被当作匹配模式，为避免通配符证书获得过大的权限，直接拒绝
+	if identity == "" || strings.Contains(identity, "*") {
+		return nil
+	}
+	return &principal{name: "cert:" + identity, rules: identityRules(identity)}
+}
+
+// 获取客户端证书身份的授权规则
+// 身份为servicePrefix+服务ID时，可以注册、触活和注销默认命名空间中该服务的节点，例如svc-orders对应orders服务
+func identityRules(identity string) (rules []global.ACLRule) {
+	clientAuth := &global.Config().API.HTTPS.ClientAuth
+	for k := range clientAuth.Identities {
+		if clientAuth.Identities[k].Name == identity {
+			rules = append(rules, clientAuth.Identities[k].Rules...)
+		}
+	}
+	if clientAuth.ServicePrefix != "" && len(identity) > len(clientAuth.ServicePrefix) && strings.HasPrefix(identity, clientAuth.ServicePrefix) {
+		rules = append(rules, global.ACLRule{
+			Pattern: identity[len(clientAuth.ServicePrefix):],
+			Access:  global.AccessRegister,
+		})
+	}
+	return
+}
+
+// 将身份写入请求上下文
+func setPrincipal(ctx *tsing.Context, p *principal) {
+	ctx.Request = ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), principalKey{}, p))
+}
+
+// 获取请求的身份
+func getPrincipal(ctx *tsing.Context) *principal {
+	p, _ := ctx.Request.Context().Value(principalKey{}).(*principal)
+	return p
+}
+
+// 判断当前身份能否以指定的访问级别访问请求的命名空间中的服务
+func allowService(ctx *tsing.Context, serviceID, access string) bool {
+	p := getPrincipal(ctx)
+	return p != nil && global.AllowService(p.rules, requestNamespace(ctx), serviceID, access)
+}
+
+// 返回判断当前身份能否读取请求的命名空间中的服务的函数
+func readableServices(ctx *tsing.Context) func(serviceID string) bool {
+	return func(serviceID string) bool {
+		return allowService(ctx, serviceID, global.AccessRead)
+	}
+}
+
+// 返回判断当前身份能否注册节点到请求的命名空间中的服务的函数
+func registrableServices(ctx *tsing.Context) func(serviceID string) bool {
+	return func(serviceID string) bool {
+		return allowService(ctx, serviceID, global.AccessRegister)
+	}
+}
+
+// 输出没有权限的错误
+func forbidden(ctx *tsing.Context) error {
+	if isV1Request(ctx.Request) {
+		return v1Fail(ctx, 403, codeForbidden, "permission denied")
+	}
+	return Status(ctx, 403)
+}
+
+// 要求当前身份对请求的命名空间中路径参数指定的服务拥有指定的访问级别
+func requireService(access string) tsing.Handler {
+	return func(ctx *tsing.Context) error {
+		serviceID := ctx.PathParams.Value("serviceID")
+		// 旧版API的服务ID使用base64编码，解码失败时只有拥有全局权限的身份才能继续，由处理器响应参数错误
+		if !isV1Request(ctx.Request) {
+			var err error
+			if serviceID, err = global.DecodeKey(serviceID); err != nil {
+				serviceID = ""
+			}
+		}
+		if !allowService(ctx, serviceID, access) {
+			ctx.Abort()
+			return forbidden(ctx)
+		}
+		return nil
+	}
+}
+
+// 要求当前身份拥有指定访问级别的全局权限
+func requireGlobal(access string) tsing.Handler {
+	return func(ctx *tsing.Context) error {
+		p := getPrincipal(ctx)
+		if p == nil || !global.AllowGlobal(p.rules, access) {
+			ctx.Abort()
+			return forbidden(ctx)
+		}
+		return nil
+	}
+}
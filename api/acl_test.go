@@ -0,0 +1,130 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"local/global"
+)
+
+// 创建带有客户端证书的请求，verified为false时模拟未通过验证的证书
+func newCertRequest(dnsNames []string, commonName string, verified bool) *http.Request {
+	req := httptest.NewRequest("GET", "/v1/services", nil)
+	cert := &x509.Certificate{DNSNames: dnsNames, Subject: pkix.Name{CommonName: commonName}}
+	req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	if verified {
+		req.TLS.VerifiedChains = [][]*x509.Certificate{{cert}}
+	}
+	return req
+}
+
+// 使用测试用的客户端证书配置
+func useClientAuthConfig(t *testing.T) {
+	previous := global.Config()
+	config := *previous
+	config.API.ACL.Enable = true
+	config.API.HTTPS.ClientAuth.ServicePrefix = "svc-"
+	config.API.HTTPS.ClientAuth.Identities = []global.ClientIdentity{
+		{Name: "gateway", Rules: []global.ACLRule{{Pattern: "*", Access: global.AccessRead}}},
+		{Name: "svc-orders", Rules: []global.ACLRule{{Namespace: "prod", Pattern: "orders", Access: global.AccessRead}}},
+	}
+	global.SetConfig(&config)
+	t.Cleanup(func() { global.SetConfig(previous) })
+}
+
+func TestCertIdentity(t *testing.T) {
+	cases := []struct {
+		name     string
+		req      *http.Request
+		identity string
+	}{
+		{"优先使用第一个DNS SAN", newCertRequest([]string{"svc-orders", "other"}, "cn", true), "svc-orders"},
+		{"没有DNS SAN时使用CN", newCertRequest(nil, "gateway", true), "gateway"},
+		{"未通过验证的证书", newCertRequest([]string{"svc-orders"}, "", false), ""},
+		{"没有TLS连接", httptest.NewRequest("GET", "/v1/services", nil), ""},
+	}
+	for _, c := range cases {
+		if identity := certIdentity(c.req); identity != c.identity {
+			t.Fatalf("%s：身份应为%q，实际为%q", c.name, c.identity, identity)
+		}
+	}
+}
+
+func TestIdentityRules(t *testing.T) {
+	useClientAuthConfig(t)
+	cases := []struct {
+		identity  string
+		namespace string
+		serviceID string
+		access    string
+		allow     bool
+	}{
+		// 服务前缀的身份可以注册默认命名空间中对应服务的节点
+		{"svc-orders", global.DefaultNamespace, "orders", global.AccessRegister, true},
+		{"svc-orders", global.DefaultNamespace, "orders", global.AccessAdmin, false},
+		{"svc-orders", global.DefaultNamespace, "users", global.AccessRegister, false},
+		{"svc-orders", "prod", "orders", global.AccessRegister, false},
+		// 同名的身份规则与服务前缀的规则合并
+		{"svc-orders", "prod", "orders", global.AccessRead, true},
+		{"gateway", global.DefaultNamespace, "users", global.AccessRead, true},
+		{"gateway", global.DefaultNamespace, "users", global.AccessRegister, false},
+		// 只有前缀的身份不对应任何服务
+		{"svc-", global.DefaultNamespace, "", global.AccessRead, false},
+		{"unknown", global.DefaultNamespace, "orders", global.AccessRead, false},
+	}
+	for _, c := range cases {
+		if allow := global.AllowService(identityRules(c.identity), c.namespace, c.serviceID, c.access); allow != c.allow {
+			t.Fatalf("%s以%s访问%s/%s的结果应为%v", c.identity, c.access, c.namespace, c.serviceID, c.allow)
+		}
+	}
+	if len(identityRules("svc-")) != 0 || len(identityRules("unknown")) != 0 {
+		t.Fatal("未配置的身份不应有任何规则")
+	}
+}
+
+func TestCertPrincipal(t *testing.T) {
+	useClientAuthConfig(t)
+	cases := []struct {
+		name string
+		req  *http.Request
+		ok   bool
+	}{
+		{"已验证的证书", newCertRequest([]string{"svc-orders"}, "", true), true},
+		{"通配符证书", newCertRequest([]string{"*.example.com"}, "", true), false},
+		{"通配符CN", newCertRequest(nil, "svc-*", true), false},
+		{"未通过验证的证书", newCertRequest([]string{"svc-orders"}, "", false), false},
+	}
+	for _, c := range cases {
+		p := certPrincipal(c.req)
+		if (p != nil) != c.ok {
+			t.Fatalf("%s：验证结果应为%v", c.name, c.ok)
+		}
+		if p != nil && p.name != "cert:svc-orders" {
+			t.Fatalf("%s：身份名称不正确：%s", c.name, p.name)
+		}
+	}
+}
+
+func TestAuthenticateCert(t *testing.T) {
+	useClientAuthConfig(t)
+
+	// 未传入令牌时使用证书的身份
+	p := authenticate(newCertRequest([]string{"gateway"}, "", true))
+	if p == nil || p.name != "cert:gateway" {
+		t.Fatalf("应使用证书的身份验证：%+v", p)
+	}
+	// 传入令牌时不使用证书的身份，无效的令牌不能通过验证
+	req := newCertRequest([]string{"gateway"}, "", true)
+	req.Header.Set("SECRET", "invalid")
+	if p = authenticate(req); p != nil {
+		t.Fatalf("传入的令牌无效时不应回退到证书的身份：%+v", p)
+	}
+	// 没有证书也没有令牌
+	if p = authenticate(httptest.NewRequest("GET", "/v1/services", nil)); p != nil {
+		t.Fatalf("没有证书及令牌时不应通过验证：%+v", p)
+	}
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/dxvgef/tsing"
+	"github.com/rs/zerolog/log"
+
+	"local/audit"
+	"local/engine"
+	"local/global"
+)
+
+// 记录变更操作的审计日志，before为nil表示新建，after为nil表示删除
+func writeAudit(ctx *tsing.Context, action, serviceID, node string, before, after interface{}) {
+	if !audit.Enabled() {
+		return
+	}
+	record := audit.Record{
+		IP:        remoteIP(ctx.Request),
+		Method:    ctx.Request.Method,
+		Path:      ctx.Request.URL.Path,
+		Action:    action,
+		ServiceID: serviceID,
+		Node:      node,
+		Before:    auditValue(before),
+		After:     auditValue(after),
+	}
+	if serviceID != "" {
+		record.Namespace = requestNamespace(ctx)
+	}
+	if p := getPrincipal(ctx); p != nil {
+		record.Identity = p.name
+	}
+	audit.Write(record)
+}
+
+// 将审计记录中变更前后的值编码成JSON
+func auditValue(value interface{}) json.RawMessage {
+	if value == nil {
+		return nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Err(err).Caller().Send()
+		return nil
+	}
+	return data
+}
+
+// 获取本地的服务配置作为审计记录中变更前的值，服务不存在时返回nil
+func auditService(namespace, serviceID string) interface{} {
+	ci := engine.FindCluster(namespace, serviceID)
+	if ci == nil {
+		return nil
+	}
+	config := ci.Config()
+	return &config
+}
+
+// 获取本地的节点作为审计记录中变更前的值，节点不存在时返回nil
+func auditNode(namespace, serviceID, ip string, port uint16) interface{} {
+	ci := engine.FindCluster(namespace, serviceID)
+	if ci == nil {
+		return nil
+	}
+	node := ci.Find(ip, port)
+	if node.IP == "" {
+		return nil
+	}
+	return &node
+}
+
+// 节点在审计记录中的标识
+func auditNodeID(ip string, port uint16) string {
+	return global.NodeAddress(ip, port)
+}
+
+// 解析审计记录的查询参数
+// service为服务ID，与请求的命名空间一起匹配，since为RFC3339格式的时间或unix时间戳(秒)，limit为最多返回的记录数
+func parseAuditQuery(ctx *tsing.Context) (query audit.Query, err error) {
+	query.ServiceID = ctx.Query("service")
+	query.Namespace = requestNamespace(ctx)
+	if since := ctx.Query("since"); since != "" {
+		if query.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			var sec int64
+			if sec, err = strconv.ParseInt(since, 10, 64); err != nil {
+				return query, errors.New("since参数必须是RFC3339格式的时间或unix时间戳")
+			}
+			query.Since = time.Unix(sec, 0)
+		}
+	}
+	if limit := ctx.Query("limit"); limit != "" {
+		if query.Limit, err = strconv.Atoi(limit); err != nil || query.Limit < 1 {
+			return query, errors.New("limit参数必须是正整数")
+		}
+	}
+	return query, nil
+}
+
+// 判断当前身份能否查询审计记录，指定服务时需要该服务的admin权限，否则需要全局的admin权限
+func allowAudit(ctx *tsing.Context, serviceID string) bool {
+	if serviceID != "" {
+		return allowService(ctx, serviceID, global.AccessAdmin)
+	}
+	p := getPrincipal(ctx)
+	return p != nil && global.AllowGlobal(p.rules, global.AccessAdmin)
+}
+
+type Audit struct{}
+
+// 查询审计记录
+func (self *Audit) Query(ctx *tsing.Context) error {
+	resp := make(map[string]string)
+	query, err := parseAuditQuery(ctx)
+	if err != nil {
+		// 来自客户端的数据，无需记录日志
+		resp["error"] = err.Error()
+		return JSON(ctx, 400, &resp)
+	}
+	if !allowAudit(ctx, query.ServiceID) {
+		return forbidden(ctx)
+	}
+	records, err := audit.Search(query)
+	if err == audit.ErrDisabled {
+		resp["error"] = err.Error()
+		return JSON(ctx, 501, &resp)
+	}
+	if err != nil {
+		return ctx.Caller(err)
+	}
+	if records == nil {
+		records = []audit.Record{}
+	}
+	return JSON(ctx, 200, &records)
+}
+
+type V1Audit struct{}
+
+// 查询审计记录
+func (self *V1Audit) Query(ctx *tsing.Context) error {
+	query, err := parseAuditQuery(ctx)
+	if err != nil {
+		return v1Fail(ctx, 400, codeInvalidParameter, "since must be RFC3339 or unix seconds and limit a positive integer")
+	}
+	if !allowAudit(ctx, query.ServiceID) {
+		return forbidden(ctx)
+	}
+	records, err := audit.Search(query)
+	if err == audit.ErrDisabled {
+		return v1Fail(ctx, 501, codeAuditDisabled, "audit log is disabled")
+	}
+	if err != nil {
+		return ctx.Caller(err)
+	}
+	if records == nil {
+		records = []audit.Record{}
+	}
+	return JSON(ctx, 200, &records)
+}
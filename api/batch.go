@@ -0,0 +1,235 @@
+package api
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/dxvgef/filter/v2"
+	"github.com/dxvgef/tsing"
+
+	"local/audit"
+	"local/engine"
+	"local/global"
+	"local/meta"
+	"local/tag"
+)
+
+// 单次批量请求允许的最大操作数
+const maxBatchItems = 1000
+
+type Batch struct{}
+
+// 批量操作中的单个节点操作
+type batchNodeItem struct {
+	Action    string   `json:"action"` // set|touch|delete
+	ServiceID string   `json:"service_id"`
+	IP        string   `json:"ip"`
+	Port      uint16   `json:"port"`
+	Weight    int      `json:"weight"`
+	TTL       uint     `json:"ttl"`
+	Meta      string   `json:"meta,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	namespace string   // 请求的命名空间
+}
+
+// 单个操作的执行结果
+type batchResult struct {
+	Status int    `json:"status"`
+	Code   string `json:"code,omitempty"` // 错误码，与v1 API的错误码一致
+	Error  string `json:"error,omitempty"`
+}
+
+// 批量创建、重写、触活或删除节点
+func (self *Batch) Nodes(ctx *tsing.Context) error {
+	var (
+		err   error
+		resp  = make(map[string]string)
+		items []batchNodeItem
+	)
+	if err = ctx.UnmarshalJSON(&items); err != nil {
+		// 来自客户端的数据，无需记录日志
+		resp["error"] = "请求数据不是有效的JSON数组"
+		return JSON(ctx, 400, &resp)
+	}
+	if len(items) == 0 {
+		resp["error"] = "请求数据不能为空"
+		return JSON(ctx, 400, &resp)
+	}
+	if len(items) > maxBatchItems {
+		resp["error"] = "单次请求最多允许" + strconv.Itoa(maxBatchItems) + "个操作"
+		return JSON(ctx, 400, &resp)
+	}
+
+	results := applyBatchNodes(ctx, items)
+	return JSON(ctx, 200, &results)
+}
+
+// 执行批量节点操作，返回与入参一一对应的结果，只允许操作当前身份能注册节点的服务
+func applyBatchNodes(ctx *tsing.Context, items []batchNodeItem) []batchResult {
+	var (
+		allow      = registrableServices(ctx)
+		results    = make([]batchResult, len(items))
+		operations []global.NodeOperation
+		index      []int                           // operations中每个操作对应的items下标
+		befores    []interface{}                   // operations中每个操作写入前的节点，用于审计记录
+		pending    = make(map[string]*global.Node) // 本次请求中前面的操作写入(值为nil表示删除)的节点
+	)
+	for k := range items {
+		items[k].namespace = requestNamespace(ctx)
+		if !allow(items[k].ServiceID) {
+			results[k] = batchResult{Status: 403, Code: codeForbidden, Error: "没有权限"}
+			continue
+		}
+		if fail := items[k].normalize(); fail != nil {
+			results[k] = *fail
+			continue
+		}
+		before := items[k].before(pending)
+		operation, fail := items[k].operation(pending)
+		if fail != nil {
+			results[k] = *fail
+			continue
+		}
+		// 无需写入存储器的操作(如未设置TTL的节点触活)
+		if operation == nil {
+			results[k].Status = 204
+			continue
+		}
+		operations = append(operations, *operation)
+		index = append(index, k)
+		befores = append(befores, before)
+	}
+
+	if len(operations) > 0 {
+		errs := requestStorage(ctx).BatchNodes(operations)
+		for k := range errs {
+			if errs[k] != nil {
+				results[index[k]] = batchResult{Status: 500, Code: codeInternal, Error: errs[k].Error()}
+				continue
+			}
+			results[index[k]].Status = 204
+			// 触活只更新生命周期的截止时间，不记录审计日志
+			switch items[index[k]].Action {
+			case "set":
+				writeAudit(ctx, audit.ActionNodeSet, operations[k].ServiceID, auditNodeID(operations[k].Node.IP, operations[k].Node.Port), befores[k], &operations[k].Node)
+			case "delete":
+				writeAudit(ctx, audit.ActionNodeDelete, operations[k].ServiceID, auditNodeID(operations[k].Node.IP, operations[k].Node.Port), befores[k], nil)
+			}
+		}
+	}
+	return results
+}
+
+// 校验并规范化节点的主机，删除及触活操作不受node.allowHostname限制
+func (self *batchNodeItem) normalize() *batchResult {
+	host, err := global.NormalizeNodeHost(self.IP)
+	if err == nil && self.Action == "set" {
+		host, err = nodeHost(self.IP)
+	}
+	if err != nil {
+		return &batchResult{Status: 400, Code: codeInvalidParameter, Error: err.Error()}
+	}
+	self.IP = host
+	return nil
+}
+
+// 节点在本次请求中的key
+func (self *batchNodeItem) key() string {
+	return self.ServiceID + "/" + auditNodeID(self.IP, self.Port)
+}
+
+// 获取操作执行前的节点，包括本次请求中前面的操作写入的节点，节点不存在时返回nil
+func (self *batchNodeItem) before(pending map[string]*global.Node) interface{} {
+	if written, exist := pending[self.key()]; exist {
+		if written == nil {
+			return nil
+		}
+		return written
+	}
+	return auditNode(self.namespace, self.ServiceID, self.IP, self.Port)
+}
+
+// 校验操作并转换成存储器的节点操作，校验失败时返回描述错误的结果
+func (self *batchNodeItem) operation(pending map[string]*global.Node) (*global.NodeOperation, *batchResult) {
+	if err := filter.String(self.ServiceID, "service_id").Require().Error(); err != nil {
+		return nil, &batchResult{Status: 400, Code: codeInvalidParameter, Error: err.Error()}
+	}
+	if self.Port == 0 {
+		return nil, &batchResult{Status: 400, Code: codeInvalidParameter, Error: "port参数不能为0"}
+	}
+	ci := engine.FindCluster(self.namespace, self.ServiceID)
+	if ci == nil {
+		return nil, &batchResult{Status: 404, Code: codeServiceNotFound, Error: "服务不存在"}
+	}
+	key := self.key()
+
+	switch self.Action {
+	case "set":
+		if self.Weight < 0 || self.Weight > math.MaxUint16 {
+			return nil, &batchResult{Status: 400, Code: codeInvalidParameter, Error: "weight参数无效"}
+		}
+		metadata, err := meta.Normalize(global.StrToBytes(self.Meta))
+		if err == nil {
+			err = validateNodeMeta(ci.Config(), metadata)
+		}
+		if err != nil {
+			return nil, &batchResult{Status: 400, Code: codeInvalidParameter, Error: err.Error()}
+		}
+		tags, err := tag.Normalize(self.Tags)
+		if err != nil {
+			return nil, &batchResult{Status: 400, Code: codeInvalidParameter, Error: err.Error()}
+		}
+		node := global.Node{
+			IP:     self.IP,
+			Port:   self.Port,
+			Weight: self.Weight,
+			TTL:    self.TTL,
+			Mete:   metadata,
+			Tags:   tags,
+		}
+		if node.TTL > 0 {
+			node.Expires = time.Now().Add(time.Duration(node.TTL) * time.Second).Unix()
+		}
+		pending[key] = &node
+		return &global.NodeOperation{
+			Action:    global.NodeOperationSet,
+			Namespace: self.namespace,
+			ServiceID: self.ServiceID,
+			Node:      node,
+		}, nil
+	case "touch":
+		node := ci.Find(self.IP, self.Port)
+		if written, exist := pending[key]; exist {
+			if written == nil {
+				return nil, &batchResult{Status: 404, Code: codeNodeNotFound, Error: "节点不存在"}
+			}
+			node = *written
+		}
+		if node.IP == "" {
+			return nil, &batchResult{Status: 404, Code: codeNodeNotFound, Error: "节点不存在"}
+		}
+		if node.TTL == 0 {
+			return nil, nil
+		}
+		node.Expires = time.Now().Add(time.Duration(node.TTL) * time.Second).Unix()
+		return &global.NodeOperation{
+			Action:    global.NodeOperationSet,
+			Namespace: self.namespace,
+			ServiceID: self.ServiceID,
+			Node:      node,
+		}, nil
+	case "delete":
+		pending[key] = nil
+		return &global.NodeOperation{
+			Action:    global.NodeOperationDelete,
+			Namespace: self.namespace,
+			ServiceID: self.ServiceID,
+			Node: global.Node{
+				IP:   self.IP,
+				Port: self.Port,
+			},
+		}, nil
+	}
+	return nil, &batchResult{Status: 400, Code: codeInvalidParameter, Error: "action参数只支持set|touch|delete"}
+}
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dxvgef/tsing"
+
+	"local/engine"
+	"local/global"
+)
+
+// 创建测试用的请求上下文，principal为nil时不设置身份
+func newTestContext(method, target, body string, p *principal) (*tsing.Context, *httptest.ResponseRecorder) {
+	recorder := httptest.NewRecorder()
+	ctx := &tsing.Context{
+		Request:        httptest.NewRequest(method, target, strings.NewReader(body)),
+		ResponseWriter: recorder,
+	}
+	if p != nil {
+		setPrincipal(ctx, p)
+	}
+	return ctx, recorder
+}
+
+// 测试用的存储器，只实现批量写入节点，记录收到的操作
+type fakeStorage struct {
+	global.StorageType
+	operations []global.NodeOperation
+	errs       map[int]error // 按操作的下标返回的错误
+}
+
+func (self *fakeStorage) BatchNodes(operations []global.NodeOperation) []error {
+	self.operations = append(self.operations, operations...)
+	errs := make([]error, len(operations))
+	for k := range operations {
+		errs[k] = self.errs[k]
+	}
+	return errs
+}
+
+// 使用测试用的存储器替换当前的存储器
+func useFakeStorage(t *testing.T, storage *fakeStorage) {
+	previous := global.Storage
+	global.Storage = storage
+	t.Cleanup(func() { global.Storage = previous })
+}
+
+// 创建测试用的服务及节点
+func setTestService(t *testing.T, serviceID string, nodes ...global.Node) {
+	if err := engine.SetService(global.ServiceConfig{ServiceID: serviceID, LoadBalance: "WR"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = engine.DelService(global.DefaultNamespace, serviceID) })
+	for k := range nodes {
+		if err := engine.SetNode(global.DefaultNamespace, serviceID, nodes[k]); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBatchNodeItem(t *testing.T) {
+	setTestService(t, "orders",
+		global.Node{IP: "10.0.0.1", Port: 80, Weight: 1, TTL: 30},
+		global.Node{IP: "10.0.0.2", Port: 80, Weight: 1},
+	)
+	cases := []struct {
+		name   string
+		item   batchNodeItem
+		status int
+		code   string
+		action string // 转换后的存储器操作，为空表示无需写入存储器
+	}{
+		{"写入节点", batchNodeItem{Action: "set", ServiceID: "orders", IP: "10.0.0.3", Port: 80, Weight: 1}, 0, "", global.NodeOperationSet},
+		{"触活设置了TTL的节点", batchNodeItem{Action: "touch", ServiceID: "orders", IP: "10.0.0.1", Port: 80}, 0, "", global.NodeOperationSet},
+		{"触活未设置TTL的节点", batchNodeItem{Action: "touch", ServiceID: "orders", IP: "10.0.0.2", Port: 80}, 0, "", ""},
+		{"删除节点", batchNodeItem{Action: "delete", ServiceID: "orders", IP: "10.0.0.1", Port: 80}, 0, "", global.NodeOperationDelete},
+		{"未知的操作", batchNodeItem{Action: "patch", ServiceID: "orders", IP: "10.0.0.1", Port: 80}, 400, codeInvalidParameter, ""},
+		{"缺少服务ID", batchNodeItem{Action: "set", IP: "10.0.0.1", Port: 80}, 400, codeInvalidParameter, ""},
+		{"端口为0", batchNodeItem{Action: "set", ServiceID: "orders", IP: "10.0.0.1"}, 400, codeInvalidParameter, ""},
+		{"服务不存在", batchNodeItem{Action: "set", ServiceID: "users", IP: "10.0.0.1", Port: 80}, 404, codeServiceNotFound, ""},
+		{"权重超出范围", batchNodeItem{Action: "set", ServiceID: "orders", IP: "10.0.0.1", Port: 80, Weight: 70000}, 400, codeInvalidParameter, ""},
+		{"元信息不是JSON", batchNodeItem{Action: "set", ServiceID: "orders", IP: "10.0.0.1", Port: 80, Meta: "{"}, 400, codeInvalidParameter, ""},
+		{"触活不存在的节点", batchNodeItem{Action: "touch", ServiceID: "orders", IP: "10.0.0.9", Port: 80}, 404, codeNodeNotFound, ""},
+	}
+	for _, c := range cases {
+		item := c.item
+		item.namespace = global.DefaultNamespace
+		operation, fail := item.operation(make(map[string]*global.Node))
+		switch {
+		case c.status != 0:
+			if fail == nil || fail.Status != c.status || fail.Code != c.code {
+				t.Fatalf("%s：应返回%d %s，实际为%+v", c.name, c.status, c.code, fail)
+			}
+		case fail != nil:
+			t.Fatalf("%s：不应返回错误：%+v", c.name, fail)
+		case c.action == "":
+			if operation != nil {
+				t.Fatalf("%s：不应写入存储器", c.name)
+			}
+		case operation == nil || operation.Action != c.action || operation.Node.IP != item.IP:
+			t.Fatalf("%s：存储器操作不正确：%+v", c.name, operation)
+		}
+	}
+}
+
+func TestBatchNodeItemNormalize(t *testing.T) {
+	cases := []struct {
+		item batchNodeItem
+		ip   string
+		ok   bool
+	}{
+		{batchNodeItem{Action: "set", IP: "10.0.0.1"}, "10.0.0.1", true},
+		{batchNodeItem{Action: "set", IP: "0:0:0:0:0:0:0:1"}, "::1", true},
+		{batchNodeItem{Action: "set", IP: "node.local"}, "", false},
+		// 删除及触活不受node.allowHostname限制，以便清理已注册的主机名节点
+		{batchNodeItem{Action: "delete", IP: "node.local"}, "node.local", true},
+		{batchNodeItem{Action: "touch", IP: "node.local"}, "node.local", true},
+		{batchNodeItem{Action: "delete", IP: ""}, "", false},
+	}
+	for _, c := range cases {
+		item := c.item
+		fail := item.normalize()
+		if (fail == nil) != c.ok {
+			t.Fatalf("%s %q：校验结果应为%v，实际为%+v", c.item.Action, c.item.IP, c.ok, fail)
+		}
+		if c.ok && item.IP != c.ip {
+			t.Fatalf("%q应规范化为%q，实际为%q", c.item.IP, c.ip, item.IP)
+		}
+	}
+}
+
+func TestApplyBatchNodes(t *testing.T) {
+	setTestService(t, "orders", global.Node{IP: "10.0.0.1", Port: 80, Weight: 1, TTL: 30})
+	setTestService(t, "users")
+	storage := &fakeStorage{errs: map[int]error{3: errors.New("storage failed")}}
+	useFakeStorage(t, storage)
+
+	items := []batchNodeItem{
+		{Action: "set", ServiceID: "orders", IP: "10.0.0.2", Port: 80, Weight: 1, TTL: 10},
+		// 触活本次请求中前面写入的节点
+		{Action: "touch", ServiceID: "orders", IP: "10.0.0.2", Port: 80},
+		{Action: "delete", ServiceID: "orders", IP: "10.0.0.1", Port: 80},
+		// 本次请求中前面已删除的节点
+		{Action: "touch", ServiceID: "orders", IP: "10.0.0.1", Port: 80},
+		{Action: "set", ServiceID: "orders", IP: "10.0.0.3", Port: 80, Weight: 1},
+		// 没有权限的服务
+		{Action: "set", ServiceID: "users", IP: "10.0.0.4", Port: 80, Weight: 1},
+	}
+	ctx, _ := newTestContext("POST", "/batch/nodes", "", &principal{rules: []global.ACLRule{
+		{Namespace: global.DefaultNamespace, Pattern: "orders", Access: global.AccessRegister},
+	}})
+	results := applyBatchNodes(ctx, items)
+
+	statuses := []int{204, 204, 204, 404, 500, 403}
+	for k := range statuses {
+		if results[k].Status != statuses[k] {
+			t.Fatalf("第%d个操作的状态码应为%d，实际为%+v", k, statuses[k], results[k])
+		}
+	}
+	if results[3].Code != codeNodeNotFound || results[4].Code != codeInternal || results[5].Code != codeForbidden {
+		t.Fatalf("错误码不正确：%+v", results)
+	}
+	// 校验失败及没有权限的操作不写入存储器
+	if len(storage.operations) != 4 {
+		t.Fatalf("应写入4个操作，实际为%d", len(storage.operations))
+	}
+	if touched := storage.operations[1].Node; touched.TTL != 10 || touched.Expires == 0 {
+		t.Fatalf("触活应基于本次请求中前面写入的节点：%+v", touched)
+	}
+}
+
+func TestBatchNodes(t *testing.T) {
+	setTestService(t, "orders")
+	useFakeStorage(t, &fakeStorage{})
+
+	tooMany := make([]batchNodeItem, maxBatchItems+1)
+	data, err := json.Marshal(tooMany)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		body   string
+		status int
+	}{
+		{`[{"action":"set","service_id":"orders","ip":"10.0.0.1","port":80,"weight":1}]`, 200},
+		{`{"action":"set"}`, 400},
+		{`[]`, 400},
+		{string(data), 400},
+	}
+	var handler Batch
+	for _, c := range cases {
+		ctx, recorder := newTestContext("POST", "/batch/nodes", c.body, &principal{rules: rootRules})
+		if err = handler.Nodes(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if recorder.Code != c.status {
+			t.Fatalf("请求数据%.40s的状态码应为%d，实际为%d", c.body, c.status, recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/dxvgef/filter/v2"
+	"github.com/dxvgef/tsing"
+
+	"local/global"
+)
+
+const (
+	indexHeader     = "X-Tsing-Index" // 响应修改索引的头信息
+	defaultWaitTime = 5 * time.Minute // 阻塞查询的默认等待时间
+	maxWaitTime     = 10 * time.Minute
+)
+
+// 阻塞查询参数
+type blockingQuery struct {
+	index uint64        // 客户端已知的修改索引，为0表示不阻塞
+	wait  time.Duration // 最长等待时间
+}
+
+// 从请求中解析阻塞查询参数，?index=N&wait=30s
+func parseBlockingQuery(ctx *tsing.Context) (query blockingQuery, err error) {
+	if err = filter.Batch(
+		filter.String(ctx.Query("index"), "index").IsDigit().Set(&query.index),
+	); err != nil {
+		return
+	}
+	if query.index == 0 {
+		return
+	}
+	query.wait = defaultWaitTime
+	if value := ctx.Query("wait"); value != "" {
+		if query.wait, err = time.ParseDuration(value); err != nil || query.wait <= 0 {
+			err = errors.New("wait参数无效")
+			return
+		}
+		if query.wait > maxWaitTime {
+			query.wait = maxWaitTime
+		}
+	}
+	// 响应必须在写超时之前完成，预留1秒用于输出数据
+	if global.Config().API.WriteTimeout > 0 && query.wait > global.Config().API.WriteTimeout-time.Second {
+		query.wait = global.Config().API.WriteTimeout - time.Second
+	}
+	return
+}
+
+// 输出修改索引的头信息
+func setIndexHeader(ctx *tsing.Context, index uint64) {
+	ctx.ResponseWriter.Header().Set(indexHeader, strconv.FormatUint(index, 10))
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dxvgef/tsing"
+
+	"local/global"
+)
+
+// 写操作的前置条件，基于存储器中的修订版本号实现乐观锁
+// 可通过If-Match头信息或cas参数传入，值为ETag中的修订版本号
+type precondition struct {
+	revision int64 // 期望的修订版本号，值为global.AnyRevision表示未指定
+	header   bool  // 是否来自If-Match头信息
+}
+
+// 解析请求中的前置条件
+func parsePrecondition(ctx *tsing.Context) (result precondition, err error) {
+	result.revision = global.AnyRevision
+	ifMatch := strings.TrimSpace(ctx.Request.Header.Get("If-Match"))
+	cas := ctx.Query("cas")
+	if ifMatch != "" && cas != "" {
+		return result, errors.New("If-Match和cas参数不能同时使用")
+	}
+	if ifMatch != "" {
+		result.header = true
+		if ifMatch == "*" {
+			result.revision = global.ExistsRevision
+			return
+		}
+		// 只支持单个强校验的ETag
+		if len(ifMatch) < 3 || ifMatch[0] != '"' || ifMatch[len(ifMatch)-1] != '"' {
+			return result, errors.New("If-Match必须是单个ETag或*")
+		}
+		ifMatch = ifMatch[1 : len(ifMatch)-1]
+		if result.revision, err = strconv.ParseInt(ifMatch, 10, 64); err != nil || result.revision < 0 {
+			return result, errors.New("If-Match必须是单个ETag或*")
+		}
+		return
+	}
+	if cas != "" {
+		if result.revision, err = strconv.ParseInt(cas, 10, 64); err != nil || result.revision < 0 {
+			return result, errors.New("cas参数必须是非负整数")
+		}
+	}
+	return
+}
+
+// 修订版本号不一致时的HTTP状态码，If-Match头信息响应412，cas参数响应409
+func (self precondition) status() int {
+	if self.header {
+		return http.StatusPreconditionFailed
+	}
+	return http.StatusConflict
+}
+
+// 输出修订版本号对应的ETag
+func setETag(ctx *tsing.Context, revision int64) {
+	if revision > 0 {
+		ctx.ResponseWriter.Header().Set("ETag", `"`+strconv.FormatInt(revision, 10)+`"`)
+	}
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/dxvgef/tsing"
+
+	"local/global"
+)
+
+func TestParsePrecondition(t *testing.T) {
+	cases := []struct {
+		ifMatch  string
+		cas      string
+		revision int64
+		header   bool
+		ok       bool
+	}{
+		{"", "", global.AnyRevision, false, true},
+		{`"12"`, "", 12, true, true},
+		{` "12" `, "", 12, true, true},
+		{`"0"`, "", 0, true, true},
+		{"*", "", global.ExistsRevision, true, true},
+		{"", "12", 12, false, true},
+		{"", "0", 0, false, true},
+		{`"12"`, "12", 0, false, false},
+		{"12", "", 0, false, false},
+		{`W/"12"`, "", 0, false, false},
+		{`"12", "13"`, "", 0, false, false},
+		{`""`, "", 0, false, false},
+		{`"-1"`, "", 0, false, false},
+		{`"abc"`, "", 0, false, false},
+		{"", "-1", 0, false, false},
+		{"", "abc", 0, false, false},
+	}
+	for _, c := range cases {
+		target := "/v1/services/orders"
+		if c.cas != "" {
+			target += "?cas=" + c.cas
+		}
+		ctx, _ := newTestContext("PUT", target, "", nil)
+		if c.ifMatch != "" {
+			ctx.Request.Header.Set("If-Match", c.ifMatch)
+		}
+		result, err := parsePrecondition(ctx)
+		if (err == nil) != c.ok {
+			t.Fatalf("If-Match=%q cas=%q的解析结果应为%v，实际为%v", c.ifMatch, c.cas, c.ok, err)
+		}
+		if c.ok && (result.revision != c.revision || result.header != c.header) {
+			t.Fatalf("If-Match=%q cas=%q应解析为%d %v，实际为%+v", c.ifMatch, c.cas, c.revision, c.header, result)
+		}
+	}
+}
+
+func TestPreconditionStatus(t *testing.T) {
+	if status := (precondition{revision: 1, header: true}).status(); status != http.StatusPreconditionFailed {
+		t.Fatalf("If-Match不一致时应响应412，实际为%d", status)
+	}
+	if status := (precondition{revision: 1}).status(); status != http.StatusConflict {
+		t.Fatalf("cas参数不一致时应响应409，实际为%d", status)
+	}
+}
+
+func TestSetETag(t *testing.T) {
+	cases := []struct {
+		revision int64
+		etag     string
+	}{
+		{12, `"12"`},
+		{0, ""},
+		{global.AnyRevision, ""},
+	}
+	for _, c := range cases {
+		ctx, recorder := newTestContext("GET", "/v1/services/orders", "", nil)
+		setETag(ctx, c.revision)
+		if etag := recorder.Header().Get("ETag"); etag != c.etag {
+			t.Fatalf("修订版本号%d的ETag应为%q，实际为%q", c.revision, c.etag, etag)
+		}
+	}
+
+	// 输出的ETag可以原样作为If-Match传入
+	ctx, recorder := newTestContext("GET", "/v1/services/orders", "", nil)
+	setETag(ctx, 12)
+	ctx, _ = newTestContext("PUT", "/v1/services/orders", "", nil)
+	ctx.Request.Header.Set("If-Match", recorder.Header().Get("ETag"))
+	if result, err := parsePrecondition(ctx); err != nil || result.revision != 12 {
+		t.Fatalf("ETag应能作为If-Match传入：%+v %v", result, err)
+	}
+}
+
+// 测试用的存储器，按修订版本号比较后保存服务
+type casStorage struct {
+	fakeStorage
+	revision int64 // 服务当前的修订版本号，为0表示服务不存在
+}
+
+func (self *casStorage) SaveServiceCAS(_ global.ServiceConfig, revision int64) (int64, error) {
+	switch {
+	case revision == global.AnyRevision:
+	case revision == global.ExistsRevision && self.revision > 0:
+	case revision != self.revision:
+		return 0, global.ErrRevisionMismatch
+	}
+	self.revision++
+	return self.revision, nil
+}
+
+func TestV1ServicePutPrecondition(t *testing.T) {
+	cases := []struct {
+		ifMatch string
+		cas     string
+		status  int
+		code    string
+	}{
+		{"", "", 200, ""},
+		{`"5"`, "", 200, ""},
+		{"*", "", 200, ""},
+		{"", "5", 200, ""},
+		{`"4"`, "", 412, codeRevisionMismatch},
+		{`"0"`, "", 412, codeRevisionMismatch},
+		{"", "4", 409, codeRevisionMismatch},
+		// cas=0表示只在服务不存在时创建
+		{"", "0", 409, codeServiceExists},
+		{`"5"`, "5", 400, codeInvalidParameter},
+	}
+	var handler V1Service
+	for _, c := range cases {
+		storage := &casStorage{revision: 5}
+		previous := global.Storage
+		global.Storage = storage
+
+		target := "/v1/services/orders"
+		if c.cas != "" {
+			target += "?cas=" + c.cas
+		}
+		ctx, recorder := newTestContext("PUT", target, `{"load_balance":"WR"}`, &principal{rules: rootRules})
+		ctx.PathParams = tsing.PathParams{{Key: "serviceID", Value: "orders"}}
+		if c.ifMatch != "" {
+			ctx.Request.Header.Set("If-Match", c.ifMatch)
+		}
+		err := handler.Put(ctx)
+		global.Storage = previous
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if recorder.Code != c.status {
+			t.Fatalf("If-Match=%q cas=%q的状态码应为%d，实际为%d", c.ifMatch, c.cas, c.status, recorder.Code)
+		}
+		if c.code != "" {
+			if body := decodeV1Error(t, recorder); body.Code != c.code {
+				t.Fatalf("If-Match=%q cas=%q的错误码应为%s，实际为%s", c.ifMatch, c.cas, c.code, body.Code)
+			}
+			continue
+		}
+		if etag := recorder.Header().Get("ETag"); etag != `"6"` {
+			t.Fatalf("保存成功后应输出新的ETag，实际为%q", etag)
+		}
+	}
+}
@@ -2,14 +2,18 @@ package api
 
 import (
 	"github.com/dxvgef/tsing"
-
-	"local/global"
 )
 
+// 验证访问密钥或ACL令牌，并将请求的身份写入上下文
 func checkSecretFromHeader(ctx *tsing.Context) error {
-	if ctx.Request.Header.Get("SECRET") != global.Config.API.Secret {
+	p := authenticate(ctx.Request)
+	if p == nil {
 		ctx.Abort()
+		if isV1Request(ctx.Request) {
+			return v1Fail(ctx, 401, codeUnauthorized, "missing or invalid SECRET header or bearer token")
+		}
 		return Status(ctx, 401)
 	}
+	setPrincipal(ctx, p)
 	return nil
 }
@@ -1,12 +1,43 @@
 package api
 
 import (
-	"strings"
+	"errors"
+	"net"
+	"net/http"
 
 	"github.com/dxvgef/tsing"
+
+	"local/global"
+	"local/storage"
 )
 
 // 用于客户端获取IP地址
 func GetIP(ctx *tsing.Context) error {
-	return String(ctx, 200, ctx.Request.RemoteAddr[:strings.Index(ctx.Request.RemoteAddr, ":")])
+	return String(ctx, 200, remoteIP(ctx.Request))
+}
+
+// 获取客户端的IP地址
+func remoteIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// 获取绑定了请求上下文的存储器，存储器操作的追踪span作为请求span的子span
+func requestStorage(ctx *tsing.Context) global.StorageType {
+	return storage.WithContext(global.Storage, ctx.Request.Context())
+}
+
+// 校验并规范化注册节点时传入的主机，未启用node.allowHostname时只允许IP地址
+func nodeHost(host string) (string, error) {
+	normalized, err := global.NormalizeNodeHost(host)
+	if err != nil {
+		return "", err
+	}
+	if !global.Config().Node.AllowHostname && net.ParseIP(normalized) == nil {
+		return "", errors.New("ip参数必须是IP地址，以主机名注册节点须启用node.allowHostname")
+	}
+	return normalized, nil
 }
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"github.com/dxvgef/tsing"
+
+	"local/audit"
+	"local/global"
+)
+
+// 重新加载配置文件的函数，由main设置
+// 返回已在运行时生效及需要重启进程才能生效的配置项，配置无效时返回错误并保留原配置
+var ConfigReloader func() (applied, restartRequired []string, err error)
+
+// 重新加载配置文件的结果
+type configReloadResult struct {
+	Applied         []string `json:"applied"`          // 已生效的配置项
+	RestartRequired []string `json:"restart_required"` // 有变化但需要重启进程才能生效的配置项
+}
+
+// 应用可在运行时生效的API配置
+func ApplyConfig(config *global.ConfigType) {
+	apiSecret.Store(config.API.Secret)
+	initRateLimit(config)
+}
+
+type V1Config struct{}
+
+// 重新加载配置文件，只对当前实例生效
+func (self *V1Config) Reload(ctx *tsing.Context) error {
+	if ConfigReloader == nil {
+		return v1Fail(ctx, 500, codeInternal, "config reload is not available")
+	}
+	applied, restartRequired, err := ConfigReloader()
+	if err != nil {
+		return v1Fail(ctx, 400, codeInvalidConfig, err.Error())
+	}
+	result := configReloadResult{
+		Applied:         make([]string, 0, len(applied)),
+		RestartRequired: make([]string, 0, len(restartRequired)),
+	}
+	result.Applied = append(result.Applied, applied...)
+	result.RestartRequired = append(result.RestartRequired, restartRequired...)
+	writeAudit(ctx, audit.ActionConfigReload, "", "", nil, &result)
+	return JSON(ctx, 200, &result)
+}
@@ -4,34 +4,31 @@ import (
 	"github.com/dxvgef/tsing"
 	"github.com/rs/zerolog/log"
 
+	"local/audit"
+	"local/engine"
 	"local/global"
 )
 
 type Data struct {
-	Services []global.ServiceConfig   `json:"services,omitempty"`
-	Nodes    map[string][]global.Node `json:"nodes,omitempty"`
+	Namespace string                   `json:"namespace"`
+	Services  []global.ServiceConfig   `json:"services,omitempty"`
+	Nodes     map[string][]global.Node `json:"nodes,omitempty"`
 }
 
 func (self *Data) OutputJSON(ctx *tsing.Context) error {
-	var data Data
-	global.Services.Range(func(_, value interface{}) bool {
-		v, ok := value.(global.Cluster)
-		if !ok {
-			log.Error().Caller().Msg("类型断言失败")
-			return false
-		}
-		config := v.Config()
-		data.Services = append(data.Services, config)
-		if data.Nodes == nil {
-			data.Nodes = map[string][]global.Node{}
-		}
-		nodes := v.Nodes()
-		for k := range nodes {
-			data.Nodes[config.ServiceID] = append(data.Nodes[config.ServiceID], nodes[k])
-		}
-		return true
-	})
-
+	resp := make(map[string]string)
+	query, err := parseBlockingQuery(ctx)
+	if err != nil {
+		// 来自客户端的数据，无需记录日志
+		resp["error"] = err.Error()
+		return JSON(ctx, 400, &resp)
+	}
+	if query.index > 0 {
+		setIndexHeader(ctx, engine.WaitIndex(ctx.Request.Context(), query.index, query.wait))
+	} else {
+		setIndexHeader(ctx, engine.Index())
+	}
+	data := localData(requestNamespace(ctx), readableServices(ctx))
 	bs, err := data.MarshalJSON()
 	if err != nil {
 		log.Err(err).Caller().Send()
@@ -49,29 +46,57 @@ func (self *Data) OutputJSON(ctx *tsing.Context) error {
 
 func (*Data) LoadAll(ctx *tsing.Context) error {
 	resp := make(map[string]string)
-	if err := loadAll(); err != nil {
+	if err := loadAll(ctx); err != nil {
 		log.Err(err).Caller().Send()
 		resp["error"] = err.Error()
 		return JSON(ctx, 500, &resp)
 	}
+	writeAudit(ctx, audit.ActionDataLoad, "", "", nil, nil)
 	return Status(ctx, 204)
 }
 func (*Data) SaveAll(ctx *tsing.Context) error {
 	resp := make(map[string]string)
-	if err := saveAll(); err != nil {
+	if err := saveAll(ctx); err != nil {
 		log.Err(err).Caller().Send()
 		resp["error"] = err.Error()
 		return JSON(ctx, 500, &resp)
 	}
+	writeAudit(ctx, audit.ActionDataSave, "", "", nil, nil)
 	return Status(ctx, 204)
 }
 
+// 收集命名空间中的本地数据，match为nil时收集所有服务，否则只收集match返回true的服务
+func localData(namespace string, match func(serviceID string) bool) (data Data) {
+	data.Namespace = namespace
+	global.Services.Range(func(_, value interface{}) bool {
+		v, ok := value.(global.Cluster)
+		if !ok {
+			log.Error().Caller().Msg("类型断言失败")
+			return false
+		}
+		config := v.Config()
+		if config.Namespace != namespace || (match != nil && !match(config.ServiceID)) {
+			return true
+		}
+		data.Services = append(data.Services, config)
+		if data.Nodes == nil {
+			data.Nodes = map[string][]global.Node{}
+		}
+		nodes := v.Nodes()
+		for k := range nodes {
+			data.Nodes[config.ServiceID] = append(data.Nodes[config.ServiceID], nodes[k])
+		}
+		return true
+	})
+	return
+}
+
 // 加载所有数据
-func loadAll() (err error) {
-	return global.Storage.LoadAll()
+func loadAll(ctx *tsing.Context) (err error) {
+	return requestStorage(ctx).LoadAll()
 }
 
 // 保存所有数据
-func saveAll() (err error) {
-	return global.Storage.SaveAll()
+func saveAll(ctx *tsing.Context) (err error) {
+	return requestStorage(ctx).SaveAll()
 }
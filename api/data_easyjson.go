@@ -37,6 +37,8 @@ func easyjson794297d0DecodeLocalApi(in *jlexer.Lexer, out *Data) {
 			continue
 		}
 		switch key {
+		case "namespace":
+			out.Namespace = string(in.String())
 		case "services":
 			if in.IsNull() {
 				in.Skip()
@@ -115,10 +117,14 @@ func easyjson794297d0EncodeLocalApi(out *jwriter.Writer, in Data) {
 	out.RawByte('{')
 	first := true
 	_ = first
+	{
+		const prefix string = ",\"namespace\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Namespace))
+	}
 	if len(in.Services) != 0 {
 		const prefix string = ",\"services\":"
-		first = false
-		out.RawString(prefix[1:])
+		out.RawString(prefix)
 		{
 			out.RawByte('[')
 			for v4, v5 := range in.Services {
@@ -132,12 +138,7 @@ func easyjson794297d0EncodeLocalApi(out *jwriter.Writer, in Data) {
 	}
 	if len(in.Nodes) != 0 {
 		const prefix string = ",\"nodes\":"
-		if first {
-			first = false
-			out.RawString(prefix[1:])
-		} else {
-			out.RawString(prefix)
-		}
+		out.RawString(prefix)
 		{
 			out.RawByte('{')
 			v6First := true
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"encoding/json"
 	"net/http"
 	"strconv"
 
@@ -12,8 +13,6 @@ import (
 
 // 事件处理器
 func EventHandler(event tsing.Event) {
-	event.ResponseWriter.WriteHeader(event.Status)
-
 	switch event.Status {
 	case 404:
 		log.Error().Int("status", event.Status).
@@ -36,7 +35,35 @@ func EventHandler(event tsing.Event) {
 		e.Send()
 	}
 
+	if isV1Request(event.Request) {
+		writeV1Event(event)
+		return
+	}
+	event.ResponseWriter.WriteHeader(event.Status)
 	if _, err := event.ResponseWriter.Write(global.StrToBytes(event.Message.Error())); err != nil {
 		log.Err(err).Caller().Send()
 	}
 }
+
+// 以v1 API的错误格式输出事件，不向客户端暴露内部错误信息
+func writeV1Event(event tsing.Event) {
+	var body v1Error
+	switch event.Status {
+	case 404:
+		body = v1Error{Code: codeNotFound, Message: "route not found"}
+	case 405:
+		body = v1Error{Code: codeMethodNotAllowed, Message: "method not allowed"}
+	default:
+		body = v1Error{Code: codeInternal, Message: http.StatusText(event.Status)}
+	}
+	bs, err := json.Marshal(map[string]v1Error{"error": body})
+	if err != nil {
+		log.Err(err).Caller().Send()
+		return
+	}
+	event.ResponseWriter.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	event.ResponseWriter.WriteHeader(event.Status)
+	if _, err = event.ResponseWriter.Write(bs); err != nil {
+		log.Err(err).Caller().Send()
+	}
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"github.com/dxvgef/tsing"
+
+	"local/federation"
+	"local/global"
+)
+
+// 响应节点来源数据中心的头信息，节点来自远端集群时输出
+const datacenterHeader = "X-Tsing-Datacenter"
+
+// 解析?datacenter=参数，返回远端数据中心的名称，为空或当前数据中心时返回空字符串
+// 未配置的数据中心已输出错误，ok为false
+func requestDatacenter(ctx *tsing.Context) (datacenter string, ok bool) {
+	datacenter = ctx.Query("datacenter")
+	if datacenter == "" || datacenter == global.Config().Federation.Datacenter {
+		return "", true
+	}
+	if !federation.IsRemote(datacenter) {
+		return "", false
+	}
+	return datacenter, true
+}
+
+// 本地服务没有可用节点时，按配置从远端数据中心导入的同一命名空间的服务中选取节点，返回节点的来源数据中心
+func selectFallback(namespace, serviceID string, count int, exclude func(global.Node) bool) (string, []global.Node) {
+	if !global.Config().Federation.Fallback {
+		return "", nil
+	}
+	return federation.SelectN(namespace, serviceID, count, exclude)
+}
+
+type V1Federation struct{}
+
+// 获取所有远端集群的同步状态
+func (self *V1Federation) Get(ctx *tsing.Context) error {
+	status := struct {
+		Datacenter string                    `json:"datacenter"`
+		Fallback   bool                      `json:"fallback"`
+		Remotes    []federation.RemoteStatus `json:"remotes"`
+	}{
+		Datacenter: global.Config().Federation.Datacenter,
+		Fallback:   global.Config().Federation.Fallback,
+		Remotes:    federation.Status(),
+	}
+	return JSON(ctx, 200, &status)
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"github.com/dxvgef/tsing"
+
+	"local/engine"
+	"local/global"
+	"local/meta"
+	"local/tag"
+)
+
+// 选取及列出节点时的过滤条件
+type nodeFilter struct {
+	tags     tag.Filter
+	metadata meta.Filter
+}
+
+// 从请求中解析节点的过滤条件，?tag=按标签过滤，?meta=key:value按元信息的值过滤，?meta=key按元信息的键是否存在过滤
+// 解析失败时返回出错的参数名
+func requestNodeFilter(ctx *tsing.Context) (filter nodeFilter, field string, err error) {
+	if filter.tags, err = requestTagFilter(ctx); err != nil {
+		return filter, "tag", err
+	}
+	if filter.metadata, err = meta.ParseFilter(ctx.QueryParams()["meta"]); err != nil {
+		return filter, "meta", err
+	}
+	return filter, "", nil
+}
+
+// 本地节点的匹配函数，标签使用索引匹配
+func (self nodeFilter) local(namespace, serviceID string) func(global.Node) bool {
+	matchTags := engine.MatchNodes(namespace, serviceID, self.tags)
+	return func(node global.Node) bool {
+		return matchTags(node) && self.metadata.Match(node.Mete)
+	}
+}
+
+// 远端导入的节点没有标签索引，按节点的标签逐个匹配
+func (self nodeFilter) remote() func(global.Node) bool {
+	return func(node global.Node) bool {
+		return self.tags.Match(node.Tags) && self.metadata.Match(node.Mete)
+	}
+}
+
+// 合并匹配函数与排除的节点，用作SelectN的exclude参数
+func excludeUnmatched(match func(global.Node) bool, exclude func(global.Node) bool) func(global.Node) bool {
+	return func(node global.Node) bool {
+		if exclude != nil && exclude(node) {
+			return true
+		}
+		return !match(node)
+	}
+}
+
+// 过滤出匹配的节点
+func filterNodes(nodes []global.Node, match func(global.Node) bool) []global.Node {
+	result := nodes[:0]
+	for k := range nodes {
+		if match(nodes[k]) {
+			result = append(result, nodes[k])
+		}
+	}
+	return result
+}
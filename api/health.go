@@ -0,0 +1,28 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/dxvgef/tsing"
+
+	"local/global"
+	"local/health"
+)
+
+// 存活检查，无需验证
+func Healthz(ctx *tsing.Context) error {
+	return JSON(ctx, http.StatusOK, health.Live())
+}
+
+// 就绪检查，无需验证，未就绪时响应503及各组件的状态
+func Readyz(ctx *tsing.Context) error {
+	config := &global.Config().API.Health
+	reqCtx, cancel := context.WithTimeout(ctx.Request.Context(), config.Timeout)
+	defer cancel()
+	report := health.Ready(reqCtx, requestStorage(ctx), config.MaxWatchLag)
+	if report.Status != health.StatusOK {
+		return JSON(ctx, http.StatusServiceUnavailable, report)
+	}
+	return JSON(ctx, http.StatusOK, report)
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/dxvgef/tsing"
+
+	"local/accesslog"
+	"local/global"
+	"local/metrics"
+	"local/tracing"
+)
+
+// 请求上下文中保存路由信息的key
+type routeKey struct{}
+
+// 请求匹配的路由信息，由recordRoute中间件写入
+type routeInfo struct {
+	route     string
+	namespace string // 请求的命名空间，由checkNamespace中间件写入
+	serviceID string // 解码后的服务ID
+}
+
+// 未匹配到路由的请求使用的路由名称
+const unmatchedRoute = "unmatched"
+
+// 记录响应状态码及字节数的ResponseWriter
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (self *statusWriter) WriteHeader(status int) {
+	if self.status == 0 {
+		self.status = status
+	}
+	self.ResponseWriter.WriteHeader(status)
+}
+
+func (self *statusWriter) Write(data []byte) (int, error) {
+	if self.status == 0 {
+		self.status = http.StatusOK
+	}
+	n, err := self.ResponseWriter.Write(data)
+	self.bytes += int64(n)
+	return n, err
+}
+
+// 事件推送需要实时输出
+func (self *statusWriter) Flush() {
+	if flusher, ok := self.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// 包装API服务的处理器，记录每个请求的路由、状态码、处理时间、追踪span及访问日志
+// 请求中有W3C traceparent头信息时，请求的span作为其子span
+func Instrument(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		start := time.Now()
+		info := new(routeInfo)
+		writer := &statusWriter{ResponseWriter: resp}
+		ctx := context.WithValue(req.Context(), routeKey{}, info)
+		if sc, ok := tracing.ParseTraceParent(req.Header.Get("traceparent")); ok {
+			ctx = tracing.ContextWithRemote(ctx, sc)
+		}
+		ctx, span := tracing.StartServer(ctx, req.Method)
+		handler.ServeHTTP(writer, req.WithContext(ctx))
+		if info.route == "" {
+			info.route = unmatchedRoute
+		}
+		if writer.status == 0 {
+			writer.status = http.StatusOK
+		}
+		latency := time.Since(start)
+		metrics.ObserveRequest(info.route, req.Method, writer.status, latency)
+		accesslog.Write(accesslog.Entry{
+			Method:    req.Method,
+			Route:     info.route,
+			Path:      req.URL.Path,
+			Namespace: info.namespace,
+			ServiceID: info.serviceID,
+			Status:    writer.status,
+			Latency:   latency,
+			Bytes:     writer.bytes,
+			IP:        remoteIP(req),
+		})
+
+		span.SetName(req.Method + " " + info.route)
+		span.SetAttribute("http.method", req.Method)
+		span.SetAttribute("http.route", info.route)
+		span.SetAttribute("http.status_code", strconv.Itoa(writer.status))
+		if writer.status >= http.StatusInternalServerError {
+			span.SetError(errors.New(http.StatusText(writer.status)))
+		}
+		span.End()
+	})
+}
+
+// 返回记录请求匹配的路由及服务ID的中间件，route为注册时的路由模板，须作为路由的第一个中间件
+func recordRoute(route string) tsing.Handler {
+	return func(ctx *tsing.Context) error {
+		info, ok := ctx.Request.Context().Value(routeKey{}).(*routeInfo)
+		if !ok {
+			return nil
+		}
+		info.route = route
+		info.serviceID = ctx.PathParams.Value("serviceID")
+		// 旧版API的服务ID使用base64编码，解码失败时保留原值
+		if info.serviceID != "" && !isV1Request(ctx.Request) {
+			if serviceID, err := global.DecodeKey(info.serviceID); err == nil {
+				info.serviceID = serviceID
+			}
+		}
+		return nil
+	}
+}
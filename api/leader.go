@@ -0,0 +1,29 @@
+package api
+
+import (
+	"github.com/dxvgef/tsing"
+	"github.com/rs/zerolog/log"
+
+	"local/leader"
+)
+
+type Leader struct{}
+
+// 获取领导者选举的状态
+func (self *Leader) Get(ctx *tsing.Context) error {
+	status, err := leader.Get(ctx.Request.Context())
+	if err != nil {
+		log.Err(err).Caller().Send()
+		return ctx.Caller(err)
+	}
+	return JSON(ctx, 200, &status)
+}
+
+// v1版本的领导者选举状态
+func (self *Leader) V1Get(ctx *tsing.Context) error {
+	status, err := leader.Get(ctx.Request.Context())
+	if err != nil {
+		return v1Fail(ctx, 500, codeInternal, err.Error())
+	}
+	return JSON(ctx, 200, &status)
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"github.com/dxvgef/tsing"
+
+	"local/audit"
+	"local/global"
+)
+
+// 日志配置
+type loggerConfig struct {
+	Level string `json:"level"`
+}
+
+type V1Logger struct{}
+
+// 获取当前实例的日志记录级别
+func (self *V1Logger) Get(ctx *tsing.Context) error {
+	return JSON(ctx, 200, &loggerConfig{Level: global.LogLevel()})
+}
+
+// 修改当前实例的日志记录级别，只对当前实例生效，重启后恢复为配置文件中的级别
+func (self *V1Logger) Put(ctx *tsing.Context) error {
+	var body loggerConfig
+	if err := v1Decode(ctx, &body); err != nil {
+		return v1Fail(ctx, 400, codeInvalidRequest, err.Error())
+	}
+	before := loggerConfig{Level: global.LogLevel()}
+	if err := global.SetLogLevel(body.Level); err != nil {
+		return v1FailField(ctx, 400, codeInvalidParameter, "level", "level must be one of debug, info, warn, error, empty, disabled")
+	}
+	after := loggerConfig{Level: global.LogLevel()}
+	writeAudit(ctx, audit.ActionLoggerSet, "", "", &before, &after)
+	return JSON(ctx, 200, &after)
+}
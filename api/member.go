@@ -0,0 +1,48 @@
+package api
+
+import (
+	"github.com/dxvgef/tsing"
+	"github.com/rs/zerolog/log"
+
+	"local/member"
+)
+
+// 成员列表
+type memberList struct {
+	Revision int64           `json:"revision"` // 所有成员中最新的修订版本号
+	Members  []member.Status `json:"members"`
+}
+
+type Member struct{}
+
+// 获取集群中所有存活的实例及其数据同步状态
+func (self *Member) List(ctx *tsing.Context) error {
+	list, err := listMembers(ctx)
+	if err != nil {
+		log.Err(err).Caller().Send()
+		return ctx.Caller(err)
+	}
+	return JSON(ctx, 200, &list)
+}
+
+// v1版本的成员列表
+func (self *Member) V1List(ctx *tsing.Context) error {
+	list, err := listMembers(ctx)
+	if err != nil {
+		return v1Fail(ctx, 500, codeInternal, err.Error())
+	}
+	return JSON(ctx, 200, &list)
+}
+
+func listMembers(ctx *tsing.Context) (list memberList, err error) {
+	list.Members, err = member.List(requestStorage(ctx))
+	if err != nil {
+		return
+	}
+	for k := range list.Members {
+		if list.Members[k].Revision > list.Revision {
+			list.Revision = list.Members[k].Revision
+		}
+	}
+	return
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"local/global"
+	"local/meta"
+)
+
+// 校验并压缩服务的meta_schema，空值或null表示不校验节点的元信息
+func normalizeMetaSchema(raw []byte) (string, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+	if _, err := meta.CompileSchema(raw); err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// 按服务的meta_schema校验节点的元信息，服务未设置meta_schema时不校验
+func validateNodeMeta(config global.ServiceConfig, value string) error {
+	schema, err := config.NodeMetaSchema()
+	if err != nil || schema == nil {
+		return err
+	}
+	return schema.Validate(value)
+}
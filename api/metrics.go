@@ -0,0 +1,23 @@
+package api
+
+import (
+	"crypto/subtle"
+
+	"github.com/dxvgef/tsing"
+
+	"local/global"
+	"local/metrics"
+)
+
+// 输出指标的处理器
+var metricsHandler = metrics.Handler()
+
+// 输出Prometheus格式的指标，配置了令牌时须在Authorization: Bearer或SECRET头信息中传入
+func Metrics(ctx *tsing.Context) error {
+	if token := global.Config().API.Metrics.Token; token != "" &&
+		subtle.ConstantTimeCompare(global.StrToBytes(requestSecret(ctx.Request)), global.StrToBytes(token)) != 1 {
+		return Status(ctx, 401)
+	}
+	metricsHandler.ServeHTTP(ctx.ResponseWriter, ctx.Request)
+	return nil
+}
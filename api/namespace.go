@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"sort"
+
+	"github.com/dxvgef/tsing"
+
+	"local/global"
+)
+
+// 指定命名空间的头信息，未传入namespace参数时使用
+const namespaceHeader = "X-Tsing-Namespace"
+
+// 请求上下文中保存命名空间的key
+type namespaceKey struct{}
+
+// 解析请求的命名空间并写入请求上下文，优先使用namespace参数，其次是X-Tsing-Namespace头信息，都未传入时使用默认命名空间
+func checkNamespace(ctx *tsing.Context) error {
+	namespace := ctx.Query("namespace")
+	if namespace == "" {
+		namespace = ctx.Request.Header.Get(namespaceHeader)
+	}
+	if namespace == "" {
+		namespace = global.DefaultNamespace
+	}
+	if !global.ValidNamespace(namespace) {
+		ctx.Abort()
+		if isV1Request(ctx.Request) {
+			return v1FailField(ctx, 400, codeInvalidParameter, "namespace", "namespace must match [a-z0-9][a-z0-9_-]{0,62} and must not be a reserved name")
+		}
+		return JSON(ctx, 400, map[string]string{"error": "namespace参数无效"})
+	}
+	ctx.Request = ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), namespaceKey{}, namespace))
+	if info, ok := ctx.Request.Context().Value(routeKey{}).(*routeInfo); ok {
+		info.namespace = namespace
+	}
+	return nil
+}
+
+// 获取请求的命名空间
+func requestNamespace(ctx *tsing.Context) string {
+	if namespace, ok := ctx.Request.Context().Value(namespaceKey{}).(string); ok {
+		return namespace
+	}
+	return global.DefaultNamespace
+}
+
+// v1 API的命名空间
+type v1Namespace struct {
+	Name     string `json:"name"`
+	Services int    `json:"services"` // 当前身份可读取的服务数量
+}
+
+// 本地数据中各命名空间的服务列表
+func namespaceServices() map[string][]global.Cluster {
+	result := make(map[string][]global.Cluster)
+	global.Services.Range(func(_, value interface{}) bool {
+		if ci, ok := value.(global.Cluster); ok {
+			namespace := ci.Config().Namespace
+			result[namespace] = append(result[namespace], ci)
+		}
+		return true
+	})
+	return result
+}
+
+type V1Namespace struct{}
+
+// 获取命名空间列表，只返回当前身份可读取其中服务的命名空间
+func (self *V1Namespace) List(ctx *tsing.Context) error {
+	p := getPrincipal(ctx)
+	namespaces := []v1Namespace{}
+	for namespace, clusters := range namespaceServices() {
+		item := v1Namespace{Name: namespace}
+		for k := range clusters {
+			if p != nil && global.AllowService(p.rules, namespace, clusters[k].Config().ServiceID, global.AccessRead) {
+				item.Services++
+			}
+		}
+		if item.Services > 0 {
+			namespaces = append(namespaces, item)
+		}
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		return namespaces[i].Name < namespaces[j].Name
+	})
+	return JSON(ctx, 200, map[string]interface{}{"namespaces": namespaces})
+}
@@ -2,12 +2,12 @@ package api
 
 import (
 	"math"
-	"strconv"
-	"strings"
 	"time"
 
+	"local/audit"
 	"local/engine"
 	"local/global"
+	"local/meta"
 
 	"github.com/dxvgef/filter/v2"
 	"github.com/dxvgef/tsing"
@@ -27,11 +27,12 @@ func (self *Node) Add(ctx *tsing.Context) error {
 			ttl       uint
 			expires   int64
 			meta      string
+			tags      []string
 		}
 	)
 	if err = filter.Batch(
 		filter.String(ctx.Post("service_id"), "service_id").Require().Set(&req.serviceID),
-		filter.String(ctx.Post("ip"), "ip").Require().IsIP().Set(&req.ip),
+		filter.String(ctx.Post("ip"), "ip").Require().Set(&req.ip),
 		filter.String(ctx.Post("port"), "port").Require().IsDigit().MinInteger(1).MaxInteger(math.MaxUint16).Set(&req.port),
 		filter.String(ctx.Post("weight"), "weight").Require().MinInteger(0).MaxInteger(math.MaxUint16).Set(&req.weight),
 		filter.String(ctx.Post("ttl"), "ttl").MinInteger(0).IsDigit().Set(&req.ttl),
@@ -41,12 +42,32 @@ func (self *Node) Add(ctx *tsing.Context) error {
 		resp["error"] = err.Error()
 		return JSON(ctx, 400, &resp)
 	}
+	if req.tags, err = postTags(ctx); err != nil {
+		resp["error"] = err.Error()
+		return JSON(ctx, 400, &resp)
+	}
+	if req.ip, err = nodeHost(req.ip); err != nil {
+		resp["error"] = err.Error()
+		return JSON(ctx, 400, &resp)
+	}
+	if req.meta, err = meta.Normalize(global.StrToBytes(req.meta)); err != nil {
+		resp["error"] = err.Error()
+		return JSON(ctx, 400, &resp)
+	}
+
+	if !allowService(ctx, req.serviceID, global.AccessRegister) {
+		return forbidden(ctx)
+	}
 
-	ci := engine.FindCluster(req.serviceID)
+	ci := engine.FindCluster(requestNamespace(ctx), req.serviceID)
 	if ci == nil {
 		resp["error"] = "服务不存在"
 		return JSON(ctx, 400, &resp)
 	}
+	if err = validateNodeMeta(ci.Config(), req.meta); err != nil {
+		resp["error"] = err.Error()
+		return JSON(ctx, 400, &resp)
+	}
 	node := ci.Find(req.ip, req.port)
 	if node.IP != "" {
 		resp["error"] = "节点已存在"
@@ -57,16 +78,19 @@ func (self *Node) Add(ctx *tsing.Context) error {
 		req.expires = time.Now().Add(time.Duration(req.ttl) * time.Second).Unix()
 	}
 
-	if err = global.Storage.SaveNode(req.serviceID, global.Node{
+	node = global.Node{
 		IP:      req.ip,
 		Port:    req.port,
 		Weight:  req.weight,
 		TTL:     req.ttl,
 		Expires: req.expires,
 		Mete:    req.meta,
-	}); err != nil {
+		Tags:    req.tags,
+	}
+	if err = requestStorage(ctx).SaveNode(requestNamespace(ctx), req.serviceID, node); err != nil {
 		return ctx.Caller(err)
 	}
+	writeAudit(ctx, audit.ActionNodeSet, req.serviceID, auditNodeID(req.ip, req.port), nil, &node)
 
 	return Status(ctx, 204)
 }
@@ -84,8 +108,10 @@ func (self *Node) Put(ctx *tsing.Context) error {
 			ttl       uint
 			expires   int64
 			meta      string
+			tags      []string
 		}
-		port uint64
+		cond     precondition
+		revision int64
 	)
 	if err = filter.Batch(
 		filter.String(ctx.PathParams.Value("serviceID"), "serviceID").Require().Base64RawURLDecode().Set(&req.serviceID),
@@ -98,40 +124,64 @@ func (self *Node) Put(ctx *tsing.Context) error {
 		resp["error"] = err.Error()
 		return JSON(ctx, 400, &resp)
 	}
-	pos := strings.Index(req.node, ":")
-	if pos == -1 {
-		// 来自客户端的数据，无需记录日志
-		return Status(ctx, 404)
+	if req.tags, err = postTags(ctx); err != nil {
+		resp["error"] = err.Error()
+		return JSON(ctx, 400, &resp)
 	}
-	req.ip = req.node[0:pos]
-	port, err = strconv.ParseUint(req.node[pos+1:], 10, 16)
-	if err != nil {
+	if req.meta, err = meta.Normalize(global.StrToBytes(req.meta)); err != nil {
+		resp["error"] = err.Error()
+		return JSON(ctx, 400, &resp)
+	}
+	// 节点标识为host:port，IPv6地址须加上[]
+	if req.ip, req.port, err = global.ParseNodeAddress(req.node); err != nil {
 		// 来自客户端的数据，无需记录日志
 		return Status(ctx, 404)
 	}
-	req.port = uint16(port)
+	if _, err = nodeHost(req.ip); err != nil {
+		resp["error"] = err.Error()
+		return JSON(ctx, 400, &resp)
+	}
 
-	ci := engine.FindCluster(req.serviceID)
+	ci := engine.FindCluster(requestNamespace(ctx), req.serviceID)
 	if ci == nil {
 		resp["error"] = "服务不存在" + req.serviceID
 		return JSON(ctx, 400, &resp)
 	}
+	if err = validateNodeMeta(ci.Config(), req.meta); err != nil {
+		resp["error"] = err.Error()
+		return JSON(ctx, 400, &resp)
+	}
+
+	if cond, err = parsePrecondition(ctx); err != nil {
+		// 来自客户端的数据，无需记录日志
+		resp["error"] = err.Error()
+		return JSON(ctx, 400, &resp)
+	}
 
 	if req.ttl > 0 {
 		req.expires = time.Now().Add(time.Duration(req.ttl) * time.Second).Unix()
 	}
 
-	if err = global.Storage.SaveNode(req.serviceID, global.Node{
+	before := auditNode(requestNamespace(ctx), req.serviceID, req.ip, req.port)
+	node := global.Node{
 		IP:      req.ip,
 		Port:    req.port,
 		Weight:  req.weight,
 		TTL:     req.ttl,
 		Expires: req.expires,
 		Mete:    req.meta,
-	}); err != nil {
+		Tags:    req.tags,
+	}
+	if revision, err = requestStorage(ctx).SaveNodeCAS(requestNamespace(ctx), req.serviceID, node, cond.revision); err != nil {
+		if err == global.ErrRevisionMismatch {
+			resp["error"] = err.Error()
+			return JSON(ctx, cond.status(), &resp)
+		}
 		return ctx.Caller(err)
 	}
+	writeAudit(ctx, audit.ActionNodeSet, req.serviceID, auditNodeID(req.ip, req.port), before, &node)
 
+	setETag(ctx, revision)
 	return Status(ctx, 204)
 }
 
@@ -143,9 +193,9 @@ func (self *Node) Delete(ctx *tsing.Context) error {
 			serviceID string
 			node      string
 		}
-		ip     string
-		port   uint16
-		port64 uint64
+		ip   string
+		port uint16
+		cond precondition
 	)
 	if err = filter.Batch(
 		filter.String(ctx.PathParams.Value("serviceID"), "serviceID").Require().Base64RawURLDecode().Set(&req.serviceID),
@@ -155,23 +205,27 @@ func (self *Node) Delete(ctx *tsing.Context) error {
 		resp["error"] = err.Error()
 		return JSON(ctx, 400, &resp)
 	}
-	pos := strings.Index(req.node, ":")
-	if pos == -1 {
+	// 节点标识为host:port，IPv6地址须加上[]
+	if ip, port, err = global.ParseNodeAddress(req.node); err != nil {
 		// 来自客户端的数据，无需记录日志
 		return Status(ctx, 404)
 	}
-	ip = req.node[0:pos]
-	port64, err = strconv.ParseUint(req.node[pos+1:], 10, 16)
-	if err != nil {
+	if cond, err = parsePrecondition(ctx); err != nil {
 		// 来自客户端的数据，无需记录日志
-		return Status(ctx, 404)
+		resp["error"] = err.Error()
+		return JSON(ctx, 400, &resp)
 	}
-	port = uint16(port64)
 
-	err = global.Storage.DeleteStorageNode(req.serviceID, ip, port)
+	before := auditNode(requestNamespace(ctx), req.serviceID, ip, port)
+	err = requestStorage(ctx).DeleteStorageNodeCAS(requestNamespace(ctx), req.serviceID, ip, port, cond.revision)
+	if err == global.ErrRevisionMismatch {
+		resp["error"] = err.Error()
+		return JSON(ctx, cond.status(), &resp)
+	}
 	if err != nil {
 		return ctx.Caller(err)
 	}
+	writeAudit(ctx, audit.ActionNodeDelete, req.serviceID, auditNodeID(ip, port), before, nil)
 	return Status(ctx, 204)
 }
 
@@ -189,34 +243,33 @@ func (self *Node) Patch(ctx *tsing.Context) error {
 			expires   int64
 			meta      string
 		}
-		port64 uint64
+		cond     precondition
+		revision int64
 	)
 
 	// 验证请求参数
 	if err = filter.Batch(
 		filter.String(ctx.PathParams.Value("serviceID"), "serviceID").Require().Base64RawURLDecode().Set(&req.serviceID),
 		filter.String(ctx.PathParams.Value("node"), "node").Require().Base64RawURLDecode().Set(&req.node),
-		filter.String(ctx.PathParams.Value("attrs"), "attrs").Require().EnumSliceString(",", []string{"ttl", "weight", "meta"}).SetSlice(&req.attrs, ","),
+		filter.String(ctx.PathParams.Value("attrs"), "attrs").Require().EnumSliceString(",", []string{"ttl", "weight", "meta", "tags"}).SetSlice(&req.attrs, ","),
 	); err != nil {
 		// 来自客户端的数据，无需记录日志
 		resp["error"] = err.Error()
 		return JSON(ctx, 400, &resp)
 	}
-	pos := strings.Index(req.node, ":")
-	if pos == -1 {
+	// 节点标识为host:port，IPv6地址须加上[]
+	if req.ip, req.port, err = global.ParseNodeAddress(req.node); err != nil {
 		// 来自客户端的数据，无需记录日志
 		return Status(ctx, 404)
 	}
-	req.ip = req.node[0:pos]
-	port64, err = strconv.ParseUint(req.node[pos+1:], 10, 16)
-	if err != nil {
+	if cond, err = parsePrecondition(ctx); err != nil {
 		// 来自客户端的数据，无需记录日志
-		return Status(ctx, 404)
+		resp["error"] = err.Error()
+		return JSON(ctx, 400, &resp)
 	}
-	req.port = uint16(port64)
 
 	// 获取集群
-	ci := engine.FindCluster(req.serviceID)
+	ci := engine.FindCluster(requestNamespace(ctx), req.serviceID)
 	if ci == nil {
 		// 来自客户端的数据，无需记录日志
 		return Status(ctx, 404)
@@ -227,6 +280,7 @@ func (self *Node) Patch(ctx *tsing.Context) error {
 	if node.IP == "" {
 		return Status(ctx, 404)
 	}
+	before := node
 
 	for k := range req.attrs {
 		if req.attrs[k] == "weight" {
@@ -240,9 +294,21 @@ func (self *Node) Patch(ctx *tsing.Context) error {
 				return JSON(ctx, 400, &resp)
 			}
 		}
+		// 元信息按JSON Merge Patch更新，值为null的键被删除，meta为空时清空元信息
 		if req.attrs[k] == "meta" {
-			node.Mete, err = filter.String(ctx.Post("weight"), "weight").IsJSON().String()
-			if err != nil {
+			if patch := ctx.Post("meta"); patch == "" {
+				node.Mete = ""
+			} else if node.Mete, err = meta.Merge(node.Mete, global.StrToBytes(patch)); err != nil {
+				resp["error"] = err.Error()
+				return JSON(ctx, 400, &resp)
+			}
+			if err = validateNodeMeta(ci.Config(), node.Mete); err != nil {
+				resp["error"] = err.Error()
+				return JSON(ctx, 400, &resp)
+			}
+		}
+		if req.attrs[k] == "tags" {
+			if node.Tags, err = postTags(ctx); err != nil {
 				resp["error"] = err.Error()
 				return JSON(ctx, 400, &resp)
 			}
@@ -266,17 +332,24 @@ func (self *Node) Patch(ctx *tsing.Context) error {
 	}
 
 	// 更新存储引擎中的数据
-	if err = global.Storage.SaveNode(req.serviceID, global.Node{
+	if revision, err = requestStorage(ctx).SaveNodeCAS(requestNamespace(ctx), req.serviceID, global.Node{
 		IP:      node.IP,
 		Port:    node.Port,
 		Weight:  node.Weight,
 		TTL:     node.TTL,
 		Mete:    node.Mete,
+		Tags:    node.Tags,
 		Expires: node.Expires,
-	}); err != nil {
+	}, cond.revision); err != nil {
+		if err == global.ErrRevisionMismatch {
+			resp["error"] = err.Error()
+			return JSON(ctx, cond.status(), &resp)
+		}
 		return ctx.Caller(err)
 	}
+	writeAudit(ctx, audit.ActionNodePatch, req.serviceID, auditNodeID(req.ip, req.port), &before, &node)
 
+	setETag(ctx, revision)
 	return Status(ctx, 204)
 }
 
@@ -291,7 +364,6 @@ func (self *Node) Touch(ctx *tsing.Context) error {
 			ip        string
 			port      uint16
 		}
-		port64 uint64
 	)
 
 	// 验证请求参数
@@ -303,21 +375,14 @@ func (self *Node) Touch(ctx *tsing.Context) error {
 		resp["error"] = err.Error()
 		return JSON(ctx, 400, &resp)
 	}
-	pos := strings.Index(req.node, ":")
-	if pos == -1 {
-		// 来自客户端的数据，无需记录日志
-		return Status(ctx, 404)
-	}
-	req.ip = req.node[0:pos]
-	port64, err = strconv.ParseUint(req.node[pos+1:], 10, 16)
-	if err != nil {
+	// 节点标识为host:port，IPv6地址须加上[]
+	if req.ip, req.port, err = global.ParseNodeAddress(req.node); err != nil {
 		// 来自客户端的数据，无需记录日志
 		return Status(ctx, 404)
 	}
-	req.port = uint16(port64)
 
 	// 获取集群
-	ci := engine.FindCluster(req.serviceID)
+	ci := engine.FindCluster(requestNamespace(ctx), req.serviceID)
 	if ci == nil {
 		// 来自客户端的数据，无需记录日志
 		return Status(ctx, 404)
@@ -335,13 +400,14 @@ func (self *Node) Touch(ctx *tsing.Context) error {
 
 	// 更新存储引擎中的数据
 	expires := time.Now().Add(time.Duration(node.TTL) * time.Second).Unix()
-	if err = global.Storage.SaveNode(req.serviceID, global.Node{
+	if err = requestStorage(ctx).SaveNode(requestNamespace(ctx), req.serviceID, global.Node{
 		IP:      node.IP,
 		Port:    node.Port,
 		Weight:  node.Weight,
 		TTL:     node.TTL,
 		Expires: expires,
 		Mete:    node.Mete,
+		Tags:    node.Tags,
 	}); err != nil {
 		return ctx.Caller(err)
 	}
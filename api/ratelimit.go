@@ -0,0 +1,140 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/dxvgef/tsing"
+
+	"local/audit"
+	"local/global"
+	"local/ratelimit"
+)
+
+// 当前实例的限流状态，可在运行时通过API调整，重启后恢复为配置文件中的值
+var (
+	rateLimitEnable int32                 // 是否启用限流，1表示启用
+	writeLimiter    = ratelimit.New(0, 0) // 写操作的限流器
+	selectLimiter   = ratelimit.New(0, 0) // 选取节点的限流器
+)
+
+// 限流配置
+type rateLimitConfig struct {
+	Enable bool                 `json:"enable"`
+	Write  global.RateLimitRule `json:"write"`
+	Select global.RateLimitRule `json:"select"`
+}
+
+// 最近一次从配置文件应用的限流配置
+var configRateLimit atomic.Value
+
+// 使用配置文件中的值设置限流器，配置文件中的值未变化时保留通过API调整的值
+func initRateLimit(config *global.ConfigType) {
+	current := rateLimitConfig{
+		Enable: config.API.RateLimit.Enable,
+		Write:  config.API.RateLimit.Write,
+		Select: config.API.RateLimit.Select,
+	}
+	if previous, ok := configRateLimit.Load().(rateLimitConfig); ok && previous == current {
+		return
+	}
+	configRateLimit.Store(current)
+	setRateLimit(current)
+}
+
+// 获取当前的限流配置
+func getRateLimit() (config rateLimitConfig) {
+	config.Enable = atomic.LoadInt32(&rateLimitEnable) == 1
+	var rate float64
+	rate, config.Write.Burst = writeLimiter.Limit()
+	config.Write.Rate = global.Float(rate)
+	rate, config.Select.Burst = selectLimiter.Limit()
+	config.Select.Rate = global.Float(rate)
+	return
+}
+
+// 修改限流配置
+func setRateLimit(config rateLimitConfig) {
+	writeLimiter.SetLimit(float64(config.Write.Rate), config.Write.Burst)
+	selectLimiter.SetLimit(float64(config.Select.Rate), config.Select.Burst)
+	if config.Enable {
+		atomic.StoreInt32(&rateLimitEnable, 1)
+	} else {
+		atomic.StoreInt32(&rateLimitEnable, 0)
+	}
+}
+
+// 获取请求对应的限流器，写操作和选取节点分别计数，其它请求不限流
+func requestLimiter(req *http.Request) *ratelimit.Limiter {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead:
+		if strings.HasSuffix(req.URL.Path, "/select") {
+			return selectLimiter
+		}
+		return nil
+	case http.MethodOptions:
+		return nil
+	}
+	return writeLimiter
+}
+
+// 限流中间件，客户端IP及ACL令牌(或客户端证书)的令牌桶都有余量时才允许请求
+// 未启用ACL时所有客户端共用同一个访问密钥，只按客户端IP限流
+func checkRateLimit(ctx *tsing.Context) error {
+	if atomic.LoadInt32(&rateLimitEnable) == 0 {
+		return nil
+	}
+	limiter := requestLimiter(ctx.Request)
+	if limiter == nil {
+		return nil
+	}
+	keys := []string{"ip:" + remoteIP(ctx.Request)}
+	if p := getPrincipal(ctx); p != nil && p.name != secretPrincipal {
+		keys = append(keys, "id:"+p.name)
+	}
+	if ok, wait := limiter.Allow(keys...); !ok {
+		ctx.Abort()
+		return tooManyRequests(ctx, wait)
+	}
+	return nil
+}
+
+// 输出请求过多的错误，Retry-After为需要等待的秒数
+func tooManyRequests(ctx *tsing.Context, wait time.Duration) error {
+	ctx.ResponseWriter.Header().Set("Retry-After", strconv.FormatInt(int64(math.Max(1, math.Ceil(wait.Seconds()))), 10))
+	if isV1Request(ctx.Request) {
+		return v1Fail(ctx, 429, codeRateLimited, "too many requests")
+	}
+	return Status(ctx, 429)
+}
+
+type V1RateLimit struct{}
+
+// 获取当前实例的限流配置
+func (self *V1RateLimit) Get(ctx *tsing.Context) error {
+	config := getRateLimit()
+	return JSON(ctx, 200, &config)
+}
+
+// 修改当前实例的限流配置，只对当前实例生效
+func (self *V1RateLimit) Put(ctx *tsing.Context) error {
+	var body rateLimitConfig
+	if err := v1Decode(ctx, &body); err != nil {
+		return v1Fail(ctx, 400, codeInvalidRequest, err.Error())
+	}
+	if body.Write.Rate < 0 || body.Write.Burst < 0 {
+		return v1FailField(ctx, 400, codeInvalidParameter, "write", "rate and burst must not be negative")
+	}
+	if body.Select.Rate < 0 || body.Select.Burst < 0 {
+		return v1FailField(ctx, 400, codeInvalidParameter, "select", "rate and burst must not be negative")
+	}
+	before := getRateLimit()
+	setRateLimit(body)
+	after := getRateLimit()
+	writeAudit(ctx, audit.ActionRateLimitSet, "", "", &before, &after)
+	return JSON(ctx, 200, &after)
+}
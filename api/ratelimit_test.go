@@ -0,0 +1,75 @@
+package api
+
+import (
+	"testing"
+
+	"local/global"
+	"local/ratelimit"
+)
+
+// 以指定的客户端IP及身份发起写请求，返回是否通过限流
+func allowWrite(t *testing.T, ip, name string) bool {
+	ctx, recorder := newTestContext("POST", "/v1/services", "", &principal{name: name, rules: rootRules})
+	ctx.Request.RemoteAddr = ip + ":1234"
+	if err := checkRateLimit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	switch recorder.Code {
+	case 429:
+		if recorder.Header().Get("Retry-After") == "" {
+			t.Fatal("限流时应输出Retry-After头信息")
+		}
+		return false
+	case 200:
+		return true
+	}
+	t.Fatalf("未知的状态码：%d", recorder.Code)
+	return false
+}
+
+func TestCheckRateLimit(t *testing.T) {
+	previous := getRateLimit()
+	previousWrite, previousSelect := writeLimiter, selectLimiter
+	// 使用新的限流器，避免重复运行时沿用之前耗尽的令牌桶
+	writeLimiter, selectLimiter = ratelimit.New(0, 0), ratelimit.New(0, 0)
+	defer func() {
+		writeLimiter, selectLimiter = previousWrite, previousSelect
+		setRateLimit(previous)
+	}()
+	// 令牌几乎不补充，每个令牌桶只允许2次请求
+	setRateLimit(rateLimitConfig{
+		Enable: true,
+		Write:  global.RateLimitRule{Rate: 0.001, Burst: 2},
+		Select: global.RateLimitRule{Rate: 0.001, Burst: 2},
+	})
+
+	// 从另一个IP耗尽身份的令牌桶
+	for i := 0; i < 2; i++ {
+		if !allowWrite(t, "192.0.2.1", "limit-a") {
+			t.Fatalf("第%d次请求不应被限流", i+1)
+		}
+	}
+	// 身份的令牌桶已耗尽，IP的令牌桶还有令牌
+	if allowWrite(t, "192.0.2.2", "limit-a") {
+		t.Fatal("身份的令牌桶耗尽后应被限流")
+	}
+	// 被限流的请求不应取走IP的令牌，同一IP的其它身份仍有2次请求的余量
+	for i := 0; i < 2; i++ {
+		if !allowWrite(t, "192.0.2.2", "limit-b") {
+			t.Fatalf("同一IP的其它身份的第%d次请求不应被限流", i+1)
+		}
+	}
+	if allowWrite(t, "192.0.2.2", "limit-c") {
+		t.Fatal("IP的令牌桶耗尽后应被限流")
+	}
+
+	// 未启用ACL时使用访问密钥的请求只按IP限流
+	for i := 0; i < 2; i++ {
+		if !allowWrite(t, "192.0.2.3", secretPrincipal) {
+			t.Fatalf("第%d次请求不应被限流", i+1)
+		}
+	}
+	if !allowWrite(t, "192.0.2.4", secretPrincipal) {
+		t.Fatal("访问密钥不应作为身份限流")
+	}
+}
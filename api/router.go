@@ -1,32 +1,193 @@
 package api
 
-import "github.com/dxvgef/tsing"
+import (
+	"net/http"
+
+	"github.com/dxvgef/tsing"
+
+	"local/global"
+)
 
 // 设置路由
 func SetRouter(engine *tsing.Engine) {
-	// 检查secret中间件
-	router := engine.Group("", checkSecretFromHeader)
+	ApplyConfig(global.Config())
+
+	root := newRouteGroup(engine, "")
+
+	// Prometheus指标，使用独立的令牌或无需验证
+	if global.Config().API.Metrics.Enable {
+		root.GET("/metrics", Metrics)
+	}
+
+	// 存活及就绪检查，无需验证
+	root.GET("/healthz", Healthz)
+	root.GET("/readyz", Readyz)
+
+	// 检查secret或ACL令牌、限流及命名空间的中间件，各路由再按所需的访问级别检查权限
+	router := newRouteGroup(engine, "", checkSecretFromHeader, checkRateLimit, checkNamespace)
 
 	router.GET("/ip", GetIP) // 用于客户端获取IP地址
 
 	// 数据管理
 	var dataHandler Data
-	router.GET("/data/", dataHandler.OutputJSON) // 将本节点所有本地缓存数据以JSON格式输出
-	router.POST("/data/", dataHandler.LoadAll)   // 从存储器加载所有数据到本地缓存
-	router.PUT("/data/", dataHandler.SaveAll)    // 将本节点所有本地缓存数据写入到存储器
+	router.GET("/data/", dataHandler.OutputJSON)                                  // 将本节点所有本地缓存数据以JSON格式输出
+	router.POST("/data/", requireGlobal(global.AccessAdmin), dataHandler.LoadAll) // 从存储器加载所有数据到本地缓存
+	router.PUT("/data/", requireGlobal(global.AccessAdmin), dataHandler.SaveAll)  // 将本节点所有本地缓存数据写入到存储器
+
+	// 数据变更事件推送
+	var streamHandler Stream
+	router.GET("/events", streamHandler.Events) // 以SSE方式推送服务及节点的变更事件
 
 	// 服务管理
 	var serviceHandler Service
-	router.POST("/services/", serviceHandler.Add)                    // 创建服务
-	router.PUT("/services/:serviceID", serviceHandler.Put)           // 重写或创建服务
-	router.GET("/services/:serviceID/select", serviceHandler.Select) // 获取服务中的节点信息
-	router.DELETE("/services/:serviceID", serviceHandler.Delete)     // 删除服务
+	router.POST("/services/", serviceHandler.Add)                                                       // 创建服务
+	router.PUT("/services/:serviceID", requireService(global.AccessAdmin), serviceHandler.Put)          // 重写或创建服务
+	router.GET("/services/:serviceID/select", requireService(global.AccessRead), serviceHandler.Select) // 获取服务中的节点信息
+	router.GET("/services/:serviceID/nodes", requireService(global.AccessRead), serviceHandler.Nodes)   // 获取服务中的节点列表
+	router.DELETE("/services/:serviceID", requireService(global.AccessAdmin), serviceHandler.Delete)    // 删除服务
 
 	// 节点管理
 	var nodeHandler Node
-	router.POST("/nodes/", nodeHandler.Add)                           // 创建节点
-	router.PUT("/nodes/:serviceID/:node", nodeHandler.Put)            // 重写或创建节点
-	router.DELETE("/nodes/:serviceID/:node", nodeHandler.Delete)      // 删除节点
-	router.PATCH("/nodes/:serviceID/:node/:attrs", nodeHandler.Patch) // 更新节点属性
-	router.POST("/nodes/:serviceID/:node", nodeHandler.Touch)         // 节点触活
+	router.POST("/nodes/", nodeHandler.Add)                                                                  // 创建节点
+	router.PUT("/nodes/:serviceID/:node", requireService(global.AccessRegister), nodeHandler.Put)            // 重写或创建节点
+	router.DELETE("/nodes/:serviceID/:node", requireService(global.AccessRegister), nodeHandler.Delete)      // 删除节点
+	router.PATCH("/nodes/:serviceID/:node/:attrs", requireService(global.AccessRegister), nodeHandler.Patch) // 更新节点属性
+	router.POST("/nodes/:serviceID/:node", requireService(global.AccessRegister), nodeHandler.Touch)         // 节点触活
+
+	// 批量操作
+	var batchHandler Batch
+	router.POST("/batch/nodes", batchHandler.Nodes) // 批量创建、重写、触活或删除节点
+
+	// 审计日志
+	var auditHandler Audit
+	router.GET("/audit", auditHandler.Query) // 查询审计记录
+
+	// 集群成员
+	var memberHandler Member
+	router.GET("/members", requireGlobal(global.AccessRead), memberHandler.List) // 获取所有存活的实例
+
+	// 领导者选举
+	var leaderHandler Leader
+	router.GET("/leader", requireGlobal(global.AccessRead), leaderHandler.Get) // 获取领导者选举的状态
+
+	setV1Router(engine)
+}
+
+// 设置v1 API的路由
+func setV1Router(engine *tsing.Engine) {
+	// OpenAPI文档无需验证secret
+	newRouteGroup(engine, "").GET("/v1/openapi.json", OpenAPI)
+
+	router := newRouteGroup(engine, "/v1", checkSecretFromHeader, checkRateLimit, checkNamespace)
+
+	var dataHandler V1Data
+	router.GET("/data", dataHandler.Export)                                        // 将本节点所有本地缓存数据以JSON格式输出
+	router.POST("/data/load", requireGlobal(global.AccessAdmin), dataHandler.Load) // 从存储器加载所有数据到本地缓存
+	router.POST("/data/save", requireGlobal(global.AccessAdmin), dataHandler.Save) // 将本节点所有本地缓存数据写入到存储器
+
+	var streamHandler Stream
+	router.GET("/events", streamHandler.Events) // 以SSE方式推送服务及节点的变更事件
+
+	var namespaceHandler V1Namespace
+	router.GET("/namespaces", namespaceHandler.List) // 获取命名空间列表
+
+	var serviceHandler V1Service
+	router.GET("/services", serviceHandler.List)                                                        // 获取服务列表
+	router.POST("/services", serviceHandler.Create)                                                     // 创建服务
+	router.GET("/services/:serviceID", requireService(global.AccessRead), serviceHandler.Get)           // 获取服务
+	router.PUT("/services/:serviceID", requireService(global.AccessAdmin), serviceHandler.Put)          // 重写或创建服务
+	router.DELETE("/services/:serviceID", requireService(global.AccessAdmin), serviceHandler.Delete)    // 删除服务
+	router.GET("/services/:serviceID/select", requireService(global.AccessRead), serviceHandler.Select) // 选取节点
+
+	var nodeHandler V1Node
+	router.GET("/services/:serviceID/nodes", requireService(global.AccessRead), nodeHandler.List)                   // 获取服务中的节点列表
+	router.POST("/services/:serviceID/nodes", requireService(global.AccessRegister), nodeHandler.Create)            // 创建节点
+	router.GET("/services/:serviceID/nodes/:node", requireService(global.AccessRead), nodeHandler.Get)              // 获取节点
+	router.PUT("/services/:serviceID/nodes/:node", requireService(global.AccessRegister), nodeHandler.Put)          // 重写或创建节点
+	router.PATCH("/services/:serviceID/nodes/:node", requireService(global.AccessRegister), nodeHandler.Patch)      // 更新节点的部分属性
+	router.DELETE("/services/:serviceID/nodes/:node", requireService(global.AccessRegister), nodeHandler.Delete)    // 删除节点
+	router.POST("/services/:serviceID/nodes/:node/touch", requireService(global.AccessRegister), nodeHandler.Touch) // 节点触活
+
+	var batchHandler V1Batch
+	router.POST("/batch/nodes", batchHandler.Nodes) // 批量创建、重写、触活或删除节点
+
+	var auditHandler V1Audit
+	router.GET("/audit", auditHandler.Query) // 查询审计记录
+
+	// 当前实例的限流配置
+	var rateLimitHandler V1RateLimit
+	router.GET("/ratelimit", requireGlobal(global.AccessAdmin), rateLimitHandler.Get) // 获取限流配置
+	router.PUT("/ratelimit", requireGlobal(global.AccessAdmin), rateLimitHandler.Put) // 修改限流配置
+
+	var configHandler V1Config
+	router.POST("/config/reload", requireGlobal(global.AccessAdmin), configHandler.Reload) // 重新加载配置文件
+
+	var memberHandler Member
+	router.GET("/members", requireGlobal(global.AccessRead), memberHandler.V1List) // 获取所有存活的实例
+
+	var leaderHandler Leader
+	router.GET("/leader", requireGlobal(global.AccessRead), leaderHandler.V1Get) // 获取领导者选举的状态
+
+	var federationHandler V1Federation
+	router.GET("/federation", requireGlobal(global.AccessRead), federationHandler.Get) // 获取远端集群的同步状态
+
+	var loggerHandler V1Logger
+	router.GET("/logger", requireGlobal(global.AccessAdmin), loggerHandler.Get) // 获取日志记录级别
+	router.PUT("/logger", requireGlobal(global.AccessAdmin), loggerHandler.Put) // 修改日志记录级别
+
+	// ACL令牌管理
+	var aclHandler V1ACL
+	aclRouter := router.Group("/acl", requireGlobal(global.AccessAdmin))
+	aclRouter.GET("/tokens", aclHandler.List)                  // 获取ACL令牌列表
+	aclRouter.POST("/tokens", aclHandler.Create)               // 创建ACL令牌
+	aclRouter.GET("/tokens/:accessorID", aclHandler.Get)       // 获取ACL令牌
+	aclRouter.DELETE("/tokens/:accessorID", aclHandler.Delete) // 吊销ACL令牌
+}
+
+// 路由组，注册路由时记录其路由模板，请求时先记录匹配的路由，再依次执行组的中间件及路由的处理器
+type routeGroup struct {
+	router      *tsing.Router
+	prefix      string // 路由模板的前缀
+	middlewares []tsing.Handler
+}
+
+// 创建路由组
+func newRouteGroup(engine *tsing.Engine, prefix string, middlewares ...tsing.Handler) *routeGroup {
+	return &routeGroup{router: engine.Group(prefix), prefix: prefix, middlewares: middlewares}
+}
+
+// 创建子路由组，子路由组的中间件在父路由组的中间件之后执行
+func (self *routeGroup) Group(prefix string, middlewares ...tsing.Handler) *routeGroup {
+	return &routeGroup{
+		router:      self.router.Group(prefix),
+		prefix:      self.prefix + prefix,
+		middlewares: append(append([]tsing.Handler{}, self.middlewares...), middlewares...),
+	}
+}
+
+func (self *routeGroup) handle(method, path string, handlers []tsing.Handler) {
+	chain := make([]tsing.Handler, 0, 1+len(self.middlewares)+len(handlers))
+	chain = append(chain, recordRoute(self.prefix+path))
+	chain = append(chain, self.middlewares...)
+	self.router.Handle(method, path, append(chain, handlers...)...)
+}
+
+func (self *routeGroup) GET(path string, handlers ...tsing.Handler) {
+	self.handle(http.MethodGet, path, handlers)
+}
+
+func (self *routeGroup) POST(path string, handlers ...tsing.Handler) {
+	self.handle(http.MethodPost, path, handlers)
+}
+
+func (self *routeGroup) PUT(path string, handlers ...tsing.Handler) {
+	self.handle(http.MethodPut, path, handlers)
+}
+
+func (self *routeGroup) PATCH(path string, handlers ...tsing.Handler) {
+	self.handle(http.MethodPatch, path, handlers)
+}
+
+func (self *routeGroup) DELETE(path string, handlers ...tsing.Handler) {
+	self.handle(http.MethodDelete, path, handlers)
 }
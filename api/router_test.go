@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dxvgef/tsing"
+)
+
+func TestRouteGroup(t *testing.T) {
+	engine := tsing.New(tsing.Config{})
+	var (
+		route     string
+		serviceID string
+		order     []string
+	)
+	middleware := func(name string) tsing.Handler {
+		return func(*tsing.Context) error {
+			order = append(order, name)
+			return nil
+		}
+	}
+	capture := func(ctx *tsing.Context) error {
+		info := ctx.Request.Context().Value(routeKey{}).(*routeInfo)
+		route, serviceID = info.route, info.serviceID
+		return Status(ctx, 204)
+	}
+	router := newRouteGroup(engine, "/v1", middleware("group"))
+	router.GET("/services/:serviceID", capture)
+	router.GET("/services/:serviceID/nodes/:node", capture)
+	router.Group("/acl", middleware("acl")).GET("/tokens/:accessorID", capture)
+	newRouteGroup(engine, "").GET("/services/:serviceID/nodes", capture)
+	handler := Instrument(engine)
+
+	cases := []struct {
+		path      string
+		route     string
+		serviceID string
+	}{
+		// 路径参数的值与路由中的固定部分相同时，仍按注册时的路由模板记录
+		{"/v1/services/services/nodes/10.0.0.1:80", "/v1/services/:serviceID/nodes/:node", "services"},
+		{"/v1/services/nodes/nodes/nodes", "/v1/services/:serviceID/nodes/:node", "nodes"},
+		{"/v1/services/v1", "/v1/services/:serviceID", "v1"},
+		{"/v1/acl/tokens/acl", "/v1/acl/tokens/:accessorID", ""},
+		// 旧版API的服务ID使用base64编码
+		{"/services/b3JkZXJz/nodes", "/services/:serviceID/nodes", "orders"},
+	}
+	for _, c := range cases {
+		route, serviceID = "", ""
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", c.path, nil))
+		if route != c.route || serviceID != c.serviceID {
+			t.Fatalf("%s应记录为%s %q，实际为%s %q", c.path, c.route, c.serviceID, route, serviceID)
+		}
+	}
+
+	// 子路由组的中间件在父路由组的中间件之后执行
+	order = nil
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/v1/acl/tokens/a", nil))
+	if len(order) != 2 || order[0] != "group" || order[1] != "acl" {
+		t.Fatalf("中间件的执行顺序不正确：%v", order)
+	}
+}
@@ -3,8 +3,10 @@ package api
 import (
 	"net/http"
 
+	"local/audit"
 	"local/engine"
 	"local/global"
+	"local/metrics"
 
 	"github.com/dxvgef/filter/v2"
 	"github.com/dxvgef/tsing"
@@ -27,7 +29,19 @@ func (self *Service) Add(ctx *tsing.Context) error {
 		resp["error"] = err.Error()
 		return JSON(ctx, 400, &resp)
 	}
-	if _, exists := global.Services.Load(config.ServiceID); exists {
+	if config.Tags, err = postTags(ctx); err != nil {
+		resp["error"] = err.Error()
+		return JSON(ctx, 400, &resp)
+	}
+	if config.MetaSchema, err = normalizeMetaSchema(global.StrToBytes(ctx.Post("meta_schema"))); err != nil {
+		resp["error"] = err.Error()
+		return JSON(ctx, 400, &resp)
+	}
+	if !allowService(ctx, config.ServiceID, global.AccessAdmin) {
+		return forbidden(ctx)
+	}
+	config.Namespace = requestNamespace(ctx)
+	if engine.FindCluster(config.Namespace, config.ServiceID) != nil {
 		resp["error"] = "服务ID已存在"
 		return JSON(ctx, 400, &resp)
 	}
@@ -36,17 +50,20 @@ func (self *Service) Add(ctx *tsing.Context) error {
 		return JSON(ctx, 400, &resp)
 	}
 
-	if err = global.Storage.SaveService(config); err != nil {
+	if err = requestStorage(ctx).SaveService(config); err != nil {
 		return ctx.Caller(err)
 	}
+	writeAudit(ctx, audit.ActionServiceSet, config.ServiceID, "", nil, &config)
 
 	return Status(ctx, 204)
 }
 func (self *Service) Put(ctx *tsing.Context) error {
 	var (
-		err    error
-		resp   = make(map[string]string)
-		config global.ServiceConfig
+		err      error
+		resp     = make(map[string]string)
+		config   global.ServiceConfig
+		cond     precondition
+		revision int64
 	)
 	if err = filter.Batch(
 		filter.String(ctx.PathParams.Value("serviceID"), "serviceID").Require().Base64RawURLDecode().Set(&config.ServiceID),
@@ -57,57 +74,192 @@ func (self *Service) Put(ctx *tsing.Context) error {
 		resp["error"] = err.Error()
 		return JSON(ctx, 400, &resp)
 	}
+	if config.Tags, err = postTags(ctx); err != nil {
+		resp["error"] = err.Error()
+		return JSON(ctx, 400, &resp)
+	}
+	if config.MetaSchema, err = normalizeMetaSchema(global.StrToBytes(ctx.Post("meta_schema"))); err != nil {
+		resp["error"] = err.Error()
+		return JSON(ctx, 400, &resp)
+	}
 	if config.LoadBalance == "" {
 		resp["error"] = "load_balance参数不能为空"
 		return JSON(ctx, 400, &resp)
 	}
+	config.Namespace = requestNamespace(ctx)
+	if cond, err = parsePrecondition(ctx); err != nil {
+		// 来自客户端的数据，无需记录日志
+		resp["error"] = err.Error()
+		return JSON(ctx, 400, &resp)
+	}
 
-	if err = global.Storage.SaveService(config); err != nil {
+	before := auditService(requestNamespace(ctx), config.ServiceID)
+	if revision, err = requestStorage(ctx).SaveServiceCAS(config, cond.revision); err != nil {
+		if err == global.ErrRevisionMismatch {
+			resp["error"] = err.Error()
+			return JSON(ctx, cond.status(), &resp)
+		}
 		return ctx.Caller(err)
 	}
+	writeAudit(ctx, audit.ActionServiceSet, config.ServiceID, "", before, &config)
 
+	setETag(ctx, revision)
 	return Status(ctx, 204)
 }
 
 func (self *Service) Delete(ctx *tsing.Context) error {
 	var (
 		err       error
+		resp      = make(map[string]string)
 		serviceID string
+		cond      precondition
 	)
 	if serviceID, err = global.DecodeKey(ctx.PathParams.Value("serviceID")); err != nil {
 		// 来自客户端的数据，无需记录日志
 		return Status(ctx, 404)
 	}
-	if _, exist := global.Services.Load(serviceID); !exist {
+	before := auditService(requestNamespace(ctx), serviceID)
+	if before == nil {
 		return Status(ctx, 404)
 	}
-	err = global.Storage.DeleteStorageService(ctx.PathParams.Value("serviceID"))
+	if cond, err = parsePrecondition(ctx); err != nil {
+		// 来自客户端的数据，无需记录日志
+		resp["error"] = err.Error()
+		return JSON(ctx, 400, &resp)
+	}
+	err = requestStorage(ctx).DeleteStorageServiceCAS(requestNamespace(ctx), ctx.PathParams.Value("serviceID"), cond.revision)
+	if err == global.ErrRevisionMismatch {
+		resp["error"] = err.Error()
+		return JSON(ctx, cond.status(), &resp)
+	}
 	if err != nil {
 		return ctx.Caller(err)
 	}
+	writeAudit(ctx, audit.ActionServiceDelete, serviceID, "", before, nil)
 	return Status(ctx, 204)
 }
 
-// 选取节点
+// 选取节点，?tag=及?meta=只选取匹配的节点
 func (self *Service) Select(ctx *tsing.Context) error {
 	var (
 		err       error
 		resp      = make(map[string]string)
 		serviceID string
+		query     blockingQuery
+		count     int
+		exclude   []string
+		match     nodeFilter
 	)
-	if serviceID, err = filter.String(ctx.PathParams.Value("serviceID"), "serviceID").Require().Base64RawURLDecode().String(); err != nil {
+	if err = filter.Batch(
+		filter.String(ctx.PathParams.Value("serviceID"), "serviceID").Require().Base64RawURLDecode().Set(&serviceID),
+		filter.String(ctx.Query("count"), "count").IsDigit().MinInteger(1).Set(&count),
+		filter.String(ctx.Query("exclude"), "exclude").SetSlice(&exclude, ","),
+	); err != nil {
 		// 来自客户端的数据，无需记录日志
 		resp["error"] = err.Error()
 		return JSON(ctx, 400, &resp)
 	}
-	ci := engine.FindCluster(serviceID)
-	if ci == nil {
+	if query, err = parseBlockingQuery(ctx); err != nil {
+		// 来自客户端的数据，无需记录日志
+		resp["error"] = err.Error()
+		return JSON(ctx, 400, &resp)
+	}
+	if match, _, err = requestNodeFilter(ctx); err != nil {
+		resp["error"] = err.Error()
+		return JSON(ctx, 400, &resp)
+	}
+	if query.index > 0 {
+		setIndexHeader(ctx, engine.WaitServiceIndex(ctx.Request.Context(), requestNamespace(ctx), serviceID, query.index, query.wait))
+	} else {
+		setIndexHeader(ctx, engine.ServiceIndex(requestNamespace(ctx), serviceID))
+	}
+	// 未指定数量时只返回单个节点
+	single := count == 0
+	if single {
+		count = 1
+	}
+	var (
+		nodes      []global.Node
+		datacenter string
+	)
+	ci := engine.FindCluster(requestNamespace(ctx), serviceID)
+	if ci != nil {
+		nodes = ci.SelectN(count, excludeUnmatched(match.local(requestNamespace(ctx), serviceID), excludeNodes(exclude)))
+	}
+	// 本地服务不存在或没有可用节点时回退到远端数据中心
+	if len(nodes) == 0 {
+		datacenter, nodes = selectFallback(requestNamespace(ctx), serviceID, count, excludeUnmatched(match.remote(), excludeNodes(exclude)))
+	}
+	if ci == nil && datacenter == "" {
 		resp["error"] = "服务不存在"
 		return JSON(ctx, 400, &resp)
 	}
-	node := ci.Select()
-	if node.IP == "" {
+	metrics.ObserveSelect(requestNamespace(ctx), serviceID, datacenter, nodes)
+	if len(nodes) == 0 {
 		return Status(ctx, http.StatusNotImplemented)
 	}
-	return JSON(ctx, 200, &node)
+	if datacenter != "" {
+		ctx.ResponseWriter.Header().Set(datacenterHeader, datacenter)
+	}
+	if single {
+		return JSON(ctx, 200, &nodes[0])
+	}
+	return JSON(ctx, 200, &nodes)
+}
+
+// 根据host:port列表生成排除节点的判断函数，IPv6地址须加上[]
+func excludeNodes(list []string) func(global.Node) bool {
+	if len(list) == 0 {
+		return nil
+	}
+	excluded := make(map[string]struct{}, len(list))
+	for k := range list {
+		// 规范化地址，无法解析的原样比较
+		if host, port, err := global.ParseNodeAddress(list[k]); err == nil {
+			excluded[global.NodeAddress(host, port)] = struct{}{}
+			continue
+		}
+		excluded[list[k]] = struct{}{}
+	}
+	return func(node global.Node) bool {
+		_, exist := excluded[global.NodeAddress(node.IP, node.Port)]
+		return exist
+	}
+}
+
+// 获取服务中的节点列表，?tag=及?meta=只返回匹配的节点
+func (self *Service) Nodes(ctx *tsing.Context) error {
+	var (
+		err       error
+		resp      = make(map[string]string)
+		serviceID string
+		query     blockingQuery
+		match     nodeFilter
+	)
+	if serviceID, err = filter.String(ctx.PathParams.Value("serviceID"), "serviceID").Require().Base64RawURLDecode().String(); err != nil {
+		// 来自客户端的数据，无需记录日志
+		resp["error"] = err.Error()
+		return JSON(ctx, 400, &resp)
+	}
+	if query, err = parseBlockingQuery(ctx); err != nil {
+		// 来自客户端的数据，无需记录日志
+		resp["error"] = err.Error()
+		return JSON(ctx, 400, &resp)
+	}
+	if match, _, err = requestNodeFilter(ctx); err != nil {
+		resp["error"] = err.Error()
+		return JSON(ctx, 400, &resp)
+	}
+	if query.index > 0 {
+		setIndexHeader(ctx, engine.WaitServiceIndex(ctx.Request.Context(), requestNamespace(ctx), serviceID, query.index, query.wait))
+	} else {
+		setIndexHeader(ctx, engine.ServiceIndex(requestNamespace(ctx), serviceID))
+	}
+	ci := engine.FindCluster(requestNamespace(ctx), serviceID)
+	if ci == nil {
+		resp["error"] = "服务不存在"
+		return JSON(ctx, 400, &resp)
+	}
+	nodes := filterNodes(ci.Nodes(), match.local(requestNamespace(ctx), serviceID))
+	return JSON(ctx, 200, &nodes)
 }
@@ -0,0 +1,223 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dxvgef/tsing"
+	"github.com/rs/zerolog/log"
+
+	"local/engine"
+	"local/global"
+)
+
+const (
+	streamHeartbeat = 15 * time.Second // 心跳帧的最大发送间隔
+	streamRetry     = 1000             // 客户端断线后重连的等待时间(毫秒)
+)
+
+// 事件ID的前缀，进程重启后修改索引会从0开始，前缀不同的事件ID不能用于续传
+var streamEpoch = strconv.FormatInt(time.Now().UnixNano(), 36)
+
+type Stream struct{}
+
+// 以Server-Sent Events方式推送数据变更事件
+// GET /events?services=a,b，只推送请求的命名空间中的服务，通过Last-Event-ID头信息或last_event_id参数续传
+func (self *Stream) Events(ctx *tsing.Context) error {
+	flusher, ok := ctx.ResponseWriter.(http.Flusher)
+	if !ok {
+		return ctx.Caller(errors.New("ResponseWriter未实现http.Flusher接口"))
+	}
+
+	// 要订阅的服务，为空表示订阅所有服务
+	var services map[string]struct{}
+	if value := ctx.Query("services"); value != "" {
+		services = make(map[string]struct{})
+		for _, serviceID := range strings.Split(value, ",") {
+			if serviceID != "" {
+				services[serviceID] = struct{}{}
+			}
+		}
+	}
+	// 只推送当前身份有权读取的服务
+	readable := readableServices(ctx)
+	match := func(serviceID string) bool {
+		if !readable(serviceID) {
+			return false
+		}
+		if services == nil {
+			return true
+		}
+		_, exist := services[serviceID]
+		return exist
+	}
+
+	// 响应必须在写超时之前结束，客户端会自动重连并续传
+	var deadline time.Time
+	if window := streamWindow(global.Config().API.WriteTimeout); window > 0 {
+		deadline = time.Now().Add(window)
+	}
+	heartbeat := streamHeartbeatInterval(global.Config().API.WriteTimeout)
+
+	header := ctx.ResponseWriter.Header()
+	header.Set("Content-Type", "text/event-stream; charset=UTF-8")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	ctx.ResponseWriter.WriteHeader(200)
+
+	stream := eventStream{writer: ctx.ResponseWriter, flusher: flusher, namespace: requestNamespace(ctx)}
+	stream.writeRetry()
+
+	lastID, resume := parseLastEventID(ctx)
+	var events []engine.Event
+	if resume {
+		events, resume = engine.EventsSince(lastID)
+	}
+	if resume {
+		lastID = stream.writeEvents(events, lastID, match)
+	} else {
+		lastID = stream.writeSnapshot(match)
+	}
+
+	reqCtx := ctx.Request.Context()
+	for stream.err == nil {
+		timeout := heartbeat
+		if !deadline.IsZero() {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				return nil
+			}
+			if remaining < timeout {
+				timeout = remaining
+			}
+		}
+		current := engine.WaitIndex(reqCtx, lastID, timeout)
+		if reqCtx.Err() != nil {
+			return nil
+		}
+		if current == lastID {
+			stream.writeHeartbeat(lastID)
+			continue
+		}
+		if events, ok = engine.EventsSince(lastID); ok {
+			lastID = stream.writeEvents(events, lastID, match)
+		} else {
+			// 积压的事件已被丢弃，重新发送全量数据
+			lastID = stream.writeSnapshot(match)
+		}
+	}
+	return nil
+}
+
+// 单个响应可推送事件的时长，在写超时前1秒结束，未设置写超时时返回0表示不限制
+func streamWindow(writeTimeout time.Duration) time.Duration {
+	if writeTimeout <= 0 {
+		return 0
+	}
+	if writeTimeout <= 2*time.Second {
+		return writeTimeout / 2
+	}
+	return writeTimeout - time.Second
+}
+
+// 心跳帧的发送间隔，写超时较短时缩短间隔，保证每个响应结束前至少收到一次心跳
+func streamHeartbeatInterval(writeTimeout time.Duration) time.Duration {
+	if window := streamWindow(writeTimeout); window > 0 && window/2 < streamHeartbeat {
+		return window / 2
+	}
+	return streamHeartbeat
+}
+
+// 从请求中解析客户端最后收到的事件ID
+func parseLastEventID(ctx *tsing.Context) (uint64, bool) {
+	value := ctx.Request.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = ctx.Query("last_event_id")
+	}
+	pos := strings.LastIndex(value, "-")
+	if pos == -1 || value[:pos] != streamEpoch {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(value[pos+1:], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return id, true
+}
+
+// 事件流的输出器，写入失败后不再输出
+type eventStream struct {
+	writer    http.ResponseWriter
+	flusher   http.Flusher
+	namespace string // 订阅的命名空间
+	err       error
+}
+
+func (self *eventStream) write(id uint64, event string, data []byte) {
+	if self.err != nil {
+		return
+	}
+	var frame strings.Builder
+	frame.WriteString("id: ")
+	frame.WriteString(streamEpoch)
+	frame.WriteString("-")
+	frame.WriteString(strconv.FormatUint(id, 10))
+	frame.WriteString("\nevent: ")
+	frame.WriteString(event)
+	frame.WriteString("\ndata: ")
+	frame.Write(data)
+	frame.WriteString("\n\n")
+	if _, self.err = self.writer.Write(global.StrToBytes(frame.String())); self.err != nil {
+		return
+	}
+	self.flusher.Flush()
+}
+
+func (self *eventStream) writeRetry() {
+	if _, self.err = self.writer.Write([]byte("retry: " + strconv.Itoa(streamRetry) + "\n\n")); self.err != nil {
+		return
+	}
+	self.flusher.Flush()
+}
+
+// 发送订阅服务的全量数据，返回快照对应的修改索引
+func (self *eventStream) writeSnapshot(match func(string) bool) uint64 {
+	// 先取索引再收集数据，快照中可能包含索引之后的变更，重复应用事件不会产生副作用
+	id := engine.Index()
+	data := localData(self.namespace, match)
+	bs, err := data.MarshalJSON()
+	if err != nil {
+		log.Err(err).Caller().Send()
+		self.err = err
+		return id
+	}
+	self.write(id, "snapshot", bs)
+	return id
+}
+
+// 发送订阅服务的事件，返回最后一个事件的ID
+func (self *eventStream) writeEvents(events []engine.Event, lastID uint64, match func(string) bool) uint64 {
+	for k := range events {
+		lastID = events[k].ID
+		if events[k].Namespace != self.namespace || !match(events[k].ServiceID) {
+			continue
+		}
+		bs, err := json.Marshal(&events[k])
+		if err != nil {
+			log.Err(err).Caller().Send()
+			continue
+		}
+		self.write(events[k].ID, events[k].Type, bs)
+	}
+	return lastID
+}
+
+// 发送心跳帧，同时携带最新的事件ID，使客户端续传时无需重放被过滤的事件
+func (self *eventStream) writeHeartbeat(lastID uint64) {
+	self.write(lastID, "heartbeat", []byte(`{"index":`+strconv.FormatUint(lastID, 10)+`}`))
+}
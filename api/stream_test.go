@@ -0,0 +1,30 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStreamHeartbeatInterval(t *testing.T) {
+	cases := []struct {
+		writeTimeout time.Duration
+		window       time.Duration
+		heartbeat    time.Duration
+	}{
+		{0, 0, streamHeartbeat},
+		{10 * time.Second, 9 * time.Second, 4500 * time.Millisecond},
+		{time.Minute, 59 * time.Second, streamHeartbeat},
+		{2 * time.Second, time.Second, 500 * time.Millisecond},
+		{time.Second, 500 * time.Millisecond, 250 * time.Millisecond},
+	}
+	for _, c := range cases {
+		window := streamWindow(c.writeTimeout)
+		heartbeat := streamHeartbeatInterval(c.writeTimeout)
+		if window != c.window || heartbeat != c.heartbeat {
+			t.Fatalf("写超时为%v时，时长应为%v、心跳间隔应为%v，实际为%v、%v", c.writeTimeout, c.window, c.heartbeat, window, heartbeat)
+		}
+		if window > 0 && heartbeat >= window {
+			t.Fatalf("写超时为%v时，心跳间隔必须小于响应的时长", c.writeTimeout)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package api
+
+import (
+	"github.com/dxvgef/tsing"
+
+	"local/tag"
+)
+
+// 从请求中解析标签过滤条件，?tag=a,b&tag=c&tag_mode=all|any
+func requestTagFilter(ctx *tsing.Context) (tag.Filter, error) {
+	return tag.ParseFilter(ctx.QueryParams()["tag"], ctx.Query("tag_mode"))
+}
+
+// 从表单中解析以,分隔的tags参数
+func postTags(ctx *tsing.Context) ([]string, error) {
+	return tag.Normalize(tag.Split(ctx.Post("tags")))
+}
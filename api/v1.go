@@ -0,0 +1,196 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+
+	"github.com/dxvgef/tsing"
+
+	"local/global"
+)
+
+// v1 API的路径前缀
+const v1Prefix = "/v1/"
+
+// v1 API的错误码，一经发布不可修改
+const (
+	codeInvalidRequest   = "INVALID_REQUEST"   // 请求体不是有效的JSON
+	codeInvalidParameter = "INVALID_PARAMETER" // 参数校验失败
+	codeUnauthorized     = "UNAUTHORIZED"      // 未通过身份验证
+	codeForbidden        = "FORBIDDEN"         // 没有权限
+	codeNotFound         = "NOT_FOUND"         // 路由不存在
+	codeMethodNotAllowed = "METHOD_NOT_ALLOWED"
+	codeServiceNotFound  = "SERVICE_NOT_FOUND"
+	codeServiceExists    = "SERVICE_EXISTS"
+	codeNodeNotFound     = "NODE_NOT_FOUND"
+	codeNodeExists       = "NODE_EXISTS"
+	codeNoAvailableNode  = "NO_AVAILABLE_NODE" // 服务中没有可用的节点
+	codeRevisionMismatch = "REVISION_MISMATCH" // 修订版本号与If-Match或cas参数不一致
+	codeTokenNotFound    = "TOKEN_NOT_FOUND"
+	codeAuditDisabled    = "AUDIT_DISABLED" // 未启用审计日志
+	codeRateLimited      = "RATE_LIMITED"   // 请求过多，需按Retry-After头信息等待后重试
+	codeInvalidConfig    = "INVALID_CONFIG" // 配置文件无效，已保留原配置
+	codeInternal         = "INTERNAL_ERROR"
+)
+
+// v1 API请求体的大小限制
+const v1MaxBodySize = 1 << 20
+
+// v1 API的错误对象
+type v1Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Field   string `json:"field,omitempty"` // 校验失败的参数名
+}
+
+// v1 API的服务
+type v1Service struct {
+	ID          string          `json:"id"`
+	LoadBalance string          `json:"load_balance"`
+	Meta        json.RawMessage `json:"meta,omitempty"`
+	Tags        []string        `json:"tags,omitempty"`
+	MetaSchema  json.RawMessage `json:"meta_schema,omitempty"` // 节点元信息的JSON Schema
+	Revision    int64           `json:"revision,omitempty"`    // 修订版本号，只读
+	Datacenter  string          `json:"datacenter,omitempty"`  // 从远端集群导入时的来源数据中心，只读
+}
+
+// v1 API的节点，请求体中的可选字段使用指针以区分是否传入
+type v1Node struct {
+	IP         string          `json:"ip"`
+	Port       uint16          `json:"port"`
+	Weight     *int            `json:"weight,omitempty"`
+	TTL        *uint           `json:"ttl,omitempty"`
+	Expires    int64           `json:"expires,omitempty"`
+	Meta       json.RawMessage `json:"meta,omitempty"`
+	Tags       []string        `json:"tags,omitempty"`       // Patch时传入[]表示清空标签
+	Revision   int64           `json:"revision,omitempty"`   // 修订版本号，只读
+	Datacenter string          `json:"datacenter,omitempty"` // 从远端集群导入时的来源数据中心，只读
+}
+
+// 判断是否为v1 API的请求
+func isV1Request(req *http.Request) bool {
+	return strings.HasPrefix(req.URL.Path, v1Prefix)
+}
+
+// 输出v1 API的错误
+func v1Fail(ctx *tsing.Context, status int, code, message string) error {
+	return v1FailField(ctx, status, code, "", message)
+}
+
+// 输出v1 API的参数校验错误
+func v1FailField(ctx *tsing.Context, status int, code, field, message string) error {
+	return JSON(ctx, status, map[string]v1Error{
+		"error": {Code: code, Message: message, Field: field},
+	})
+}
+
+// 输出前置条件无效的错误
+func v1FailPrecondition(ctx *tsing.Context) error {
+	return v1Fail(ctx, 400, codeInvalidParameter, "If-Match must be a single ETag or *, cas a non-negative integer, and they cannot be used together")
+}
+
+// 输出修订版本号不一致的错误
+func v1FailRevision(ctx *tsing.Context, cond precondition) error {
+	return v1Fail(ctx, cond.status(), codeRevisionMismatch, "revision does not match")
+}
+
+// 将v1 API的JSON请求体解析到obj，不允许出现未知字段
+func v1Decode(ctx *tsing.Context, obj interface{}) error {
+	decoder := json.NewDecoder(io.LimitReader(ctx.Request.Body, v1MaxBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(obj); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return errors.New("request body must contain a single JSON value")
+	}
+	return nil
+}
+
+// 解析路径中的服务ID
+func v1ServiceID(ctx *tsing.Context) string {
+	return ctx.PathParams.Value("serviceID")
+}
+
+// 解析路径中的节点标识(host:port)，IPv6地址须加上[]
+func v1NodeID(ctx *tsing.Context) (ip string, port uint16, ok bool) {
+	ip, port, err := global.ParseNodeAddress(ctx.PathParams.Value("node"))
+	if err != nil {
+		return "", 0, false
+	}
+	return ip, port, true
+}
+
+// 校验元信息，返回压缩后的JSON字符串
+func v1Meta(meta json.RawMessage) (string, error) {
+	if len(meta) == 0 || string(meta) == "null" {
+		return "", nil
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, meta); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// 转换成v1 API的服务
+func v1ServiceFrom(config global.ServiceConfig) v1Service {
+	service := v1Service{
+		ID:          config.ServiceID,
+		LoadBalance: config.LoadBalance,
+		Tags:        config.Tags,
+		Revision:    config.Revision,
+	}
+	if config.Mete != "" {
+		service.Meta = json.RawMessage(config.Mete)
+	}
+	if config.MetaSchema != "" {
+		service.MetaSchema = json.RawMessage(config.MetaSchema)
+	}
+	return service
+}
+
+// 转换成v1 API的节点
+func v1NodeFrom(node global.Node) v1Node {
+	result := v1Node{
+		IP:       node.IP,
+		Port:     node.Port,
+		Weight:   &node.Weight,
+		TTL:      &node.TTL,
+		Expires:  node.Expires,
+		Tags:     node.Tags,
+		Revision: node.Revision,
+	}
+	if node.Mete != "" {
+		result.Meta = json.RawMessage(node.Mete)
+	}
+	return result
+}
+
+// 转换成v1 API的节点列表
+func v1NodesFrom(nodes []global.Node) []v1Node {
+	result := make([]v1Node, len(nodes))
+	for k := range nodes {
+		result[k] = v1NodeFrom(nodes[k])
+	}
+	return result
+}
+
+// 转换成v1 API的节点列表，并标记来源数据中心
+func v1RemoteNodesFrom(datacenter string, nodes []global.Node) []v1Node {
+	result := v1NodesFrom(nodes)
+	for k := range result {
+		result[k].Datacenter = datacenter
+	}
+	return result
+}
+
+// 校验节点的weight参数
+func v1ValidWeight(weight int) bool {
+	return weight >= 0 && weight <= math.MaxUint16
+}
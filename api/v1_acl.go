@@ -0,0 +1,122 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/dxvgef/tsing"
+
+	"local/audit"
+	"local/engine"
+	"local/global"
+)
+
+type V1ACL struct{}
+
+// v1 API的ACL令牌，secret只在创建时返回
+type v1Token struct {
+	AccessorID  string           `json:"accessor_id"`
+	Secret      string           `json:"secret,omitempty"`
+	Description string           `json:"description,omitempty"`
+	Rules       []global.ACLRule `json:"rules"`
+	CreateTime  int64            `json:"create_time"`
+}
+
+// 转换成v1 API的ACL令牌
+func v1TokenFrom(token global.ACLToken) v1Token {
+	return v1Token{
+		AccessorID:  token.AccessorID,
+		Description: token.Description,
+		Rules:       token.Rules,
+		CreateTime:  token.CreateTime,
+	}
+}
+
+// 获取ACL令牌列表
+func (self *V1ACL) List(ctx *tsing.Context) error {
+	tokens := engine.Tokens()
+	result := make([]v1Token, len(tokens))
+	for k := range tokens {
+		result[k] = v1TokenFrom(tokens[k])
+	}
+	return JSON(ctx, 200, &result)
+}
+
+// 获取ACL令牌
+func (self *V1ACL) Get(ctx *tsing.Context) error {
+	token := engine.FindTokenByAccessor(ctx.PathParams.Value("accessorID"))
+	if token == nil {
+		return v1Fail(ctx, 404, codeTokenNotFound, "token not found")
+	}
+	result := v1TokenFrom(*token)
+	return JSON(ctx, 200, &result)
+}
+
+// 创建ACL令牌
+func (self *V1ACL) Create(ctx *tsing.Context) error {
+	var body struct {
+		Description string           `json:"description"`
+		Rules       []global.ACLRule `json:"rules"`
+	}
+	if err := v1Decode(ctx, &body); err != nil {
+		return v1Fail(ctx, 400, codeInvalidRequest, err.Error())
+	}
+	if len(body.Rules) == 0 {
+		return v1FailField(ctx, 400, codeInvalidParameter, "rules", "rules must not be empty")
+	}
+	for k := range body.Rules {
+		if body.Rules[k].Pattern == "" {
+			return v1FailField(ctx, 400, codeInvalidParameter, "rules", "pattern must not be empty")
+		}
+		if !global.ValidAccess(body.Rules[k].Access) {
+			return v1FailField(ctx, 400, codeInvalidParameter, "rules", "access must be one of read, register, admin")
+		}
+		// 明确保存默认命名空间，存储器中未设置命名空间的规则只能是旧版本创建的
+		if body.Rules[k].Namespace == "" {
+			body.Rules[k].Namespace = global.DefaultNamespace
+		}
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return ctx.Caller(err)
+	}
+	token := global.ACLToken{
+		AccessorID:  global.SnowflakeNode.Generate().String(),
+		SecretHash:  engine.HashSecret(secret),
+		Description: body.Description,
+		Rules:       body.Rules,
+		CreateTime:  time.Now().Unix(),
+	}
+	if err = requestStorage(ctx).SaveToken(token); err != nil {
+		return ctx.Caller(err)
+	}
+	result := v1TokenFrom(token)
+	writeAudit(ctx, audit.ActionTokenCreate, "", "", nil, &result)
+	result.Secret = secret
+	return JSON(ctx, 201, &result)
+}
+
+// 吊销ACL令牌
+func (self *V1ACL) Delete(ctx *tsing.Context) error {
+	token := engine.FindTokenByAccessor(ctx.PathParams.Value("accessorID"))
+	if token == nil {
+		return v1Fail(ctx, 404, codeTokenNotFound, "token not found")
+	}
+	if err := requestStorage(ctx).DeleteStorageToken(token.SecretHash); err != nil {
+		return ctx.Caller(err)
+	}
+	before := v1TokenFrom(*token)
+	writeAudit(ctx, audit.ActionTokenDelete, "", "", &before, nil)
+	return Status(ctx, 204)
+}
+
+// 生成随机的令牌secret
+func newSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"strconv"
+
+	"github.com/dxvgef/tsing"
+	"github.com/rs/zerolog/log"
+
+	"local/audit"
+	"local/engine"
+)
+
+type V1Data struct{}
+
+// 将本节点所有本地缓存数据以JSON格式输出
+func (self *V1Data) Export(ctx *tsing.Context) error {
+	query, err := parseBlockingQuery(ctx)
+	if err != nil {
+		return v1Fail(ctx, 400, codeInvalidParameter, "index must be an unsigned integer and wait a positive duration")
+	}
+	if query.index > 0 {
+		setIndexHeader(ctx, engine.WaitIndex(ctx.Request.Context(), query.index, query.wait))
+	} else {
+		setIndexHeader(ctx, engine.Index())
+	}
+	data := localData(requestNamespace(ctx), readableServices(ctx))
+	bs, err := data.MarshalJSON()
+	if err != nil {
+		return ctx.Caller(err)
+	}
+	return JSONBytes(ctx, 200, bs)
+}
+
+// 从存储器加载所有数据到本地缓存
+func (self *V1Data) Load(ctx *tsing.Context) error {
+	if err := loadAll(ctx); err != nil {
+		log.Err(err).Caller().Send()
+		return v1Fail(ctx, 500, codeInternal, err.Error())
+	}
+	writeAudit(ctx, audit.ActionDataLoad, "", "", nil, nil)
+	return Status(ctx, 204)
+}
+
+// 将本节点所有本地缓存数据写入到存储器
+func (self *V1Data) Save(ctx *tsing.Context) error {
+	if err := saveAll(ctx); err != nil {
+		log.Err(err).Caller().Send()
+		return v1Fail(ctx, 500, codeInternal, err.Error())
+	}
+	writeAudit(ctx, audit.ActionDataSave, "", "", nil, nil)
+	return Status(ctx, 204)
+}
+
+type V1Batch struct{}
+
+// 批量创建、重写、触活或删除节点
+func (self *V1Batch) Nodes(ctx *tsing.Context) error {
+	var items []batchNodeItem
+	if err := v1Decode(ctx, &items); err != nil {
+		return v1Fail(ctx, 400, codeInvalidRequest, err.Error())
+	}
+	if len(items) == 0 || len(items) > maxBatchItems {
+		return v1Fail(ctx, 400, codeInvalidRequest, "request body must contain 1 to "+strconv.Itoa(maxBatchItems)+" operations")
+	}
+	results := applyBatchNodes(ctx, items)
+	return JSON(ctx, 200, &results)
+}
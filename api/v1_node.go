@@ -0,0 +1,286 @@
+package api
+
+import (
+	"time"
+
+	"github.com/dxvgef/tsing"
+
+	"local/audit"
+	"local/engine"
+	"local/federation"
+	"local/global"
+	"local/meta"
+	"local/tag"
+)
+
+type V1Node struct{}
+
+// 获取服务中的节点列表，?datacenter=获取从远端数据中心导入的节点，?tag=及?meta=只返回匹配的节点
+func (self *V1Node) List(ctx *tsing.Context) error {
+	query, err := parseBlockingQuery(ctx)
+	if err != nil {
+		return v1Fail(ctx, 400, codeInvalidParameter, "index must be an unsigned integer and wait a positive duration")
+	}
+	filter, field, err := requestNodeFilter(ctx)
+	if err != nil {
+		return v1FailField(ctx, 400, codeInvalidParameter, field, err.Error())
+	}
+	datacenter, ok := requestDatacenter(ctx)
+	if !ok {
+		return v1FailField(ctx, 400, codeInvalidParameter, "datacenter", "unknown datacenter")
+	}
+	serviceID := v1ServiceID(ctx)
+	// 远端导入的数据定时同步，不支持阻塞查询
+	if datacenter != "" {
+		remote := federation.Find(datacenter, requestNamespace(ctx), serviceID)
+		if remote == nil {
+			return v1Fail(ctx, 404, codeServiceNotFound, "service not found")
+		}
+		nodes := v1RemoteNodesFrom(datacenter, filterNodes(remote.Nodes(), filter.remote()))
+		return JSON(ctx, 200, &nodes)
+	}
+	if query.index > 0 {
+		setIndexHeader(ctx, engine.WaitServiceIndex(ctx.Request.Context(), requestNamespace(ctx), serviceID, query.index, query.wait))
+	} else {
+		setIndexHeader(ctx, engine.ServiceIndex(requestNamespace(ctx), serviceID))
+	}
+	ci := engine.FindCluster(requestNamespace(ctx), serviceID)
+	if ci == nil {
+		return v1Fail(ctx, 404, codeServiceNotFound, "service not found")
+	}
+	nodes := v1NodesFrom(filterNodes(ci.Nodes(), filter.local(requestNamespace(ctx), serviceID)))
+	return JSON(ctx, 200, &nodes)
+}
+
+// 获取节点
+func (self *V1Node) Get(ctx *tsing.Context) error {
+	node, err := self.find(ctx)
+	if err != nil || node.IP == "" {
+		return err
+	}
+	result := v1NodeFrom(node)
+	setETag(ctx, result.Revision)
+	return JSON(ctx, 200, &result)
+}
+
+// 创建节点
+func (self *V1Node) Create(ctx *tsing.Context) error {
+	var body v1Node
+	if err := v1Decode(ctx, &body); err != nil {
+		return v1Fail(ctx, 400, codeInvalidRequest, err.Error())
+	}
+	var err error
+	if body.IP, err = nodeHost(body.IP); err != nil {
+		return v1FailField(ctx, 400, codeInvalidParameter, "ip", "ip must be a valid IP address, or a hostname when node.allowHostname is enabled")
+	}
+	if body.Port == 0 {
+		return v1FailField(ctx, 400, codeInvalidParameter, "port", "port must be between 1 and 65535")
+	}
+	ci := engine.FindCluster(requestNamespace(ctx), v1ServiceID(ctx))
+	if ci == nil {
+		return v1Fail(ctx, 404, codeServiceNotFound, "service not found")
+	}
+	if ci.Find(body.IP, body.Port).IP != "" {
+		return v1Fail(ctx, 409, codeNodeExists, "node already exists")
+	}
+	// 要求存储器中不存在该节点，避免并发创建时互相覆盖
+	return self.save(ctx, 201, body, precondition{revision: 0})
+}
+
+// 重写或创建节点
+func (self *V1Node) Put(ctx *tsing.Context) error {
+	var body v1Node
+	if err := v1Decode(ctx, &body); err != nil {
+		return v1Fail(ctx, 400, codeInvalidRequest, err.Error())
+	}
+	ip, port, ok := v1NodeID(ctx)
+	if !ok {
+		return v1Fail(ctx, 404, codeNodeNotFound, "node must be identified as ip:port")
+	}
+	if _, err := nodeHost(ip); err != nil {
+		return v1FailField(ctx, 400, codeInvalidParameter, "ip", "ip must be a valid IP address, or a hostname when node.allowHostname is enabled")
+	}
+	if body.IP != "" {
+		if normalized, err := global.NormalizeNodeHost(body.IP); err != nil || normalized != ip {
+			return v1FailField(ctx, 400, codeInvalidParameter, "ip", "ip and port do not match the URL")
+		}
+	}
+	if body.Port != 0 && body.Port != port {
+		return v1FailField(ctx, 400, codeInvalidParameter, "ip", "ip and port do not match the URL")
+	}
+	body.IP = ip
+	body.Port = port
+	if engine.FindCluster(requestNamespace(ctx), v1ServiceID(ctx)) == nil {
+		return v1Fail(ctx, 404, codeServiceNotFound, "service not found")
+	}
+	cond, err := parsePrecondition(ctx)
+	if err != nil {
+		return v1FailPrecondition(ctx)
+	}
+	return self.save(ctx, 200, body, cond)
+}
+
+// 校验并保存节点
+func (self *V1Node) save(ctx *tsing.Context, status int, body v1Node, cond precondition) error {
+	var (
+		err  error
+		node = global.Node{
+			IP:   body.IP,
+			Port: body.Port,
+		}
+	)
+	if body.Weight == nil || !v1ValidWeight(*body.Weight) {
+		return v1FailField(ctx, 400, codeInvalidParameter, "weight", "weight is required and must be between 0 and 65535")
+	}
+	node.Weight = *body.Weight
+	if body.TTL != nil {
+		node.TTL = *body.TTL
+	}
+	if node.Mete, err = meta.Normalize(body.Meta); err != nil {
+		return v1FailField(ctx, 400, codeInvalidParameter, "meta", "meta must be a JSON object")
+	}
+	ci := engine.FindCluster(requestNamespace(ctx), v1ServiceID(ctx))
+	if ci == nil {
+		return v1Fail(ctx, 404, codeServiceNotFound, "service not found")
+	}
+	if err = validateNodeMeta(ci.Config(), node.Mete); err != nil {
+		return v1FailField(ctx, 400, codeInvalidParameter, "meta", err.Error())
+	}
+	if node.Tags, err = tag.Normalize(body.Tags); err != nil {
+		return v1FailField(ctx, 400, codeInvalidParameter, "tags", err.Error())
+	}
+	if node.TTL > 0 {
+		node.Expires = time.Now().Add(time.Duration(node.TTL) * time.Second).Unix()
+	}
+	before := auditNode(requestNamespace(ctx), v1ServiceID(ctx), node.IP, node.Port)
+	if node.Revision, err = requestStorage(ctx).SaveNodeCAS(requestNamespace(ctx), v1ServiceID(ctx), node, cond.revision); err != nil {
+		if err != global.ErrRevisionMismatch {
+			return ctx.Caller(err)
+		}
+		if cond.revision == 0 && !cond.header {
+			return v1Fail(ctx, 409, codeNodeExists, "node already exists")
+		}
+		return v1FailRevision(ctx, cond)
+	}
+	writeAudit(ctx, audit.ActionNodeSet, v1ServiceID(ctx), auditNodeID(node.IP, node.Port), before, &node)
+	result := v1NodeFrom(node)
+	setETag(ctx, result.Revision)
+	return JSON(ctx, status, &result)
+}
+
+// 更新节点的部分属性，只更新请求体中传入的字段
+func (self *V1Node) Patch(ctx *tsing.Context) error {
+	var body v1Node
+	if err := v1Decode(ctx, &body); err != nil {
+		return v1Fail(ctx, 400, codeInvalidRequest, err.Error())
+	}
+	if body.IP != "" || body.Port != 0 {
+		return v1FailField(ctx, 400, codeInvalidParameter, "ip", "ip and port cannot be changed")
+	}
+	cond, err := parsePrecondition(ctx)
+	if err != nil {
+		return v1FailPrecondition(ctx)
+	}
+	node, err := self.find(ctx)
+	if err != nil || node.IP == "" {
+		return err
+	}
+	before := node
+	if body.Weight != nil {
+		if !v1ValidWeight(*body.Weight) {
+			return v1FailField(ctx, 400, codeInvalidParameter, "weight", "weight must be between 0 and 65535")
+		}
+		node.Weight = *body.Weight
+	}
+	// 元信息按JSON Merge Patch更新，值为null的键被删除
+	if body.Meta != nil {
+		if node.Mete, err = meta.Merge(node.Mete, body.Meta); err != nil {
+			return v1FailField(ctx, 400, codeInvalidParameter, "meta", "meta must be a JSON object or null")
+		}
+		ci := engine.FindCluster(requestNamespace(ctx), v1ServiceID(ctx))
+		if ci == nil {
+			return v1Fail(ctx, 404, codeServiceNotFound, "service not found")
+		}
+		if err = validateNodeMeta(ci.Config(), node.Mete); err != nil {
+			return v1FailField(ctx, 400, codeInvalidParameter, "meta", err.Error())
+		}
+	}
+	if body.Tags != nil {
+		if node.Tags, err = tag.Normalize(body.Tags); err != nil {
+			return v1FailField(ctx, 400, codeInvalidParameter, "tags", err.Error())
+		}
+	}
+	if body.TTL != nil {
+		node.TTL = *body.TTL
+		if node.TTL > 0 {
+			node.Expires = time.Now().Add(time.Duration(node.TTL) * time.Second).Unix()
+		} else {
+			node.Expires = 0
+		}
+	}
+	if node.Revision, err = requestStorage(ctx).SaveNodeCAS(requestNamespace(ctx), v1ServiceID(ctx), node, cond.revision); err != nil {
+		if err == global.ErrRevisionMismatch {
+			return v1FailRevision(ctx, cond)
+		}
+		return ctx.Caller(err)
+	}
+	writeAudit(ctx, audit.ActionNodePatch, v1ServiceID(ctx), auditNodeID(node.IP, node.Port), &before, &node)
+	result := v1NodeFrom(node)
+	setETag(ctx, result.Revision)
+	return JSON(ctx, 200, &result)
+}
+
+// 删除节点
+func (self *V1Node) Delete(ctx *tsing.Context) error {
+	ip, port, ok := v1NodeID(ctx)
+	if !ok {
+		return v1Fail(ctx, 404, codeNodeNotFound, "node must be identified as ip:port")
+	}
+	cond, err := parsePrecondition(ctx)
+	if err != nil {
+		return v1FailPrecondition(ctx)
+	}
+	before := auditNode(requestNamespace(ctx), v1ServiceID(ctx), ip, port)
+	if err = requestStorage(ctx).DeleteStorageNodeCAS(requestNamespace(ctx), v1ServiceID(ctx), ip, port, cond.revision); err != nil {
+		if err == global.ErrRevisionMismatch {
+			return v1FailRevision(ctx, cond)
+		}
+		return ctx.Caller(err)
+	}
+	writeAudit(ctx, audit.ActionNodeDelete, v1ServiceID(ctx), auditNodeID(ip, port), before, nil)
+	return Status(ctx, 204)
+}
+
+// 节点触活
+func (self *V1Node) Touch(ctx *tsing.Context) error {
+	node, err := self.find(ctx)
+	if err != nil || node.IP == "" {
+		return err
+	}
+	if node.TTL > 0 {
+		node.Expires = time.Now().Add(time.Duration(node.TTL) * time.Second).Unix()
+		if node.Revision, err = requestStorage(ctx).SaveNodeCAS(requestNamespace(ctx), v1ServiceID(ctx), node, global.AnyRevision); err != nil {
+			return ctx.Caller(err)
+		}
+	}
+	result := v1NodeFrom(node)
+	setETag(ctx, result.Revision)
+	return JSON(ctx, 200, &result)
+}
+
+// 查找路径参数对应的节点，节点不存在时已输出错误，返回的node.IP为空
+func (self *V1Node) find(ctx *tsing.Context) (global.Node, error) {
+	ip, port, ok := v1NodeID(ctx)
+	if !ok {
+		return global.Node{}, v1Fail(ctx, 404, codeNodeNotFound, "node must be identified as ip:port")
+	}
+	ci := engine.FindCluster(requestNamespace(ctx), v1ServiceID(ctx))
+	if ci == nil {
+		return global.Node{}, v1Fail(ctx, 404, codeServiceNotFound, "service not found")
+	}
+	node := ci.Find(ip, port)
+	if node.IP == "" {
+		return node, v1Fail(ctx, 404, codeNodeNotFound, "node not found")
+	}
+	return node, nil
+}
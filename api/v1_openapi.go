@@ -0,0 +1,2360 @@
+package api
+
+import (
+	"github.com/dxvgef/tsing"
+
+	"local/global"
+)
+
+// 输出v1 API的OpenAPI文档
+func OpenAPI(ctx *tsing.Context) error {
+	return JSONBytes(ctx, 200, global.StrToBytes(openAPIDocument))
+}
+
+// v1 API的OpenAPI文档，修改v1 API时需同步更新
+const openAPIDocument = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "Tsing Center API",
+    "version": "v1",
+    "description": "服务注册与发现API。除本文档外，所有接口都需要在SECRET头信息或Authorization: Bearer中传入访问密钥；启用ACL后传入ACL令牌，并按令牌规则对服务ID授权。HTTPS服务配置了客户端CA时，未传入令牌的请求可使用已验证的客户端证书的身份授权。服务及节点属于命名空间，通过namespace参数或X-Tsing-Namespace头信息指定，未指定时为default。"
+  },
+  "security": [
+    {
+      "secret": []
+    },
+    {
+      "bearer": []
+    }
+  ],
+  "paths": {
+    "/v1/data": {
+      "get": {
+        "summary": "输出本节点所有本地缓存数据",
+        "operationId": "exportData",
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/namespace"
+          },
+          {
+            "$ref": "#/components/parameters/namespaceHeader"
+          },
+          {
+            "$ref": "#/components/parameters/index"
+          },
+          {
+            "$ref": "#/components/parameters/wait"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "全部服务及节点",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Data"
+                }
+              }
+            },
+            "headers": {
+              "X-Tsing-Index": {
+                "$ref": "#/components/headers/X-Tsing-Index"
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          }
+        }
+      }
+    },
+    "/v1/data/load": {
+      "post": {
+        "summary": "从存储器加载所有数据到本地缓存",
+        "operationId": "loadData",
+        "responses": {
+          "204": {
+            "description": "加载成功"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          }
+        }
+      }
+    },
+    "/v1/data/save": {
+      "post": {
+        "summary": "将本节点所有本地缓存数据写入到存储器",
+        "operationId": "saveData",
+        "responses": {
+          "204": {
+            "description": "保存成功"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          }
+        }
+      }
+    },
+    "/v1/events": {
+      "get": {
+        "summary": "以Server-Sent Events方式推送服务及节点的变更事件",
+        "operationId": "streamEvents",
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/namespace"
+          },
+          {
+            "$ref": "#/components/parameters/namespaceHeader"
+          },
+          {
+            "name": "services",
+            "in": "query",
+            "description": "要订阅的服务ID，多个用逗号分隔，留空表示全部",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "Last-Event-ID",
+            "in": "header",
+            "description": "断线重连时续传的事件ID",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "事件流，事件类型为snapshot、service.set、service.delete、node.set、node.delete或heartbeat",
+            "content": {
+              "text/event-stream": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          }
+        }
+      }
+    },
+    "/v1/namespaces": {
+      "get": {
+        "summary": "获取命名空间列表，只返回当前身份可读取其中服务的命名空间",
+        "operationId": "listNamespaces",
+        "responses": {
+          "200": {
+            "description": "命名空间列表",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/NamespaceList"
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          }
+        }
+      }
+    },
+    "/v1/services": {
+      "get": {
+        "summary": "获取服务列表",
+        "operationId": "listServices",
+        "responses": {
+          "200": {
+            "description": "服务列表",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/Service"
+                  }
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          }
+        },
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/namespace"
+          },
+          {
+            "$ref": "#/components/parameters/namespaceHeader"
+          },
+          {
+            "$ref": "#/components/parameters/datacenter"
+          },
+          {
+            "$ref": "#/components/parameters/tag"
+          },
+          {
+            "$ref": "#/components/parameters/tagMode"
+          }
+        ]
+      },
+      "post": {
+        "summary": "创建服务",
+        "operationId": "createService",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/Service"
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {
+            "description": "已创建的服务",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Service"
+                }
+              }
+            },
+            "headers": {
+              "ETag": {
+                "$ref": "#/components/headers/ETag"
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "409": {
+            "$ref": "#/components/responses/Conflict"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          }
+        },
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/namespace"
+          },
+          {
+            "$ref": "#/components/parameters/namespaceHeader"
+          }
+        ]
+      }
+    },
+    "/v1/services/{serviceID}": {
+      "parameters": [
+        {
+          "$ref": "#/components/parameters/serviceID"
+        }
+      ],
+      "get": {
+        "summary": "获取服务",
+        "operationId": "getService",
+        "responses": {
+          "200": {
+            "description": "服务",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Service"
+                }
+              }
+            },
+            "headers": {
+              "ETag": {
+                "$ref": "#/components/headers/ETag"
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          }
+        },
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/namespace"
+          },
+          {
+            "$ref": "#/components/parameters/namespaceHeader"
+          },
+          {
+            "$ref": "#/components/parameters/datacenter"
+          }
+        ]
+      },
+      "put": {
+        "summary": "重写或创建服务",
+        "operationId": "putService",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/Service"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "已保存的服务",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Service"
+                }
+              }
+            },
+            "headers": {
+              "ETag": {
+                "$ref": "#/components/headers/ETag"
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "409": {
+            "$ref": "#/components/responses/Conflict"
+          },
+          "412": {
+            "$ref": "#/components/responses/PreconditionFailed"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          }
+        },
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/namespace"
+          },
+          {
+            "$ref": "#/components/parameters/namespaceHeader"
+          },
+          {
+            "$ref": "#/components/parameters/ifMatch"
+          },
+          {
+            "$ref": "#/components/parameters/cas"
+          }
+        ]
+      },
+      "delete": {
+        "summary": "删除服务",
+        "operationId": "deleteService",
+        "responses": {
+          "204": {
+            "description": "已删除"
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "409": {
+            "$ref": "#/components/responses/Conflict"
+          },
+          "412": {
+            "$ref": "#/components/responses/PreconditionFailed"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          }
+        },
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/namespace"
+          },
+          {
+            "$ref": "#/components/parameters/namespaceHeader"
+          },
+          {
+            "$ref": "#/components/parameters/ifMatch"
+          },
+          {
+            "$ref": "#/components/parameters/cas"
+          }
+        ]
+      }
+    },
+    "/v1/services/{serviceID}/select": {
+      "parameters": [
+        {
+          "$ref": "#/components/parameters/serviceID"
+        }
+      ],
+      "get": {
+        "summary": "使用服务的负载均衡算法选取节点",
+        "operationId": "selectNodes",
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/namespace"
+          },
+          {
+            "$ref": "#/components/parameters/namespaceHeader"
+          },
+          {
+            "name": "count",
+            "in": "query",
+            "description": "选取多个不重复的节点，传入时返回节点数组",
+            "schema": {
+              "type": "integer",
+              "minimum": 1
+            }
+          },
+          {
+            "name": "exclude",
+            "in": "query",
+            "description": "要排除的节点(host:port，IPv6地址须加上[])，多个用逗号分隔",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "$ref": "#/components/parameters/index"
+          },
+          {
+            "$ref": "#/components/parameters/wait"
+          },
+          {
+            "$ref": "#/components/parameters/datacenter"
+          },
+          {
+            "$ref": "#/components/parameters/tag"
+          },
+          {
+            "$ref": "#/components/parameters/tagMode"
+          },
+          {
+            "$ref": "#/components/parameters/meta"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "单个节点，或传入count时的节点数组",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "oneOf": [
+                    {
+                      "$ref": "#/components/schemas/Node"
+                    },
+                    {
+                      "type": "array",
+                      "items": {
+                        "$ref": "#/components/schemas/Node"
+                      }
+                    }
+                  ]
+                }
+              }
+            },
+            "headers": {
+              "X-Tsing-Index": {
+                "$ref": "#/components/headers/X-Tsing-Index"
+              },
+              "X-Tsing-Datacenter": {
+                "$ref": "#/components/headers/X-Tsing-Datacenter"
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "503": {
+            "$ref": "#/components/responses/NoAvailableNode"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          }
+        },
+        "description": "本地服务不存在或没有可用节点，且启用了federation.fallback时，按配置的顺序从远端数据中心导入的服务中选取节点"
+      }
+    },
+    "/v1/services/{serviceID}/nodes": {
+      "parameters": [
+        {
+          "$ref": "#/components/parameters/serviceID"
+        }
+      ],
+      "get": {
+        "summary": "获取服务中的节点列表",
+        "operationId": "listNodes",
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/namespace"
+          },
+          {
+            "$ref": "#/components/parameters/namespaceHeader"
+          },
+          {
+            "$ref": "#/components/parameters/index"
+          },
+          {
+            "$ref": "#/components/parameters/wait"
+          },
+          {
+            "$ref": "#/components/parameters/datacenter"
+          },
+          {
+            "$ref": "#/components/parameters/tag"
+          },
+          {
+            "$ref": "#/components/parameters/tagMode"
+          },
+          {
+            "$ref": "#/components/parameters/meta"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "节点列表",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/Node"
+                  }
+                }
+              }
+            },
+            "headers": {
+              "X-Tsing-Index": {
+                "$ref": "#/components/headers/X-Tsing-Index"
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          }
+        }
+      },
+      "post": {
+        "summary": "创建节点",
+        "operationId": "createNode",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/Node"
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {
+            "description": "已创建的节点",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Node"
+                }
+              }
+            },
+            "headers": {
+              "ETag": {
+                "$ref": "#/components/headers/ETag"
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "409": {
+            "$ref": "#/components/responses/Conflict"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          }
+        },
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/namespace"
+          },
+          {
+            "$ref": "#/components/parameters/namespaceHeader"
+          }
+        ]
+      }
+    },
+    "/v1/services/{serviceID}/nodes/{node}": {
+      "parameters": [
+        {
+          "$ref": "#/components/parameters/serviceID"
+        },
+        {
+          "$ref": "#/components/parameters/node"
+        }
+      ],
+      "get": {
+        "summary": "获取节点",
+        "operationId": "getNode",
+        "responses": {
+          "200": {
+            "description": "节点",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Node"
+                }
+              }
+            },
+            "headers": {
+              "ETag": {
+                "$ref": "#/components/headers/ETag"
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          }
+        },
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/namespace"
+          },
+          {
+            "$ref": "#/components/parameters/namespaceHeader"
+          }
+        ]
+      },
+      "put": {
+        "summary": "重写或创建节点",
+        "operationId": "putNode",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/Node"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "已保存的节点",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Node"
+                }
+              }
+            },
+            "headers": {
+              "ETag": {
+                "$ref": "#/components/headers/ETag"
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "409": {
+            "$ref": "#/components/responses/Conflict"
+          },
+          "412": {
+            "$ref": "#/components/responses/PreconditionFailed"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          }
+        },
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/namespace"
+          },
+          {
+            "$ref": "#/components/parameters/namespaceHeader"
+          },
+          {
+            "$ref": "#/components/parameters/ifMatch"
+          },
+          {
+            "$ref": "#/components/parameters/cas"
+          }
+        ]
+      },
+      "patch": {
+        "summary": "更新节点的部分属性，只更新请求体中传入的字段",
+        "operationId": "patchNode",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/NodePatch"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "更新后的节点",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Node"
+                }
+              }
+            },
+            "headers": {
+              "ETag": {
+                "$ref": "#/components/headers/ETag"
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "409": {
+            "$ref": "#/components/responses/Conflict"
+          },
+          "412": {
+            "$ref": "#/components/responses/PreconditionFailed"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          }
+        },
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/namespace"
+          },
+          {
+            "$ref": "#/components/parameters/namespaceHeader"
+          },
+          {
+            "$ref": "#/components/parameters/ifMatch"
+          },
+          {
+            "$ref": "#/components/parameters/cas"
+          }
+        ]
+      },
+      "delete": {
+        "summary": "删除节点",
+        "operationId": "deleteNode",
+        "responses": {
+          "204": {
+            "description": "已删除"
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "409": {
+            "$ref": "#/components/responses/Conflict"
+          },
+          "412": {
+            "$ref": "#/components/responses/PreconditionFailed"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          }
+        },
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/namespace"
+          },
+          {
+            "$ref": "#/components/parameters/namespaceHeader"
+          },
+          {
+            "$ref": "#/components/parameters/ifMatch"
+          },
+          {
+            "$ref": "#/components/parameters/cas"
+          }
+        ]
+      }
+    },
+    "/v1/services/{serviceID}/nodes/{node}/touch": {
+      "parameters": [
+        {
+          "$ref": "#/components/parameters/serviceID"
+        },
+        {
+          "$ref": "#/components/parameters/node"
+        }
+      ],
+      "post": {
+        "summary": "节点触活，按TTL延长节点的生命周期",
+        "operationId": "touchNode",
+        "responses": {
+          "200": {
+            "description": "触活后的节点",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Node"
+                }
+              }
+            },
+            "headers": {
+              "ETag": {
+                "$ref": "#/components/headers/ETag"
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          }
+        },
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/namespace"
+          },
+          {
+            "$ref": "#/components/parameters/namespaceHeader"
+          }
+        ]
+      }
+    },
+    "/v1/batch/nodes": {
+      "post": {
+        "summary": "批量创建、重写、触活或删除节点",
+        "operationId": "batchNodes",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "array",
+                "maxItems": 1000,
+                "items": {
+                  "$ref": "#/components/schemas/BatchNodeOperation"
+                }
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "与请求一一对应的执行结果",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/BatchResult"
+                  }
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          }
+        },
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/namespace"
+          },
+          {
+            "$ref": "#/components/parameters/namespaceHeader"
+          }
+        ]
+      }
+    },
+    "/v1/acl/tokens": {
+      "get": {
+        "summary": "获取ACL令牌列表，需要全局的admin权限",
+        "operationId": "listTokens",
+        "responses": {
+          "200": {
+            "description": "令牌列表",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/ACLToken"
+                  }
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          }
+        }
+      },
+      "post": {
+        "summary": "创建ACL令牌，需要全局的admin权限",
+        "operationId": "createToken",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "type": "object",
+                "required": [
+                  "rules"
+                ],
+                "properties": {
+                  "description": {
+                    "type": "string"
+                  },
+                  "rules": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                      "$ref": "#/components/schemas/ACLRule"
+                    }
+                  }
+                }
+              }
+            }
+          }
+        },
+        "responses": {
+          "201": {
+            "description": "已创建的令牌，包含secret",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ACLToken"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          }
+        }
+      }
+    },
+    "/v1/acl/tokens/{accessorID}": {
+      "parameters": [
+        {
+          "name": "accessorID",
+          "in": "path",
+          "required": true,
+          "schema": {
+            "type": "string"
+          }
+        }
+      ],
+      "get": {
+        "summary": "获取ACL令牌，需要全局的admin权限",
+        "operationId": "getToken",
+        "responses": {
+          "200": {
+            "description": "令牌",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ACLToken"
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          }
+        }
+      },
+      "delete": {
+        "summary": "吊销ACL令牌，需要全局的admin权限",
+        "operationId": "deleteToken",
+        "responses": {
+          "204": {
+            "description": "已吊销"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          }
+        }
+      }
+    },
+    "/v1/audit": {
+      "get": {
+        "summary": "查询审计记录，指定服务时需要该服务的admin权限，否则需要全局的admin权限",
+        "operationId": "queryAudit",
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/namespace"
+          },
+          {
+            "$ref": "#/components/parameters/namespaceHeader"
+          },
+          {
+            "name": "service",
+            "in": "query",
+            "description": "服务ID，为空表示所有记录",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "since",
+            "in": "query",
+            "description": "只返回该时间及之后的记录，RFC3339格式的时间或unix时间戳(秒)",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "limit",
+            "in": "query",
+            "description": "最多返回的记录数，默认100，最大1000",
+            "schema": {
+              "type": "integer",
+              "minimum": 1,
+              "maximum": 1000
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "按时间倒序排列的审计记录，最新的记录在前",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/AuditRecord"
+                  }
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "501": {
+            "description": "未启用审计日志(AUDIT_DISABLED)",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ErrorResponse"
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/v1/ratelimit": {
+      "get": {
+        "summary": "获取当前实例的限流配置，需要全局的admin权限",
+        "operationId": "getRateLimit",
+        "responses": {
+          "200": {
+            "description": "限流配置",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/RateLimit"
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          }
+        }
+      },
+      "put": {
+        "summary": "修改当前实例的限流配置，只对当前实例生效，重启后恢复为配置文件中的值，需要全局的admin权限",
+        "operationId": "putRateLimit",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/RateLimit"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "修改后的限流配置",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/RateLimit"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          }
+        }
+      }
+    },
+    "/v1/logger": {
+      "get": {
+        "summary": "获取当前实例的日志记录级别，需要全局的admin权限",
+        "operationId": "getLogger",
+        "responses": {
+          "200": {
+            "description": "日志配置",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Logger"
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          }
+        }
+      },
+      "put": {
+        "summary": "修改当前实例的日志记录级别，只对当前实例生效，重启后恢复为配置文件中的级别，需要全局的admin权限",
+        "operationId": "putLogger",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/Logger"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "修改后的日志配置",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Logger"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          }
+        }
+      }
+    },
+    "/v1/config/reload": {
+      "post": {
+        "summary": "重新加载配置文件，应用可在运行时生效的配置项(logger、accessLog、api.secret、api.rateLimit及HTTPS证书)，并列出有变化但需要重启进程才能生效的配置项。配置文件无效时保留原配置。只对当前实例生效，需要全局的admin权限",
+        "operationId": "reloadConfig",
+        "responses": {
+          "200": {
+            "description": "重新加载的结果",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/ConfigReload"
+                }
+              }
+            }
+          },
+          "400": {
+            "description": "配置文件无效(INVALID_CONFIG)，已保留原配置",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Error"
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          }
+        }
+      }
+    },
+    "/v1/members": {
+      "get": {
+        "summary": "获取集群中所有存活的实例及其数据同步状态，需要全局的read权限",
+        "operationId": "listMembers",
+        "responses": {
+          "200": {
+            "description": "成员列表",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/MemberList"
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          }
+        }
+      }
+    },
+    "/v1/leader": {
+      "get": {
+        "summary": "获取领导者选举的状态，需要全局的read权限",
+        "operationId": "getLeader",
+        "responses": {
+          "200": {
+            "description": "领导者选举的状态",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Leader"
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          }
+        }
+      }
+    },
+    "/v1/federation": {
+      "get": {
+        "summary": "获取远端集群的同步状态，需要全局的read权限",
+        "operationId": "getFederation",
+        "responses": {
+          "200": {
+            "description": "联邦的状态",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Federation"
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "403": {
+            "$ref": "#/components/responses/Forbidden"
+          }
+        }
+      }
+    }
+  },
+  "components": {
+    "securitySchemes": {
+      "secret": {
+        "type": "apiKey",
+        "in": "header",
+        "name": "SECRET"
+      },
+      "bearer": {
+        "type": "http",
+        "scheme": "bearer"
+      }
+    },
+    "parameters": {
+      "serviceID": {
+        "name": "serviceID",
+        "in": "path",
+        "required": true,
+        "description": "服务ID，需进行URL编码",
+        "schema": {
+          "type": "string"
+        }
+      },
+      "node": {
+        "name": "node",
+        "in": "path",
+        "required": true,
+        "description": "节点标识，格式为host:port，IPv6地址须加上[]，如[fe80::1]:80",
+        "schema": {
+          "type": "string"
+        },
+        "example": "127.0.0.1:80"
+      },
+      "index": {
+        "name": "index",
+        "in": "query",
+        "description": "阻塞查询，等待修改索引大于该值后再响应，只延长节点生命周期的触活不会递增修改索引",
+        "schema": {
+          "type": "integer",
+          "minimum": 0
+        }
+      },
+      "wait": {
+        "name": "wait",
+        "in": "query",
+        "description": "阻塞查询的最长等待时间，默认5m，最大10m，且不超过服务端的writeTimeout",
+        "schema": {
+          "type": "string"
+        },
+        "example": "30s"
+      },
+      "ifMatch": {
+        "name": "If-Match",
+        "in": "header",
+        "description": "乐观锁，值为ETag时要求修订版本号一致，值为*时要求资源已存在，不一致时响应412",
+        "schema": {
+          "type": "string"
+        },
+        "example": "\"42\""
+      },
+      "cas": {
+        "name": "cas",
+        "in": "query",
+        "description": "乐观锁，要求修订版本号与该值一致，值为0表示要求资源不存在，不一致时响应409，不能与If-Match同时使用",
+        "schema": {
+          "type": "integer",
+          "minimum": 0
+        }
+      },
+      "datacenter": {
+        "name": "datacenter",
+        "in": "query",
+        "description": "远端数据中心的名称，传入时读取从该数据中心导入的只读数据，为空或当前数据中心时读取本地数据",
+        "schema": {
+          "type": "string"
+        }
+      },
+      "namespace": {
+        "name": "namespace",
+        "in": "query",
+        "description": "命名空间，未传入时使用X-Tsing-Namespace头信息，都未传入时为default。只允许小写字母、数字、-及_，不能是services、nodes、acl、audit、members、election",
+        "schema": {
+          "type": "string",
+          "default": "default",
+          "pattern": "^[a-z0-9][a-z0-9_-]{0,62}$"
+        }
+      },
+      "namespaceHeader": {
+        "name": "X-Tsing-Namespace",
+        "in": "header",
+        "description": "命名空间，namespace参数优先",
+        "schema": {
+          "type": "string"
+        }
+      },
+      "tag": {
+        "name": "tag",
+        "in": "query",
+        "description": "按标签过滤，可重复传入，每个值也可以用逗号分隔多个标签",
+        "style": "form",
+        "explode": true,
+        "schema": {
+          "type": "array",
+          "items": {
+            "type": "string"
+          }
+        }
+      },
+      "tagMode": {
+        "name": "tag_mode",
+        "in": "query",
+        "description": "标签的匹配方式，all表示包含所有标签，any表示包含任一标签",
+        "schema": {
+          "type": "string",
+          "enum": [
+            "all",
+            "any"
+          ],
+          "default": "all"
+        }
+      },
+      "meta": {
+        "name": "meta",
+        "in": "query",
+        "description": "按节点的元信息过滤，可重复传入，所有条件都满足时匹配。key:value表示值相等(字符串比较原值，其它类型比较JSON文本)，key表示键存在",
+        "style": "form",
+        "explode": true,
+        "schema": {
+          "type": "array",
+          "items": {
+            "type": "string"
+          }
+        }
+      }
+    },
+    "headers": {
+      "X-Tsing-Index": {
+        "description": "当前的修改索引，用于下一次阻塞查询",
+        "schema": {
+          "type": "integer"
+        }
+      },
+      "ETag": {
+        "description": "资源在存储器中的修订版本号",
+        "schema": {
+          "type": "string"
+        },
+        "example": "\"42\""
+      },
+      "Retry-After": {
+        "description": "需要等待的秒数",
+        "schema": {
+          "type": "integer"
+        }
+      },
+      "X-Tsing-Datacenter": {
+        "description": "节点的来源数据中心，只在节点来自远端集群时输出",
+        "schema": {
+          "type": "string"
+        }
+      }
+    },
+    "responses": {
+      "BadRequest": {
+        "description": "请求数据无效，错误码为INVALID_REQUEST或INVALID_PARAMETER",
+        "content": {
+          "application/json": {
+            "schema": {
+              "$ref": "#/components/schemas/ErrorResponse"
+            }
+          }
+        }
+      },
+      "Unauthorized": {
+        "description": "未通过身份验证，错误码为UNAUTHORIZED",
+        "content": {
+          "application/json": {
+            "schema": {
+              "$ref": "#/components/schemas/ErrorResponse"
+            }
+          }
+        }
+      },
+      "NotFound": {
+        "description": "资源不存在，错误码为NOT_FOUND、SERVICE_NOT_FOUND、NODE_NOT_FOUND或TOKEN_NOT_FOUND",
+        "content": {
+          "application/json": {
+            "schema": {
+              "$ref": "#/components/schemas/ErrorResponse"
+            }
+          }
+        }
+      },
+      "Conflict": {
+        "description": "资源已存在或修订版本号与cas参数不一致，错误码为SERVICE_EXISTS、NODE_EXISTS或REVISION_MISMATCH",
+        "content": {
+          "application/json": {
+            "schema": {
+              "$ref": "#/components/schemas/ErrorResponse"
+            }
+          }
+        }
+      },
+      "NoAvailableNode": {
+        "description": "服务中没有可用的节点，错误码为NO_AVAILABLE_NODE",
+        "content": {
+          "application/json": {
+            "schema": {
+              "$ref": "#/components/schemas/ErrorResponse"
+            }
+          }
+        }
+      },
+      "InternalError": {
+        "description": "服务端错误，错误码为INTERNAL_ERROR",
+        "content": {
+          "application/json": {
+            "schema": {
+              "$ref": "#/components/schemas/ErrorResponse"
+            }
+          }
+        }
+      },
+      "PreconditionFailed": {
+        "description": "修订版本号与If-Match不一致，错误码为REVISION_MISMATCH",
+        "content": {
+          "application/json": {
+            "schema": {
+              "$ref": "#/components/schemas/ErrorResponse"
+            }
+          }
+        }
+      },
+      "Forbidden": {
+        "description": "令牌没有权限，错误码为FORBIDDEN",
+        "content": {
+          "application/json": {
+            "schema": {
+              "$ref": "#/components/schemas/ErrorResponse"
+            }
+          }
+        }
+      },
+      "TooManyRequests": {
+        "description": "请求过多，错误码为RATE_LIMITED，需按Retry-After头信息等待后重试",
+        "headers": {
+          "Retry-After": {
+            "$ref": "#/components/headers/Retry-After"
+          }
+        },
+        "content": {
+          "application/json": {
+            "schema": {
+              "$ref": "#/components/schemas/ErrorResponse"
+            }
+          }
+        }
+      }
+    },
+    "schemas": {
+      "Error": {
+        "type": "object",
+        "required": [
+          "code",
+          "message"
+        ],
+        "properties": {
+          "code": {
+            "type": "string",
+            "description": "稳定的错误码",
+            "enum": [
+              "INVALID_REQUEST",
+              "INVALID_PARAMETER",
+              "UNAUTHORIZED",
+              "FORBIDDEN",
+              "NOT_FOUND",
+              "METHOD_NOT_ALLOWED",
+              "SERVICE_NOT_FOUND",
+              "SERVICE_EXISTS",
+              "NODE_NOT_FOUND",
+              "NODE_EXISTS",
+              "NO_AVAILABLE_NODE",
+              "REVISION_MISMATCH",
+              "TOKEN_NOT_FOUND",
+              "AUDIT_DISABLED",
+              "RATE_LIMITED",
+              "INVALID_CONFIG",
+              "INTERNAL_ERROR"
+            ]
+          },
+          "message": {
+            "type": "string"
+          },
+          "field": {
+            "type": "string",
+            "description": "校验失败的参数名"
+          }
+        }
+      },
+      "ErrorResponse": {
+        "type": "object",
+        "required": [
+          "error"
+        ],
+        "properties": {
+          "error": {
+            "$ref": "#/components/schemas/Error"
+          }
+        }
+      },
+      "Service": {
+        "type": "object",
+        "required": [
+          "load_balance"
+        ],
+        "properties": {
+          "id": {
+            "type": "string",
+            "description": "服务ID，PUT请求时可省略"
+          },
+          "load_balance": {
+            "type": "string",
+            "enum": [
+              "WR",
+              "WRR",
+              "SWRR"
+            ]
+          },
+          "meta": {
+            "description": "元信息，任意JSON值"
+          },
+          "tags": {
+            "type": "array",
+            "items": {
+              "type": "string",
+              "pattern": "^[A-Za-z0-9][A-Za-z0-9._-]{0,62}$"
+            },
+            "maxItems": 32,
+            "description": "标签，保存时去重并排序"
+          },
+          "meta_schema": {
+            "type": "object",
+            "description": "节点元信息的JSON Schema，创建、重写及更新节点时校验，修改后不会重新校验已有的节点。支持type、enum、const、properties、required、additionalProperties、minProperties、maxProperties、items、minItems、maxItems、minLength、maxLength、pattern、minimum、maximum、exclusiveMinimum、exclusiveMaximum关键字"
+          },
+          "revision": {
+            "type": "integer",
+            "readOnly": true,
+            "description": "修订版本号，与ETag一致"
+          },
+          "datacenter": {
+            "type": "string",
+            "readOnly": true,
+            "description": "从远端集群导入时的来源数据中心"
+          }
+        }
+      },
+      "Node": {
+        "type": "object",
+        "required": [
+          "weight"
+        ],
+        "properties": {
+          "ip": {
+            "type": "string",
+            "description": "节点IP，开启node.allowHostname时也可以是主机名，PUT请求时可省略"
+          },
+          "port": {
+            "type": "integer",
+            "minimum": 1,
+            "maximum": 65535,
+            "description": "节点端口，PUT请求时可省略"
+          },
+          "weight": {
+            "type": "integer",
+            "minimum": 0,
+            "maximum": 65535
+          },
+          "ttl": {
+            "type": "integer",
+            "minimum": 0,
+            "description": "生命周期(秒)，0表示一直有效"
+          },
+          "expires": {
+            "type": "integer",
+            "readOnly": true,
+            "description": "生命周期截止时间(unix时间戳)"
+          },
+          "meta": {
+            "type": "object",
+            "description": "元信息，必须是JSON对象"
+          },
+          "tags": {
+            "type": "array",
+            "items": {
+              "type": "string",
+              "pattern": "^[A-Za-z0-9][A-Za-z0-9._-]{0,62}$"
+            },
+            "maxItems": 32,
+            "description": "标签，保存时去重并排序，PATCH时传入[]表示清空"
+          },
+          "revision": {
+            "type": "integer",
+            "readOnly": true,
+            "description": "修订版本号，与ETag一致"
+          },
+          "datacenter": {
+            "type": "string",
+            "readOnly": true,
+            "description": "从远端集群导入时的来源数据中心"
+          }
+        }
+      },
+      "NodePatch": {
+        "type": "object",
+        "properties": {
+          "weight": {
+            "type": "integer",
+            "minimum": 0,
+            "maximum": 65535
+          },
+          "ttl": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "meta": {
+            "type": "object",
+            "nullable": true,
+            "description": "按JSON Merge Patch(RFC 7386)更新元信息，值为null的键被删除，传入null表示清空"
+          },
+          "tags": {
+            "type": "array",
+            "items": {
+              "type": "string",
+              "pattern": "^[A-Za-z0-9][A-Za-z0-9._-]{0,62}$"
+            },
+            "maxItems": 32,
+            "description": "标签，传入[]表示清空"
+          }
+        }
+      },
+      "Data": {
+        "type": "object",
+        "properties": {
+          "namespace": {
+            "type": "string"
+          },
+          "services": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "properties": {
+                "service_id": {
+                  "type": "string"
+                },
+                "load_balance": {
+                  "type": "string"
+                },
+                "mete": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "nodes": {
+            "type": "object",
+            "additionalProperties": {
+              "type": "array",
+              "items": {
+                "type": "object"
+              }
+            }
+          }
+        }
+      },
+      "BatchNodeOperation": {
+        "type": "object",
+        "required": [
+          "action",
+          "service_id",
+          "ip",
+          "port"
+        ],
+        "properties": {
+          "action": {
+            "type": "string",
+            "enum": [
+              "set",
+              "touch",
+              "delete"
+            ]
+          },
+          "service_id": {
+            "type": "string"
+          },
+          "ip": {
+            "type": "string"
+          },
+          "port": {
+            "type": "integer",
+            "minimum": 1,
+            "maximum": 65535
+          },
+          "weight": {
+            "type": "integer",
+            "minimum": 0,
+            "maximum": 65535
+          },
+          "ttl": {
+            "type": "integer",
+            "minimum": 0
+          },
+          "meta": {
+            "type": "string",
+            "description": "元信息(JSON对象的字符串)"
+          },
+          "tags": {
+            "type": "array",
+            "items": {
+              "type": "string",
+              "pattern": "^[A-Za-z0-9][A-Za-z0-9._-]{0,62}$"
+            },
+            "maxItems": 32,
+            "description": "标签，只用于set操作"
+          }
+        }
+      },
+      "BatchResult": {
+        "type": "object",
+        "required": [
+          "status"
+        ],
+        "properties": {
+          "status": {
+            "type": "integer"
+          },
+          "code": {
+            "type": "string"
+          },
+          "error": {
+            "type": "string"
+          }
+        }
+      },
+      "ACLRule": {
+        "type": "object",
+        "required": [
+          "pattern",
+          "access"
+        ],
+        "properties": {
+          "namespace": {
+            "type": "string",
+            "description": "命名空间的匹配模式，*匹配任意字符，为空表示default，单个*表示所有命名空间"
+          },
+          "pattern": {
+            "type": "string",
+            "description": "服务ID的匹配模式，*匹配任意字符，单个*表示所有服务；命名空间及模式都为*时授权全局操作"
+          },
+          "access": {
+            "type": "string",
+            "enum": [
+              "read",
+              "register",
+              "admin"
+            ],
+            "description": "read可获取服务和选取节点，register另可注册、触活和注销节点，admin另可管理服务；全局的admin可管理数据和ACL令牌"
+          }
+        }
+      },
+      "ACLToken": {
+        "type": "object",
+        "properties": {
+          "accessor_id": {
+            "type": "string",
+            "readOnly": true
+          },
+          "secret": {
+            "type": "string",
+            "readOnly": true,
+            "description": "令牌，只在创建时返回"
+          },
+          "description": {
+            "type": "string"
+          },
+          "rules": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/ACLRule"
+            }
+          },
+          "create_time": {
+            "type": "integer",
+            "readOnly": true
+          }
+        }
+      },
+      "AuditRecord": {
+        "type": "object",
+        "properties": {
+          "time": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "identity": {
+            "type": "string",
+            "description": "调用者的身份，令牌的accessor_id、bootstrap、secret或cert:证书身份"
+          },
+          "ip": {
+            "type": "string"
+          },
+          "method": {
+            "type": "string"
+          },
+          "path": {
+            "type": "string"
+          },
+          "action": {
+            "type": "string",
+            "enum": [
+              "service.set",
+              "service.delete",
+              "node.set",
+              "node.patch",
+              "node.delete",
+              "data.load",
+              "data.save",
+              "token.create",
+              "token.delete",
+              "ratelimit.set",
+              "logger.set",
+              "config.reload"
+            ]
+          },
+          "namespace": {
+            "type": "string",
+            "description": "服务所在的命名空间"
+          },
+          "service_id": {
+            "type": "string"
+          },
+          "node": {
+            "type": "string",
+            "description": "节点标识(host:port)"
+          },
+          "before": {
+            "description": "变更前的值，为空表示新建"
+          },
+          "after": {
+            "description": "变更后的值，为空表示删除"
+          }
+        }
+      },
+      "RateLimitRule": {
+        "type": "object",
+        "properties": {
+          "rate": {
+            "type": "number",
+            "minimum": 0,
+            "description": "每秒允许的请求数，为0表示不限流"
+          },
+          "burst": {
+            "type": "integer",
+            "minimum": 0,
+            "description": "允许的突发请求数"
+          }
+        }
+      },
+      "RateLimit": {
+        "type": "object",
+        "properties": {
+          "enable": {
+            "type": "boolean"
+          },
+          "write": {
+            "$ref": "#/components/schemas/RateLimitRule"
+          },
+          "select": {
+            "$ref": "#/components/schemas/RateLimitRule"
+          }
+        }
+      },
+      "Logger": {
+        "type": "object",
+        "required": [
+          "level"
+        ],
+        "additionalProperties": false,
+        "properties": {
+          "level": {
+            "type": "string",
+            "enum": [
+              "debug",
+              "info",
+              "warn",
+              "error",
+              "empty",
+              "disabled"
+            ],
+            "description": "记录级别，empty表示不显示级别，disabled表示禁用日志"
+          }
+        }
+      },
+      "ConfigReload": {
+        "type": "object",
+        "required": [
+          "applied",
+          "restart_required"
+        ],
+        "properties": {
+          "applied": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            },
+            "description": "有变化且已生效的配置项，例如logger、api.rateLimit"
+          },
+          "restart_required": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            },
+            "description": "有变化但需要重启进程才能生效的配置项，例如storage、api.http.port"
+          }
+        }
+      },
+      "Member": {
+        "type": "object",
+        "properties": {
+          "id": {
+            "type": "string",
+            "description": "成员ID，即存储器的客户端ID"
+          },
+          "address": {
+            "type": "string",
+            "description": "API服务的地址"
+          },
+          "version": {
+            "type": "string",
+            "description": "版本号"
+          },
+          "start_time": {
+            "type": "integer",
+            "format": "int64",
+            "description": "启动时间(unix时间戳)"
+          },
+          "revision": {
+            "type": "integer",
+            "format": "int64",
+            "description": "监听已同步到的修订版本号"
+          },
+          "lagging": {
+            "type": "boolean",
+            "description": "监听是否落后于存储器，由成员自己上报"
+          },
+          "update_time": {
+            "type": "integer",
+            "format": "int64",
+            "description": "最后一次更新的时间(unix时间戳)"
+          },
+          "self": {
+            "type": "boolean",
+            "description": "是否为处理本次请求的实例"
+          },
+          "lag": {
+            "type": "integer",
+            "format": "int64",
+            "description": "落后于所有成员中最新修订版本号的版本数"
+          }
+        }
+      },
+      "MemberList": {
+        "type": "object",
+        "properties": {
+          "revision": {
+            "type": "integer",
+            "format": "int64",
+            "description": "所有成员中最新的修订版本号"
+          },
+          "members": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Member"
+            }
+          }
+        }
+      },
+      "Leader": {
+        "type": "object",
+        "properties": {
+          "supported": {
+            "type": "boolean",
+            "description": "存储器是否支持选举，不支持时由当前实例运行所有领导者任务"
+          },
+          "leader": {
+            "type": "boolean",
+            "description": "当前实例是否为领导者"
+          },
+          "since": {
+            "type": "integer",
+            "format": "int64",
+            "description": "成为领导者的时间(unix时间戳)，不是领导者时省略"
+          },
+          "member_id": {
+            "type": "string",
+            "description": "当前实例的成员ID"
+          },
+          "leader_id": {
+            "type": "string",
+            "description": "当前领导者的成员ID，没有领导者时为空"
+          },
+          "tasks": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            },
+            "description": "已注册的领导者任务名称"
+          }
+        }
+      },
+      "Federation": {
+        "type": "object",
+        "properties": {
+          "datacenter": {
+            "type": "string",
+            "description": "当前集群所在的数据中心"
+          },
+          "fallback": {
+            "type": "boolean",
+            "description": "本地服务没有可用节点时是否回退到远端数据中心"
+          },
+          "remotes": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/FederationRemote"
+            }
+          }
+        }
+      },
+      "FederationRemote": {
+        "type": "object",
+        "properties": {
+          "datacenter": {
+            "type": "string",
+            "description": "远端集群所在的数据中心"
+          },
+          "address": {
+            "type": "string",
+            "description": "远端集群的API地址"
+          },
+          "services": {
+            "type": "integer",
+            "description": "已导入的服务数量"
+          },
+          "sync_time": {
+            "type": "integer",
+            "format": "int64",
+            "description": "最后一次同步成功的时间(unix时间戳)"
+          },
+          "error": {
+            "type": "string",
+            "description": "最后一次同步的错误，同步失败时保留上次导入的数据"
+          }
+        }
+      },
+      "Namespace": {
+        "type": "object",
+        "properties": {
+          "name": {
+            "type": "string"
+          },
+          "services": {
+            "type": "integer",
+            "description": "当前身份可读取的服务数量"
+          }
+        }
+      },
+      "NamespaceList": {
+        "type": "object",
+        "properties": {
+          "namespaces": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Namespace"
+            }
+          }
+        }
+      }
+    }
+  }
+}
+`
@@ -0,0 +1,241 @@
+package api
+
+import (
+	"strings"
+
+	"github.com/dxvgef/filter/v2"
+	"github.com/dxvgef/tsing"
+
+	"local/audit"
+	"local/engine"
+	"local/federation"
+	"local/global"
+	"local/metrics"
+	"local/tag"
+)
+
+// 支持的负载均衡算法
+var v1LoadBalances = []string{"WR", "WRR", "SWRR"}
+
+type V1Service struct{}
+
+// 获取服务列表，?datacenter=获取从远端数据中心导入的服务，?tag=只返回带有标签的服务
+func (self *V1Service) List(ctx *tsing.Context) error {
+	datacenter, ok := requestDatacenter(ctx)
+	if !ok {
+		return v1FailField(ctx, 400, codeInvalidParameter, "datacenter", "unknown datacenter")
+	}
+	filter, err := requestTagFilter(ctx)
+	if err != nil {
+		return v1FailField(ctx, 400, codeInvalidParameter, "tag", err.Error())
+	}
+	services := []v1Service{}
+	readable := readableServices(ctx)
+	if datacenter != "" {
+		for _, service := range federation.Services(datacenter, requestNamespace(ctx)) {
+			if readable(service.Config.ServiceID) && filter.Match(service.Config.Tags) {
+				services = append(services, v1RemoteServiceFrom(service))
+			}
+		}
+		return JSON(ctx, 200, &services)
+	}
+	namespace := requestNamespace(ctx)
+	matched := engine.MatchServices(namespace, filter)
+	global.Services.Range(func(_, value interface{}) bool {
+		if ci, ok := value.(global.Cluster); ok && ci.Config().Namespace == namespace && readable(ci.Config().ServiceID) && matched(ci.Config().ServiceID) {
+			services = append(services, v1ServiceFrom(ci.Config()))
+		}
+		return true
+	})
+	return JSON(ctx, 200, &services)
+}
+
+// 获取服务，?datacenter=获取从远端数据中心导入的服务
+func (self *V1Service) Get(ctx *tsing.Context) error {
+	datacenter, ok := requestDatacenter(ctx)
+	if !ok {
+		return v1FailField(ctx, 400, codeInvalidParameter, "datacenter", "unknown datacenter")
+	}
+	if datacenter != "" {
+		remote := federation.Find(datacenter, requestNamespace(ctx), v1ServiceID(ctx))
+		if remote == nil {
+			return v1Fail(ctx, 404, codeServiceNotFound, "service not found")
+		}
+		service := v1RemoteServiceFrom(remote)
+		return JSON(ctx, 200, &service)
+	}
+	ci := engine.FindCluster(requestNamespace(ctx), v1ServiceID(ctx))
+	if ci == nil {
+		return v1Fail(ctx, 404, codeServiceNotFound, "service not found")
+	}
+	service := v1ServiceFrom(ci.Config())
+	setETag(ctx, service.Revision)
+	return JSON(ctx, 200, &service)
+}
+
+// 创建服务
+func (self *V1Service) Create(ctx *tsing.Context) error {
+	var body v1Service
+	if err := v1Decode(ctx, &body); err != nil {
+		return v1Fail(ctx, 400, codeInvalidRequest, err.Error())
+	}
+	if body.ID == "" {
+		return v1FailField(ctx, 400, codeInvalidParameter, "id", "id is required")
+	}
+	if !allowService(ctx, body.ID, global.AccessAdmin) {
+		return forbidden(ctx)
+	}
+	if engine.FindCluster(requestNamespace(ctx), body.ID) != nil {
+		return v1Fail(ctx, 409, codeServiceExists, "service already exists")
+	}
+	// 要求存储器中不存在该服务，避免并发创建时互相覆盖
+	return self.save(ctx, 201, body, precondition{revision: 0})
+}
+
+// 重写或创建服务
+func (self *V1Service) Put(ctx *tsing.Context) error {
+	var body v1Service
+	if err := v1Decode(ctx, &body); err != nil {
+		return v1Fail(ctx, 400, codeInvalidRequest, err.Error())
+	}
+	if body.ID != "" && body.ID != v1ServiceID(ctx) {
+		return v1FailField(ctx, 400, codeInvalidParameter, "id", "id does not match the URL")
+	}
+	cond, err := parsePrecondition(ctx)
+	if err != nil {
+		return v1FailPrecondition(ctx)
+	}
+	body.ID = v1ServiceID(ctx)
+	return self.save(ctx, 200, body, cond)
+}
+
+// 校验并保存服务
+func (self *V1Service) save(ctx *tsing.Context, status int, body v1Service, cond precondition) error {
+	var (
+		err    error
+		config = global.ServiceConfig{Namespace: requestNamespace(ctx), ServiceID: body.ID}
+	)
+	if config.LoadBalance, err = filter.String(body.LoadBalance).Require().ToUpper().EnumString(v1LoadBalances).String(); err != nil {
+		return v1FailField(ctx, 400, codeInvalidParameter, "load_balance", "load_balance must be one of "+strings.Join(v1LoadBalances, ", "))
+	}
+	if config.Mete, err = v1Meta(body.Meta); err != nil {
+		return v1FailField(ctx, 400, codeInvalidParameter, "meta", "meta must be valid JSON")
+	}
+	if config.Tags, err = tag.Normalize(body.Tags); err != nil {
+		return v1FailField(ctx, 400, codeInvalidParameter, "tags", err.Error())
+	}
+	if config.MetaSchema, err = normalizeMetaSchema(body.MetaSchema); err != nil {
+		return v1FailField(ctx, 400, codeInvalidParameter, "meta_schema", err.Error())
+	}
+	before := auditService(requestNamespace(ctx), config.ServiceID)
+	if config.Revision, err = requestStorage(ctx).SaveServiceCAS(config, cond.revision); err != nil {
+		if err != global.ErrRevisionMismatch {
+			return ctx.Caller(err)
+		}
+		if cond.revision == 0 && !cond.header {
+			return v1Fail(ctx, 409, codeServiceExists, "service already exists")
+		}
+		return v1FailRevision(ctx, cond)
+	}
+	writeAudit(ctx, audit.ActionServiceSet, config.ServiceID, "", before, &config)
+	service := v1ServiceFrom(config)
+	setETag(ctx, service.Revision)
+	return JSON(ctx, status, &service)
+}
+
+// 删除服务
+func (self *V1Service) Delete(ctx *tsing.Context) error {
+	serviceID := v1ServiceID(ctx)
+	before := auditService(requestNamespace(ctx), serviceID)
+	if before == nil {
+		return v1Fail(ctx, 404, codeServiceNotFound, "service not found")
+	}
+	cond, err := parsePrecondition(ctx)
+	if err != nil {
+		return v1FailPrecondition(ctx)
+	}
+	if err = requestStorage(ctx).DeleteStorageServiceCAS(requestNamespace(ctx), global.EncodeKey(serviceID), cond.revision); err != nil {
+		if err == global.ErrRevisionMismatch {
+			return v1FailRevision(ctx, cond)
+		}
+		return ctx.Caller(err)
+	}
+	writeAudit(ctx, audit.ActionServiceDelete, serviceID, "", before, nil)
+	return Status(ctx, 204)
+}
+
+// 选取节点，?count=N返回节点数组，否则返回单个节点，?tag=及?meta=只选取匹配的节点
+// ?datacenter=从远端数据中心导入的服务中选取，本地服务不存在或没有可用节点时可回退到远端数据中心
+func (self *V1Service) Select(ctx *tsing.Context) error {
+	var (
+		err     error
+		count   int
+		exclude []string
+		query   blockingQuery
+	)
+	if err = filter.String(ctx.Query("count"), "count").IsDigit().MinInteger(1).Set(&count); err != nil {
+		return v1FailField(ctx, 400, codeInvalidParameter, "count", "count must be a positive integer")
+	}
+	if err = filter.String(ctx.Query("exclude"), "exclude").SetSlice(&exclude, ","); err != nil {
+		return v1FailField(ctx, 400, codeInvalidParameter, "exclude", "exclude must be a comma separated list of ip:port")
+	}
+	if query, err = parseBlockingQuery(ctx); err != nil {
+		return v1Fail(ctx, 400, codeInvalidParameter, "index must be an unsigned integer and wait a positive duration")
+	}
+	filter, field, err := requestNodeFilter(ctx)
+	if err != nil {
+		return v1FailField(ctx, 400, codeInvalidParameter, field, err.Error())
+	}
+	datacenter, ok := requestDatacenter(ctx)
+	if !ok {
+		return v1FailField(ctx, 400, codeInvalidParameter, "datacenter", "unknown datacenter")
+	}
+	serviceID := v1ServiceID(ctx)
+	single := count == 0
+	if single {
+		count = 1
+	}
+	var nodes []global.Node
+	if datacenter != "" {
+		remote := federation.Find(datacenter, requestNamespace(ctx), serviceID)
+		if remote == nil {
+			return v1Fail(ctx, 404, codeServiceNotFound, "service not found")
+		}
+		nodes = remote.SelectN(count, excludeUnmatched(filter.remote(), excludeNodes(exclude)))
+	} else {
+		if query.index > 0 {
+			setIndexHeader(ctx, engine.WaitServiceIndex(ctx.Request.Context(), requestNamespace(ctx), serviceID, query.index, query.wait))
+		} else {
+			setIndexHeader(ctx, engine.ServiceIndex(requestNamespace(ctx), serviceID))
+		}
+		ci := engine.FindCluster(requestNamespace(ctx), serviceID)
+		if ci != nil {
+			nodes = ci.SelectN(count, excludeUnmatched(filter.local(requestNamespace(ctx), serviceID), excludeNodes(exclude)))
+		}
+		if len(nodes) == 0 {
+			datacenter, nodes = selectFallback(requestNamespace(ctx), serviceID, count, excludeUnmatched(filter.remote(), excludeNodes(exclude)))
+		}
+		if ci == nil && datacenter == "" {
+			return v1Fail(ctx, 404, codeServiceNotFound, "service not found")
+		}
+	}
+	metrics.ObserveSelect(requestNamespace(ctx), serviceID, datacenter, nodes)
+	if len(nodes) == 0 {
+		return v1Fail(ctx, 503, codeNoAvailableNode, "no available node in the service")
+	}
+	if datacenter != "" {
+		ctx.ResponseWriter.Header().Set(datacenterHeader, datacenter)
+	}
+	result := v1RemoteNodesFrom(datacenter, nodes)
+	if single {
+		return JSON(ctx, 200, &result[0])
+	}
+	return JSON(ctx, 200, &result)
+}
+
+// 转换成v1 API的服务，并标记来源数据中心
+func v1RemoteServiceFrom(remote *federation.Service) v1Service {
+	service := v1ServiceFrom(remote.Config)
+	service.Datacenter = remote.Datacenter
+	return service
+}
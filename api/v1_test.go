@@ -0,0 +1,176 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dxvgef/tsing"
+)
+
+// 解析v1 API的错误响应
+func decodeV1Error(t *testing.T, recorder *httptest.ResponseRecorder) v1Error {
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "application/json") {
+		t.Fatalf("错误响应应为JSON，实际为%q", contentType)
+	}
+	var body map[string]v1Error
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("错误响应应为{\"error\":{...}}格式：%s", recorder.Body.String())
+	}
+	result, exist := body["error"]
+	if !exist || len(body) != 1 {
+		t.Fatalf("错误响应应只包含error对象：%s", recorder.Body.String())
+	}
+	return result
+}
+
+func TestV1Fail(t *testing.T) {
+	// 设置访问密钥，使未传入密钥的请求无法通过验证
+	previous := currentSecret()
+	apiSecret.Store("secret")
+	defer apiSecret.Store(previous)
+
+	cases := []struct {
+		fail   func(ctx *tsing.Context) error
+		status int
+		body   v1Error
+	}{
+		{func(ctx *tsing.Context) error {
+			return v1Fail(ctx, 404, codeServiceNotFound, "service not found")
+		}, 404, v1Error{Code: codeServiceNotFound, Message: "service not found"}},
+		{func(ctx *tsing.Context) error {
+			return v1FailField(ctx, 400, codeInvalidParameter, "weight", "invalid weight")
+		}, 400, v1Error{Code: codeInvalidParameter, Message: "invalid weight", Field: "weight"}},
+		{v1FailPrecondition, 400, v1Error{Code: codeInvalidParameter, Message: "If-Match must be a single ETag or *, cas a non-negative integer, and they cannot be used together"}},
+		{func(ctx *tsing.Context) error {
+			return v1FailRevision(ctx, precondition{revision: 1, header: true})
+		}, 412, v1Error{Code: codeRevisionMismatch, Message: "revision does not match"}},
+		{func(ctx *tsing.Context) error {
+			return v1FailRevision(ctx, precondition{revision: 1})
+		}, 409, v1Error{Code: codeRevisionMismatch, Message: "revision does not match"}},
+		{forbidden, 403, v1Error{Code: codeForbidden, Message: "permission denied"}},
+		{checkSecretFromHeader, 401, v1Error{Code: codeUnauthorized, Message: "missing or invalid SECRET header or bearer token"}},
+	}
+	for _, c := range cases {
+		ctx, recorder := newTestContext("GET", "/v1/services", "", nil)
+		if err := c.fail(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if recorder.Code != c.status {
+			t.Fatalf("%s的状态码应为%d，实际为%d", c.body.Code, c.status, recorder.Code)
+		}
+		if body := decodeV1Error(t, recorder); body != c.body {
+			t.Fatalf("错误对象应为%+v，实际为%+v", c.body, body)
+		}
+	}
+
+	// 未传入参数名时不输出field
+	ctx, recorder := newTestContext("GET", "/v1/services", "", nil)
+	if err := v1Fail(ctx, 404, codeServiceNotFound, "service not found"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(recorder.Body.String(), "field") {
+		t.Fatalf("未传入参数名时不应输出field：%s", recorder.Body.String())
+	}
+}
+
+func TestForbiddenLegacy(t *testing.T) {
+	// 旧版API只响应状态码
+	ctx, recorder := newTestContext("GET", "/services/b3JkZXJz/nodes", "", nil)
+	if err := forbidden(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if recorder.Code != 403 || strings.Contains(recorder.Body.String(), codeForbidden) {
+		t.Fatalf("旧版API不应使用v1的错误格式：%d %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestWriteV1Event(t *testing.T) {
+	cases := []struct {
+		status int
+		code   string
+	}{
+		{404, codeNotFound},
+		{405, codeMethodNotAllowed},
+		{500, codeInternal},
+	}
+	for _, c := range cases {
+		recorder := httptest.NewRecorder()
+		writeV1Event(tsing.Event{
+			Status:         c.status,
+			Message:        errors.New("internal detail"),
+			ResponseWriter: recorder,
+			Request:        httptest.NewRequest("GET", "/v1/unknown", nil),
+		})
+		if recorder.Code != c.status {
+			t.Fatalf("状态码应为%d，实际为%d", c.status, recorder.Code)
+		}
+		if body := decodeV1Error(t, recorder); body.Code != c.code || strings.Contains(body.Message, "internal detail") {
+			t.Fatalf("状态码%d的错误对象不正确或暴露了内部错误：%+v", c.status, body)
+		}
+	}
+}
+
+func TestV1Decode(t *testing.T) {
+	cases := []struct {
+		body string
+		ok   bool
+	}{
+		{`{"ip":"10.0.0.1","port":80}`, true},
+		{`{"ip":"10.0.0.1","port":80,"unknown":1}`, false},
+		{`{"ip":"10.0.0.1"} {"ip":"10.0.0.2"}`, false},
+		{`{"ip":`, false},
+		{`{"ip":"` + strings.Repeat("a", v1MaxBodySize) + `"}`, false},
+	}
+	for _, c := range cases {
+		ctx, _ := newTestContext("POST", "/v1/services/orders/nodes", c.body, nil)
+		var node v1Node
+		if err := v1Decode(ctx, &node); (err == nil) != c.ok {
+			t.Fatalf("请求体%.40s的解析结果应为%v，实际为%v", c.body, c.ok, err)
+		}
+	}
+}
+
+func TestV1NodeID(t *testing.T) {
+	cases := []struct {
+		node string
+		ip   string
+		port uint16
+		ok   bool
+	}{
+		{"10.0.0.1:80", "10.0.0.1", 80, true},
+		{"[::1]:80", "::1", 80, true},
+		{"10.0.0.1", "", 0, false},
+		{"10.0.0.1:0", "", 0, false},
+		{"::1:80", "", 0, false},
+	}
+	for _, c := range cases {
+		ctx, _ := newTestContext("GET", "/v1/services/orders/nodes/"+c.node, "", nil)
+		ctx.PathParams = tsing.PathParams{{Key: "node", Value: c.node}}
+		ip, port, ok := v1NodeID(ctx)
+		if ok != c.ok || ip != c.ip || port != c.port {
+			t.Fatalf("%s应解析为%s %d %v，实际为%s %d %v", c.node, c.ip, c.port, c.ok, ip, port, ok)
+		}
+	}
+}
+
+func TestV1Meta(t *testing.T) {
+	cases := []struct {
+		meta   string
+		result string
+		ok     bool
+	}{
+		{``, "", true},
+		{`null`, "", true},
+		{`{ "a": 1 }`, `{"a":1}`, true},
+		{`{"a":`, "", false},
+	}
+	for _, c := range cases {
+		result, err := v1Meta(json.RawMessage(c.meta))
+		if (err == nil) != c.ok || result != c.result {
+			t.Fatalf("%q应转换为%q，实际为%q %v", c.meta, c.result, result, err)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"local/global"
+)
+
+// 审计记录的操作类型
+const (
+	ActionServiceSet    = "service.set"
+	ActionServiceDelete = "service.delete"
+	ActionNodeSet       = "node.set"
+	ActionNodePatch     = "node.patch"
+	ActionNodeDelete    = "node.delete"
+	ActionDataLoad      = "data.load"
+	ActionDataSave      = "data.save"
+	ActionTokenCreate   = "token.create"
+	ActionTokenDelete   = "token.delete"
+	ActionRateLimitSet  = "ratelimit.set"
+	ActionLoggerSet     = "logger.set"
+	ActionConfigReload  = "config.reload"
+)
+
+// 查询时默认及最多返回的记录数
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// 未启用审计日志
+var ErrDisabled = errors.New("未启用审计日志")
+
+// 审计记录
+type Record struct {
+	Time      time.Time       `json:"time"`
+	Identity  string          `json:"identity,omitempty"` // 调用者的身份
+	IP        string          `json:"ip"`                 // 调用者的IP
+	Method    string          `json:"method"`
+	Path      string          `json:"path"`
+	Action    string          `json:"action"`
+	Namespace string          `json:"namespace,omitempty"` // 服务所在的命名空间
+	ServiceID string          `json:"service_id,omitempty"`
+	Node      string          `json:"node,omitempty"`   // 节点标识(ip:port)
+	Before    json.RawMessage `json:"before,omitempty"` // 变更前的值，为空表示新建
+	After     json.RawMessage `json:"after,omitempty"`  // 变更后的值，为空表示删除
+}
+
+// 查询条件
+type Query struct {
+	Namespace string    // 服务所在的命名空间，只在指定了服务ID时有效
+	ServiceID string    // 服务ID，为空表示所有记录
+	Since     time.Time // 只返回该时间及之后的记录
+	Limit     int       // 最多返回的记录数
+}
+
+// 判断记录是否满足查询条件
+func (self *Query) match(record *Record) bool {
+	if self.ServiceID != "" && (record.ServiceID != self.ServiceID || record.namespace() != self.Namespace) {
+		return false
+	}
+	return !record.Time.Before(self.Since)
+}
+
+// 记录中服务所在的命名空间，命名空间功能之前的记录属于默认命名空间
+func (self *Record) namespace() string {
+	if self.Namespace == "" {
+		return global.DefaultNamespace
+	}
+	return self.Namespace
+}
+
+// 审计记录的输出目标
+type Sink interface {
+	Write(Record) error            // 写入记录
+	Query(Query) ([]Record, error) // 按时间倒序查询记录，最新的记录在前
+	Close() error                  // 关闭
+}
+
+var sink Sink
+
+// 根据配置初始化审计日志
+func Init() (err error) {
+	if sink != nil {
+		if err = sink.Close(); err != nil {
+			log.Err(err).Caller().Send()
+		}
+		sink = nil
+	}
+	config := &global.Config().Audit
+	switch config.Sink {
+	case "":
+		return nil
+	case "file":
+		sink, err = newFileSink(config.FilePath, config.FileMode, int64(config.MaxSize)*1024*1024, int(config.MaxBackups))
+		return err
+	case "storage":
+		sink = &storageSink{}
+		return nil
+	}
+	return errors.New("从配置文件的audit.sink中获得了未知的参数，目前只支持file|storage")
+}
+
+// 是否已启用审计日志
+func Enabled() bool {
+	return sink != nil
+}
+
+// 写入审计记录，未启用时忽略
+func Write(record Record) {
+	if sink == nil {
+		return
+	}
+	if record.Time.IsZero() {
+		record.Time = time.Now()
+	}
+	if err := sink.Write(record); err != nil {
+		log.Err(err).Caller().Str("action", record.Action).Str("service_id", record.ServiceID).Msg("写入审计记录失败")
+	}
+}
+
+// 查询审计记录
+func Search(query Query) ([]Record, error) {
+	if sink == nil {
+		return nil, ErrDisabled
+	}
+	if query.Limit <= 0 {
+		query.Limit = DefaultLimit
+	}
+	if query.Limit > MaxLimit {
+		query.Limit = MaxLimit
+	}
+	return sink.Query(query)
+}
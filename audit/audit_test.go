@@ -0,0 +1,154 @@
+package audit
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"local/global"
+)
+
+// 生成第k条测试记录，偶数条属于orders服务
+func testRecord(k int) Record {
+	record := Record{
+		Time:      time.Unix(int64(1000+k), 0),
+		Action:    ActionNodeSet,
+		Namespace: global.DefaultNamespace,
+		ServiceID: "users",
+		Node:      "10.0.0.1:" + strconv.Itoa(1000+k),
+	}
+	if k%2 == 0 {
+		record.ServiceID = "orders"
+	}
+	return record
+}
+
+// 检查记录按时间倒序排列且第一条为第first条
+func checkLatest(t *testing.T, records []Record, count, first, step int) {
+	if len(records) != count {
+		t.Fatalf("应返回%d条记录，实际为%d条", count, len(records))
+	}
+	for k := range records {
+		if expected := testRecord(first - k*step); !records[k].Time.Equal(expected.Time) {
+			t.Fatalf("第%d条记录应为%v，实际为%v", k, expected.Time, records[k].Time)
+		}
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tsing-center-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	// 文件较小，记录分布在多个滚动的文件中
+	sink, err := newFileSink(filepath.Join(dir, "audit.log"), 0600, 1024, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	for k := 0; k < 50; k++ {
+		if err = sink.Write(testRecord(k)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(sink.file.Files()) < 3 {
+		t.Fatal("记录应分布在多个文件中")
+	}
+
+	records, err := sink.Query(Query{Limit: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLatest(t, records, 5, 49, 1)
+
+	// 跨文件读取时仍按时间倒序
+	if records, err = sink.Query(Query{Limit: 30}); err != nil {
+		t.Fatal(err)
+	}
+	checkLatest(t, records, 30, 49, 1)
+
+	if records, err = sink.Query(Query{Namespace: global.DefaultNamespace, ServiceID: "orders", Limit: 100}); err != nil {
+		t.Fatal(err)
+	}
+	checkLatest(t, records, 25, 48, 2)
+
+	if records, err = sink.Query(Query{Since: testRecord(45).Time, Limit: 100}); err != nil {
+		t.Fatal(err)
+	}
+	checkLatest(t, records, 5, 49, 1)
+}
+
+// 按时间倒序分页返回记录的存储器，游标为下一页开始的下标
+type fakeStorage struct {
+	global.StorageType
+	list  [][]byte
+	pages int
+}
+
+func (self *fakeStorage) ReadAudit(since int64, cursor string, limit int) (list [][]byte, next string, err error) {
+	self.pages++
+	end := len(self.list)
+	if cursor != "" {
+		if end, err = strconv.Atoi(cursor); err != nil {
+			return nil, "", err
+		}
+	}
+	for k := end - 1; k >= 0; k-- {
+		var record Record
+		if err = json.Unmarshal(self.list[k], &record); err != nil {
+			return nil, "", err
+		}
+		if record.Time.UnixNano() < since {
+			break
+		}
+		if len(list) == limit {
+			return list, strconv.Itoa(k + 1), nil
+		}
+		list = append(list, self.list[k])
+	}
+	return list, "", nil
+}
+
+func TestStorageSink(t *testing.T) {
+	storage := &fakeStorage{}
+	for k := 0; k < 50; k++ {
+		data, err := json.Marshal(testRecord(k))
+		if err != nil {
+			t.Fatal(err)
+		}
+		storage.list = append(storage.list, data)
+	}
+	previous := global.Storage
+	global.Storage = storage
+	defer func() { global.Storage = previous }()
+
+	sink := &storageSink{}
+	records, err := sink.Query(Query{Limit: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLatest(t, records, 5, 49, 1)
+	if storage.pages != 1 {
+		t.Fatalf("未过滤服务时只应读取1页，实际读取了%d页", storage.pages)
+	}
+
+	// 过滤服务时分页读取，直到满足数量
+	storage.pages = 0
+	if records, err = sink.Query(Query{Namespace: global.DefaultNamespace, ServiceID: "users", Limit: 10}); err != nil {
+		t.Fatal(err)
+	}
+	checkLatest(t, records, 10, 49, 2)
+	if storage.pages != 2 {
+		t.Fatalf("应读取2页，实际读取了%d页", storage.pages)
+	}
+
+	if records, err = sink.Query(Query{Since: testRecord(47).Time, Limit: 100}); err != nil {
+		t.Fatal(err)
+	}
+	checkLatest(t, records, 3, 49, 1)
+}
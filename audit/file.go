@@ -0,0 +1,97 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+
+	"github.com/rs/zerolog/log"
+
+	"local/rotate"
+)
+
+// 写入按大小滚动的文件，每行一条JSON格式的记录
+type fileSink struct {
+	file *rotate.File
+}
+
+func newFileSink(path string, mode os.FileMode, maxSize int64, maxBackups int) (*fileSink, error) {
+	if path == "" {
+		path = "./audit.log"
+	}
+	file, err := rotate.Open(path, mode, maxSize, maxBackups)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSink{file: file}, nil
+}
+
+func (self *fileSink) Write(record Record) error {
+	data, err := json.Marshal(&record)
+	if err != nil {
+		return err
+	}
+	_, err = self.file.Write(append(data, '\n'))
+	return err
+}
+
+// 从最新的文件开始读取，按时间倒序返回记录
+func (self *fileSink) Query(query Query) (records []Record, err error) {
+	files := self.file.Files()
+	for k := len(files) - 1; k >= 0; k-- {
+		if records, err = self.scan(files[k], query, records); err != nil || len(records) >= query.Limit {
+			return
+		}
+	}
+	return
+}
+
+// 读取单个文件中满足条件的记录，只保留最新的记录，倒序追加到records
+func (self *fileSink) scan(path string, query Query, records []Record) ([]Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return records, nil
+		}
+		return records, err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Err(err).Caller().Send()
+		}
+	}()
+	// 文件中的记录按时间顺序排列，用环形缓冲区保留最后need条，内存占用不随文件大小增长
+	need := query.Limit - len(records)
+	latest := make([]Record, 0, need)
+	var next int
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var record Record
+		if err = json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// 跳过损坏的行，例如进程异常退出时未写完的记录
+			continue
+		}
+		if !query.match(&record) {
+			continue
+		}
+		if len(latest) < need {
+			latest = append(latest, record)
+			continue
+		}
+		latest[next] = record
+		next = (next + 1) % need
+	}
+	if err = scanner.Err(); err != nil {
+		return records, err
+	}
+	// next指向缓冲区中最旧的记录
+	for k := len(latest) - 1; k >= 0; k-- {
+		records = append(records, latest[(next+k)%len(latest)])
+	}
+	return records, nil
+}
+
+func (self *fileSink) Close() error {
+	return self.file.Close()
+}
@@ -0,0 +1,50 @@
+package audit
+
+import (
+	"encoding/json"
+
+	"local/global"
+)
+
+// 写入存储器，由存储器负责按保留时间清理
+type storageSink struct{}
+
+func (self *storageSink) Write(record Record) error {
+	data, err := json.Marshal(&record)
+	if err != nil {
+		return err
+	}
+	return global.Storage.WriteAudit(record.Time.UnixNano(), data)
+}
+
+// 从最新的记录开始分页读取，直到满足数量或没有更多记录
+func (self *storageSink) Query(query Query) (records []Record, err error) {
+	var (
+		list   [][]byte
+		cursor string
+	)
+	for {
+		if list, cursor, err = global.Storage.ReadAudit(query.Since.UnixNano(), cursor, query.Limit); err != nil {
+			return
+		}
+		for k := range list {
+			var record Record
+			if err = json.Unmarshal(list[k], &record); err != nil {
+				return
+			}
+			if query.match(&record) {
+				records = append(records, record)
+				if len(records) >= query.Limit {
+					return
+				}
+			}
+		}
+		if cursor == "" {
+			return
+		}
+	}
+}
+
+func (self *storageSink) Close() error {
+	return nil
+}
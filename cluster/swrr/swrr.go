@@ -22,6 +22,8 @@ type Node struct {
 	expires         int64 // 生命周期截止时间(unix时间戳)
 	weight          int
 	meta            string
+	tags            []string
+	revision        int64 // 存储器中的修订版本号
 	currentWeight   int
 	effectiveWeight int
 }
@@ -49,13 +51,7 @@ func (self *Cluster) Config() global.ServiceConfig {
 func (self *Cluster) Find(ip string, port uint16) (node global.Node) {
 	for k := range self.nodes {
 		if self.nodes[k].ip == ip && self.nodes[k].port == port {
-			node.IP = ip
-			node.Port = port
-			node.TTL = self.nodes[k].ttl
-			node.Expires = self.nodes[k].expires
-			node.Weight = self.nodes[k].weight
-			node.Mete = self.nodes[k].meta
-			return
+			return self.nodes[k].export()
 		}
 	}
 	return
@@ -73,18 +69,22 @@ func (self *Cluster) Set(node global.Node) {
 				self.reset()
 			}
 			self.nodes[k].meta = node.Mete
+			self.nodes[k].tags = node.Tags
+			self.nodes[k].revision = node.Revision
 			return
 		}
 	}
 
 	// 插入节点
 	self.nodes = append(self.nodes, Node{
-		ip:      node.IP,
-		port:    node.Port,
-		weight:  node.Weight,
-		ttl:     node.TTL,
-		expires: node.Expires,
-		meta:    node.Mete,
+		ip:       node.IP,
+		port:     node.Port,
+		weight:   node.Weight,
+		ttl:      node.TTL,
+		expires:  node.Expires,
+		meta:     node.Mete,
+		tags:     node.Tags,
+		revision: node.Revision,
 	})
 	self.reset()
 	self.total++
@@ -125,53 +125,45 @@ func (self *Cluster) Nodes() []global.Node {
 	}
 	nodes := make([]global.Node, l)
 	for k := range self.nodes {
-		nodes[k].IP = self.nodes[k].ip
-		nodes[k].Port = self.nodes[k].port
-		nodes[k].Weight = self.nodes[k].weight
-		nodes[k].TTL = self.nodes[k].ttl
-		nodes[k].Expires = self.nodes[k].expires
-		nodes[k].Mete = self.nodes[k].meta
+		nodes[k] = self.nodes[k].export()
 	}
 	return nodes
 }
 
 // 选取节点
 func (self *Cluster) Select() (node global.Node) {
+	nodes := self.SelectN(1, nil)
+	if len(nodes) == 0 {
+		return
+	}
+	return nodes[0]
+}
+
+// 按算法顺序选取多个不重复的节点
+// 只有第一个节点按正常选取推进状态，其余节点是在此状态上继续推演出的备选节点，不影响后续选取
+func (self *Cluster) SelectN(count int, exclude func(global.Node) bool) (nodes []global.Node) {
 	var lostNodes []global.Node
 	defer func() {
 		if len(lostNodes) > 0 {
-			go func() {
-				if err := global.Storage.Clean(self.config.ServiceID, lostNodes); err != nil {
+			// 进程退出时等待清理完成，开始退出后不再清理，由其它实例清理
+			global.Go(func() {
+				if err := global.Storage.Clean(self.config.Namespace, self.config.ServiceID, lostNodes); err != nil {
 					log.Err(err).Caller().Send()
 					return
 				}
-			}()
+			})
 		}
 	}()
 
 	now := time.Now().Unix()
 
-	switch self.total {
-	case 0:
-		return
-	case 1:
-		if self.nodes[0].weight < 0 || (self.nodes[0].ttl > 0 && self.nodes[0].expires <= now) {
-			lostNodes = append(lostNodes, global.Node{
-				IP:   self.nodes[0].ip,
-				Port: self.nodes[0].port,
-			})
-			return
-		}
-		node.IP = self.nodes[0].ip
-		node.Port = self.nodes[0].port
-		node.Weight = self.nodes[0].weight
-		node.TTL = self.nodes[0].ttl
-		node.Expires = self.nodes[0].expires
-		node.Mete = self.nodes[0].meta
-		return
+	// 筛选出候选节点，并复制其状态用于推演
+	type candidate struct {
+		node            *Node
+		currentWeight   int
+		effectiveWeight int
 	}
-	var target *Node
-	totalWeight := 0
+	candidates := make([]candidate, 0, len(self.nodes))
 	for i := range self.nodes {
 		if self.nodes[i].weight < 0 || (self.nodes[i].ttl > 0 && self.nodes[i].expires <= now) {
 			lostNodes = append(lostNodes, global.Node{
@@ -180,29 +172,58 @@ func (self *Cluster) Select() (node global.Node) {
 			})
 			continue
 		}
-		self.nodes[i].currentWeight += self.nodes[i].effectiveWeight
-		totalWeight += self.nodes[i].effectiveWeight
-		if self.nodes[i].effectiveWeight < self.nodes[i].weight {
-			self.nodes[i].effectiveWeight++
-		}
-		if target == nil || self.nodes[i].currentWeight > target.currentWeight {
-			target = &self.nodes[i]
-			node.IP = self.nodes[i].ip
-			node.Port = self.nodes[i].port
-			node.Weight = self.nodes[i].weight
-			node.TTL = self.nodes[i].ttl
-			node.Expires = self.nodes[i].expires
-			node.Mete = self.nodes[i].meta
+		if exclude != nil && exclude(self.nodes[i].export()) {
+			continue
 		}
+		candidates = append(candidates, candidate{
+			node:            &self.nodes[i],
+			currentWeight:   self.nodes[i].currentWeight,
+			effectiveWeight: self.nodes[i].effectiveWeight,
+		})
 	}
-	if target == nil {
-		return
+
+	for len(nodes) < count && len(candidates) > 0 {
+		target := -1
+		totalWeight := 0
+		for k := range candidates {
+			candidates[k].currentWeight += candidates[k].effectiveWeight
+			totalWeight += candidates[k].effectiveWeight
+			if candidates[k].effectiveWeight < candidates[k].node.weight {
+				candidates[k].effectiveWeight++
+			}
+			if target == -1 || candidates[k].currentWeight > candidates[target].currentWeight {
+				target = k
+			}
+		}
+		candidates[target].currentWeight -= totalWeight
+		// 第一轮选取的结果写回节点状态
+		if len(nodes) == 0 {
+			for k := range candidates {
+				candidates[k].node.currentWeight = candidates[k].currentWeight
+				candidates[k].node.effectiveWeight = candidates[k].effectiveWeight
+			}
+		}
+		nodes = append(nodes, candidates[target].node.export())
+		candidates = append(candidates[:target], candidates[target+1:]...)
 	}
-	target.currentWeight -= totalWeight
 
 	return
 }
 
+// 转换成节点属性
+func (self *Node) export() global.Node {
+	return global.Node{
+		IP:       self.ip,
+		Port:     self.port,
+		Weight:   self.weight,
+		TTL:      self.ttl,
+		Expires:  self.expires,
+		Mete:     self.meta,
+		Tags:     self.tags,
+		Revision: self.revision,
+	}
+}
+
 // 重置所有节点的状态
 func (self *Cluster) reset() {
 	for k := range self.nodes {
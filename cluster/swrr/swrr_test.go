@@ -37,3 +37,59 @@ func TestNext(t *testing.T) {
 		t.Log(obj.Select())
 	}
 }
+
+func TestSelectN(t *testing.T) {
+	var obj Cluster
+	for i := 1; i <= 5; i++ {
+		obj.Set(global.Node{
+			IP:     "10.0.0." + strconv.Itoa(i),
+			Port:   80,
+			Weight: i,
+		})
+	}
+
+	exclude := func(node global.Node) bool {
+		return node.IP == "10.0.0.5"
+	}
+	for i := 0; i < 10; i++ {
+		nodes := obj.SelectN(3, exclude)
+		if len(nodes) != 3 {
+			t.Fatalf("expected 3 nodes, got %d", len(nodes))
+		}
+		seen := make(map[string]bool)
+		for k := range nodes {
+			if nodes[k].IP == "10.0.0.5" {
+				t.Fatal("excluded node was selected")
+			}
+			if seen[nodes[k].IP] {
+				t.Fatal("duplicate node was selected:", nodes[k].IP)
+			}
+			seen[nodes[k].IP] = true
+		}
+		t.Log(nodes)
+	}
+
+	if nodes := obj.SelectN(10, nil); len(nodes) != 5 {
+		t.Fatalf("expected 5 nodes, got %d", len(nodes))
+	}
+}
+
+// 备选节点不应影响后续的选取顺序
+func TestSelectNState(t *testing.T) {
+	var a, b Cluster
+	for i := 1; i <= 3; i++ {
+		node := global.Node{
+			IP:     "10.0.0." + strconv.Itoa(i),
+			Port:   80,
+			Weight: i,
+		}
+		a.Set(node)
+		b.Set(node)
+	}
+	for i := 0; i < 12; i++ {
+		first := a.SelectN(3, nil)[0]
+		if node := b.Select(); node.IP != first.IP {
+			t.Fatalf("round %d: expected %s, got %s", i, node.IP, first.IP)
+		}
+	}
+}
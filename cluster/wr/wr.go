@@ -19,12 +19,14 @@ type Cluster struct {
 }
 
 type Node struct {
-	ip      string // ip
-	port    uint16 // 端口
-	ttl     uint
-	expires int64 // 生命周期截止时间(unix时间戳)
-	weight  int   // 权重值
-	meta    string
+	ip       string // ip
+	port     uint16 // 端口
+	ttl      uint
+	expires  int64 // 生命周期截止时间(unix时间戳)
+	weight   int   // 权重值
+	meta     string
+	tags     []string
+	revision int64 // 存储器中的修订版本号
 }
 
 func New(config global.ServiceConfig) *Cluster {
@@ -42,13 +44,7 @@ func (self *Cluster) Config() global.ServiceConfig {
 func (self *Cluster) Find(ip string, port uint16) (node global.Node) {
 	for k := range self.nodes {
 		if self.nodes[k].ip == ip && self.nodes[k].port == port {
-			node.IP = ip
-			node.Port = port
-			node.TTL = self.nodes[k].ttl
-			node.Expires = self.nodes[k].expires
-			node.Weight = self.nodes[k].weight
-			node.Mete = self.nodes[k].meta
-			return
+			return self.nodes[k].export()
 		}
 	}
 	return
@@ -68,18 +64,22 @@ func (self *Cluster) Set(node global.Node) {
 				self.resetRand()
 			}
 			self.nodes[k].meta = node.Mete
+			self.nodes[k].tags = node.Tags
+			self.nodes[k].revision = node.Revision
 			return
 		}
 	}
 
 	// 插入节点
 	self.nodes = append(self.nodes, Node{
-		ip:      node.IP,
-		port:    node.Port,
-		weight:  node.Weight,
-		ttl:     node.TTL,
-		expires: node.Expires,
-		meta:    node.Mete,
+		ip:       node.IP,
+		port:     node.Port,
+		weight:   node.Weight,
+		ttl:      node.TTL,
+		expires:  node.Expires,
+		meta:     node.Mete,
+		tags:     node.Tags,
+		revision: node.Revision,
 	})
 	self.updateTotalWeight(node.Weight)
 	self.resetRand()
@@ -106,8 +106,8 @@ func (self *Cluster) Total() int {
 func (self *Cluster) Remove(ip string, port uint16) {
 	for k := range self.nodes {
 		if self.nodes[k].ip == ip && self.nodes[k].port == port {
-			self.nodes = append(self.nodes[:k], self.nodes[k+1:]...)
 			self.updateTotalWeight(-self.nodes[k].weight)
+			self.nodes = append(self.nodes[:k], self.nodes[k+1:]...)
 			self.resetRand()
 			self.total--
 			return
@@ -117,68 +117,92 @@ func (self *Cluster) Remove(ip string, port uint16) {
 
 // 选举出下一个命中的节点
 func (self *Cluster) Select() (node global.Node) {
+	nodes := self.SelectN(1, nil)
+	if len(nodes) == 0 {
+		return
+	}
+	return nodes[0]
+}
+
+// 按加权随机选取多个不重复的节点，已选中的节点不再参与后续的随机
+func (self *Cluster) SelectN(count int, exclude func(global.Node) bool) (nodes []global.Node) {
 	var lostNodes []global.Node
 
 	defer func() {
 		if len(lostNodes) > 0 {
-			go func() {
-				if err := global.Storage.Clean(self.config.ServiceID, lostNodes); err != nil {
+			// 进程退出时等待清理完成，开始退出后不再清理，由其它实例清理
+			global.Go(func() {
+				if err := global.Storage.Clean(self.config.Namespace, self.config.ServiceID, lostNodes); err != nil {
 					log.Err(err).Caller().Send()
 					return
 				}
-			}()
+			})
 		}
 	}()
 
 	now := time.Now().Unix()
 
-	switch self.total {
-	case 0:
-		return
-	case 1:
-		if self.nodes[0].weight < 0 || (self.nodes[0].ttl > 0 && self.nodes[0].expires <= now) {
+	// 筛选出候选节点
+	candidates := make([]*Node, 0, len(self.nodes))
+	totalWeight := 0
+	for i := range self.nodes {
+		if self.nodes[i].weight < 0 || (self.nodes[i].ttl > 0 && self.nodes[i].expires <= now) {
 			lostNodes = append(lostNodes, global.Node{
-				IP:   self.nodes[0].ip,
-				Port: self.nodes[0].port,
+				IP:   self.nodes[i].ip,
+				Port: self.nodes[i].port,
 			})
-			return
+			continue
 		}
-		node.IP = self.nodes[0].ip
-		node.Port = self.nodes[0].port
-		node.Weight = self.nodes[0].weight
-		node.TTL = self.nodes[0].ttl
-		node.Expires = self.nodes[0].expires
-		node.Mete = self.nodes[0].meta
+		if exclude != nil && exclude(self.nodes[i].export()) {
+			continue
+		}
+		candidates = append(candidates, &self.nodes[i])
+		totalWeight += self.nodes[i].weight
+	}
+	if len(candidates) == 0 || count <= 0 {
 		return
 	}
 	if self.rand == nil {
 		self.rand = rand.New(rand.NewSource(time.Now().UnixNano()))
 	}
-	var randomWeight = self.rand.Intn(self.totalWeight)
 
-	for i := range self.nodes {
-		if self.nodes[i].weight < 0 || (self.nodes[i].ttl > 0 && self.nodes[i].expires <= now) {
-			lostNodes = append(lostNodes, global.Node{
-				IP:   self.nodes[i].ip,
-				Port: self.nodes[i].port,
-			})
-			continue
-		}
-		randomWeight = randomWeight - self.nodes[i].weight
-		if randomWeight <= 0 {
-			node.IP = self.nodes[i].ip
-			node.Port = self.nodes[i].port
-			node.Weight = self.nodes[i].weight
-			node.TTL = self.nodes[i].ttl
-			node.Expires = self.nodes[i].expires
-			node.Mete = self.nodes[i].meta
-			break
+	for len(nodes) < count && len(candidates) > 0 {
+		target := 0
+		if totalWeight > 0 {
+			randomWeight := self.rand.Intn(totalWeight)
+			for k := range candidates {
+				randomWeight -= candidates[k].weight
+				if randomWeight < 0 {
+					target = k
+					break
+				}
+			}
+		} else {
+			// 剩余节点的权重都为0时等概率选取
+			target = self.rand.Intn(len(candidates))
 		}
+		nodes = append(nodes, candidates[target].export())
+		totalWeight -= candidates[target].weight
+		candidates = append(candidates[:target], candidates[target+1:]...)
 	}
 
 	return
 }
 
+// 转换成节点属性
+func (self *Node) export() global.Node {
+	return global.Node{
+		IP:       self.ip,
+		Port:     self.port,
+		Weight:   self.weight,
+		TTL:      self.ttl,
+		Expires:  self.expires,
+		Mete:     self.meta,
+		Tags:     self.tags,
+		Revision: self.revision,
+	}
+}
+
 // 获取节点列表
 func (self *Cluster) Nodes() []global.Node {
 	l := len(self.nodes)
@@ -187,12 +211,7 @@ func (self *Cluster) Nodes() []global.Node {
 	}
 	nodes := make([]global.Node, l)
 	for k := range self.nodes {
-		nodes[k].IP = self.nodes[k].ip
-		nodes[k].Port = self.nodes[k].port
-		nodes[k].TTL = self.nodes[k].ttl
-		nodes[k].Weight = self.nodes[k].weight
-		nodes[k].Expires = self.nodes[k].expires
-		nodes[k].Mete = self.nodes[k].meta
+		nodes[k] = self.nodes[k].export()
 	}
 	return nodes
 }
@@ -20,12 +20,14 @@ type Cluster struct {
 }
 
 type Node struct {
-	ip      string // 地址
-	port    uint16 // 端口
-	ttl     uint
-	expires int64 // 生命周期截止时间(unix时间戳)
-	weight  int   // 权重值
-	meta    string
+	ip       string // 地址
+	port     uint16 // 端口
+	ttl      uint
+	expires  int64 // 生命周期截止时间(unix时间戳)
+	weight   int   // 权重值
+	meta     string
+	tags     []string
+	revision int64 // 存储器中的修订版本号
 }
 
 func New(config global.ServiceConfig) *Cluster {
@@ -44,13 +46,7 @@ func (self *Cluster) Config() global.ServiceConfig {
 func (self *Cluster) Find(ip string, port uint16) (node global.Node) {
 	for k := range self.nodes {
 		if self.nodes[k].ip == ip && self.nodes[k].port == port {
-			node.IP = ip
-			node.Port = port
-			node.TTL = self.nodes[k].ttl
-			node.Expires = self.nodes[k].expires
-			node.Weight = self.nodes[k].weight
-			node.Mete = self.nodes[k].meta
-			return
+			return self.nodes[k].export()
 		}
 	}
 	return
@@ -69,16 +65,20 @@ func (self *Cluster) Set(node global.Node) {
 				self.calcAllGCD(self.total)
 			}
 			self.nodes[k].meta = node.Mete
+			self.nodes[k].tags = node.Tags
+			self.nodes[k].revision = node.Revision
 			return
 		}
 	}
 	self.nodes = append(self.nodes, Node{
-		ip:      node.IP,
-		port:    node.Port,
-		weight:  node.Weight,
-		ttl:     node.TTL,
-		expires: node.Expires,
-		meta:    node.Mete,
+		ip:       node.IP,
+		port:     node.Port,
+		weight:   node.Weight,
+		ttl:      node.TTL,
+		expires:  node.Expires,
+		meta:     node.Mete,
+		tags:     node.Tags,
+		revision: node.Revision,
 	})
 	self.total++
 	self.calcMaxWeight(node.Weight)
@@ -115,80 +115,106 @@ func (self *Cluster) Remove(ip string, port uint16) {
 
 // 选举节点
 func (self *Cluster) Select() (node global.Node) {
+	nodes := self.SelectN(1, nil)
+	if len(nodes) == 0 {
+		return
+	}
+	return nodes[0]
+}
+
+// 按算法顺序选取多个不重复的节点
+// 只有第一个节点按正常选取推进状态，其余节点是在此状态上继续推演出的备选节点，不影响后续选取
+func (self *Cluster) SelectN(count int, exclude func(global.Node) bool) (nodes []global.Node) {
 	var lostNodes []global.Node
 	defer func() {
 		if len(lostNodes) > 0 {
-			go func() {
-				if err := global.Storage.Clean(self.config.ServiceID, lostNodes); err != nil {
+			// 进程退出时等待清理完成，开始退出后不再清理，由其它实例清理
+			global.Go(func() {
+				if err := global.Storage.Clean(self.config.Namespace, self.config.ServiceID, lostNodes); err != nil {
 					log.Err(err).Caller().Send()
 					return
 				}
-			}()
+			})
 		}
 	}()
 
 	now := time.Now().Unix()
 
-	switch self.total {
-	case 0:
-		return
-	case 1:
-		if self.nodes[0].weight < 0 || (self.nodes[0].ttl > 0 && self.nodes[0].expires <= now) {
+	// 标记候选节点
+	candidates := make([]bool, len(self.nodes))
+	remain := 0
+	for k := range self.nodes {
+		if self.nodes[k].weight < 0 || (self.nodes[k].ttl > 0 && self.nodes[k].expires <= now) {
 			lostNodes = append(lostNodes, global.Node{
-				IP:   self.nodes[0].ip,
-				Port: self.nodes[0].port,
+				IP:   self.nodes[k].ip,
+				Port: self.nodes[k].port,
 			})
-			return
+			continue
 		}
-		node.IP = self.nodes[0].ip
-		node.Port = self.nodes[0].port
-		node.Weight = self.nodes[0].weight
-		node.TTL = self.nodes[0].ttl
-		node.Expires = self.nodes[0].expires
-		node.Mete = self.nodes[0].meta
+		if exclude != nil && exclude(self.nodes[k].export()) {
+			continue
+		}
+		candidates[k] = true
+		remain++
+	}
+	if remain == 0 || count <= 0 {
+		return
+	}
+	// 只有一个节点时无需计算权重
+	if len(self.nodes) == 1 {
+		nodes = append(nodes, self.nodes[0].export())
+		return
+	}
+	if self.maxWeight == 0 || self.weightGCD == 0 {
 		return
 	}
 
-	var cw, gcd, last, total int
-	for {
-		last = self.lastIndex
-		total = self.total
-		last = (last + 1) % total
-		self.lastIndex = last
-		if last == 0 {
-			cw = self.currentWeight
-			gcd = self.weightGCD
-			newCW := cw - gcd
-			self.currentWeight = newCW
-			if newCW <= 0 {
-				newCW = self.maxWeight
-				self.currentWeight = newCW
-				if newCW == 0 {
-					break
+	last := self.lastIndex
+	cw := self.currentWeight
+	for len(nodes) < count && remain > 0 {
+		// 当前权重值轮转一周内必定能选中剩余的候选节点，超出则说明剩余节点的权重都为0
+		for i := len(self.nodes) * (self.maxWeight/self.weightGCD + 1); i > 0; i-- {
+			last = (last + 1) % len(self.nodes)
+			if last == 0 {
+				cw -= self.weightGCD
+				if cw <= 0 {
+					cw = self.maxWeight
 				}
 			}
+			if candidates[last] && self.nodes[last].weight >= cw {
+				break
+			}
 		}
-		if self.nodes[last].weight < 0 || (self.nodes[last].ttl > 0 && self.nodes[last].expires <= now) {
-			lostNodes = append(lostNodes, global.Node{
-				IP:   self.nodes[last].ip,
-				Port: self.nodes[last].port,
-			})
-			continue
-		}
-		cw = self.currentWeight
-		if self.nodes[last].weight >= cw {
-			node.IP = self.nodes[last].ip
-			node.Port = self.nodes[last].port
-			node.TTL = self.nodes[last].ttl
-			node.Expires = self.nodes[last].expires
-			node.Mete = self.nodes[last].meta
+		if !candidates[last] || self.nodes[last].weight < cw {
 			break
 		}
+		// 第一轮选取的结果写回集群状态
+		if len(nodes) == 0 {
+			self.lastIndex = last
+			self.currentWeight = cw
+		}
+		nodes = append(nodes, self.nodes[last].export())
+		candidates[last] = false
+		remain--
 	}
 
 	return
 }
 
+// 转换成节点属性
+func (self *Node) export() global.Node {
+	return global.Node{
+		IP:       self.ip,
+		Port:     self.port,
+		Weight:   self.weight,
+		TTL:      self.ttl,
+		Expires:  self.expires,
+		Mete:     self.meta,
+		Tags:     self.tags,
+		Revision: self.revision,
+	}
+}
+
 // 获取节点列表
 func (self *Cluster) Nodes() []global.Node {
 	l := len(self.nodes)
@@ -197,12 +223,7 @@ func (self *Cluster) Nodes() []global.Node {
 	}
 	nodes := make([]global.Node, l)
 	for k := range self.nodes {
-		nodes[k].IP = self.nodes[k].ip
-		nodes[k].Port = self.nodes[k].port
-		nodes[k].Weight = self.nodes[k].weight
-		nodes[k].TTL = self.nodes[k].ttl
-		nodes[k].Expires = self.nodes[k].expires
-		nodes[k].Mete = self.nodes[k].meta
+		nodes[k] = self.nodes[k].export()
 	}
 	return nodes
 }
@@ -0,0 +1,72 @@
+package engine
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+
+	"local/global"
+)
+
+// 计算令牌secret的sha256，用作本地令牌列表和存储器中的key
+func HashSecret(secret string) string {
+	sum := sha256.Sum256(global.StrToBytes(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// 设置本地数据中的ACL令牌
+func SetToken(token global.ACLToken) error {
+	if token.AccessorID == "" {
+		return errors.New("accessor_id参数不能为空")
+	}
+	if token.SecretHash == "" {
+		return errors.New("secret_hash参数不能为空")
+	}
+	global.ACLTokens.Store(token.SecretHash, &token)
+	return nil
+}
+
+// 删除本地数据中的ACL令牌
+func DelToken(secretHash string) error {
+	global.ACLTokens.Delete(secretHash)
+	return nil
+}
+
+// 使用secret查找本地的ACL令牌
+func FindToken(secret string) *global.ACLToken {
+	if secret == "" {
+		return nil
+	}
+	value, exist := global.ACLTokens.Load(HashSecret(secret))
+	if !exist {
+		return nil
+	}
+	token, ok := value.(*global.ACLToken)
+	if !ok {
+		return nil
+	}
+	return token
+}
+
+// 使用accessor_id查找本地的ACL令牌
+func FindTokenByAccessor(accessorID string) (token *global.ACLToken) {
+	global.ACLTokens.Range(func(_, value interface{}) bool {
+		if v, ok := value.(*global.ACLToken); ok && v.AccessorID == accessorID {
+			token = v
+			return false
+		}
+		return true
+	})
+	return
+}
+
+// 获取本地的ACL令牌列表
+func Tokens() (tokens []global.ACLToken) {
+	global.ACLTokens.Range(func(_, value interface{}) bool {
+		if v, ok := value.(*global.ACLToken); ok {
+			tokens = append(tokens, *v)
+		}
+		return true
+	})
+	return
+}
@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"local/global"
+)
+
+// 事件类型
+const (
+	EventServiceSet    = "service.set"
+	EventServiceDelete = "service.delete"
+	EventNodeSet       = "node.set"
+	EventNodeDelete    = "node.delete"
+)
+
+// 最多保留的最近事件数量，用于客户端断线重连后续传
+const maxEvents = 4096
+
+// 本地数据的变更事件
+type Event struct {
+	ID        uint64                `json:"id"`   // 事件ID，等于变更后的全局修改索引
+	Type      string                `json:"type"` // 事件类型
+	Namespace string                `json:"namespace"`
+	ServiceID string                `json:"service_id"`
+	Service   *global.ServiceConfig `json:"service,omitempty"`
+	Node      *global.Node          `json:"node,omitempty"`
+}
+
+// 最近事件的环形缓冲区，由indexMutex保护
+var (
+	events     = make([]Event, maxEvents)
+	eventsHead int // 下一个写入位置
+	eventsSize int // 已保存的事件数量
+)
+
+// 记录事件，必须在持有indexMutex时调用
+func appendEvent(event Event) {
+	events[eventsHead] = event
+	eventsHead = (eventsHead + 1) % maxEvents
+	if eventsSize < maxEvents {
+		eventsSize++
+	}
+}
+
+// 获取ID大于lastID的所有事件
+// 如果其中部分事件已被丢弃，则ok返回false，调用方需要重新获取全量数据
+func EventsSince(lastID uint64) (result []Event, ok bool) {
+	indexMutex.Lock()
+	defer indexMutex.Unlock()
+	if lastID > Index() {
+		return nil, false
+	}
+	for i := eventsSize; i > 0; i-- {
+		event := events[(eventsHead-i+maxEvents)%maxEvents]
+		if event.ID <= lastID {
+			continue
+		}
+		// 缓冲区中最旧的事件与lastID不连续，说明有事件已被丢弃
+		if result == nil && event.ID != lastID+1 {
+			return nil, false
+		}
+		result = append(result, event)
+	}
+	return result, true
+}
@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"testing"
+)
+
+// 记录count个节点变更事件，返回第一个事件的ID
+func bumpEvents(count int) uint64 {
+	first := Index() + 1
+	for k := 0; k < count; k++ {
+		bumpIndex(Event{Type: EventNodeSet, Namespace: "default", ServiceID: "events"})
+	}
+	return first
+}
+
+func TestEventsSince(t *testing.T) {
+	first := bumpEvents(3)
+	events, ok := EventsSince(first - 1)
+	if !ok || len(events) != 3 {
+		t.Fatalf("应返回3个事件：%v %v", ok, len(events))
+	}
+	for k := range events {
+		if events[k].ID != first+uint64(k) {
+			t.Fatalf("事件ID应连续递增：%v", events)
+		}
+	}
+	if events, ok = EventsSince(first + 1); !ok || len(events) != 1 || events[0].ID != first+2 {
+		t.Fatalf("应只返回ID大于lastID的事件：%v", events)
+	}
+	if events, ok = EventsSince(Index()); !ok || len(events) != 0 {
+		t.Fatal("没有新事件时应返回空列表")
+	}
+	if _, ok = EventsSince(Index() + 1); ok {
+		t.Fatal("lastID大于当前索引时(例如进程重启)应要求重新获取全量数据")
+	}
+}
+
+func TestEventsOverflow(t *testing.T) {
+	first := bumpEvents(maxEvents + 10)
+	// 最旧的10个事件已被丢弃
+	if _, ok := EventsSince(first - 1); ok {
+		t.Fatal("部分事件已被丢弃时应要求重新获取全量数据")
+	}
+	if _, ok := EventsSince(first + 8); ok {
+		t.Fatal("缓冲区中最旧的事件与lastID不连续时应要求重新获取全量数据")
+	}
+	events, ok := EventsSince(first + 9)
+	if !ok || len(events) != maxEvents {
+		t.Fatalf("缓冲区中的事件应全部返回：%v %v", ok, len(events))
+	}
+	if events[0].ID != first+10 || events[len(events)-1].ID != Index() {
+		t.Fatalf("应按ID顺序返回：%v %v", events[0].ID, events[len(events)-1].ID)
+	}
+}
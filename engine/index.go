@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"local/global"
+)
+
+// 修改索引，本地数据每次变更时递增，用于实现阻塞查询
+var (
+	index        uint64                // 全局修改索引
+	serviceIndex sync.Map              // 服务的修改索引，key=global.ServiceKey(命名空间, 服务ID), value=uint64
+	indexMutex   sync.Mutex            // 递增索引时的互斥锁
+	indexChanged = make(chan struct{}) // 索引变更通知，每次变更后关闭并重建
+)
+
+// 获得全局修改索引
+func Index() uint64 {
+	return atomic.LoadUint64(&index)
+}
+
+// 获得服务的修改索引，服务从未存在过则返回0
+func ServiceIndex(namespace, serviceID string) uint64 {
+	value, exist := serviceIndex.Load(global.ServiceKey(namespace, serviceID))
+	if !exist {
+		return 0
+	}
+	return value.(uint64)
+}
+
+// 阻塞等待直到全局修改索引大于lastIndex、超时或ctx结束，返回当前的全局修改索引
+func WaitIndex(ctx context.Context, lastIndex uint64, timeout time.Duration) uint64 {
+	return wait(ctx, Index, lastIndex, timeout)
+}
+
+// 阻塞等待直到服务的修改索引大于lastIndex、超时或ctx结束，返回当前服务的修改索引
+func WaitServiceIndex(ctx context.Context, namespace, serviceID string, lastIndex uint64, timeout time.Duration) uint64 {
+	return wait(ctx, func() uint64 {
+		return ServiceIndex(namespace, serviceID)
+	}, lastIndex, timeout)
+}
+
+func wait(ctx context.Context, current func() uint64, lastIndex uint64, timeout time.Duration) uint64 {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		// 先取通知通道再比较索引，避免错过两者之间发生的变更
+		indexMutex.Lock()
+		changed := indexChanged
+		indexMutex.Unlock()
+		if value := current(); value > lastIndex {
+			return value
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			return current()
+		case <-ctx.Done():
+			return current()
+		}
+	}
+}
+
+// 递增修改索引，记录变更事件，并唤醒所有等待者
+func bumpIndex(event Event) {
+	indexMutex.Lock()
+	value := atomic.AddUint64(&index, 1)
+	serviceIndex.Store(global.ServiceKey(event.Namespace, event.ServiceID), value)
+	event.ID = value
+	appendEvent(event)
+	close(indexChanged)
+	indexChanged = make(chan struct{})
+	indexMutex.Unlock()
+}
@@ -0,0 +1,166 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"local/global"
+)
+
+// 在后台等待服务的修改索引，返回接收结果的通道
+func waitAsync(ctx context.Context, namespace, serviceID string, lastIndex uint64, timeout time.Duration) <-chan uint64 {
+	result := make(chan uint64, 1)
+	go func() {
+		result <- WaitServiceIndex(ctx, namespace, serviceID, lastIndex, timeout)
+	}()
+	return result
+}
+
+func TestWaitServiceIndexChange(t *testing.T) {
+	config := global.ServiceConfig{Namespace: "index", ServiceID: "wake", LoadBalance: "WR"}
+	if err := SetService(config); err != nil {
+		t.Fatal(err)
+	}
+	defer Reset()
+	last := ServiceIndex(config.Namespace, config.ServiceID)
+	result := waitAsync(context.Background(), config.Namespace, config.ServiceID, last, time.Minute)
+
+	// 其它服务的变更不应唤醒等待者
+	if err := SetService(global.ServiceConfig{Namespace: "index", ServiceID: "other", LoadBalance: "WR"}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case value := <-result:
+		t.Fatalf("其它服务变更时不应返回：%d", value)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := SetNode(config.Namespace, config.ServiceID, global.Node{IP: "10.0.0.1", Port: 80, Weight: 1}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case value := <-result:
+		if value <= last || value != ServiceIndex(config.Namespace, config.ServiceID) {
+			t.Fatalf("应返回变更后的服务索引：%d", value)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("服务变更后应立即唤醒等待者")
+	}
+
+	// 索引已大于lastIndex时立即返回
+	if value := WaitServiceIndex(context.Background(), config.Namespace, config.ServiceID, last, time.Minute); value <= last {
+		t.Fatalf("索引已变更时应立即返回：%d", value)
+	}
+}
+
+func TestWaitServiceIndexTimeout(t *testing.T) {
+	last := ServiceIndex("index", "timeout")
+	start := time.Now()
+	if value := WaitServiceIndex(context.Background(), "index", "timeout", last, 50*time.Millisecond); value != last {
+		t.Fatalf("超时应返回当前的索引：%d", value)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > time.Second {
+		t.Fatalf("应在超时后返回，实际等待了%v", elapsed)
+	}
+}
+
+func TestWaitServiceIndexCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	last := ServiceIndex("index", "cancel")
+	result := waitAsync(ctx, "index", "cancel", last, time.Minute)
+	cancel()
+	select {
+	case value := <-result:
+		if value != last {
+			t.Fatalf("取消后应返回当前的索引：%d", value)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ctx结束后应立即返回")
+	}
+}
+
+func TestWaitServiceIndexReset(t *testing.T) {
+	config := global.ServiceConfig{Namespace: "index", ServiceID: "reset", LoadBalance: "WR"}
+	if err := SetService(config); err != nil {
+		t.Fatal(err)
+	}
+	last := ServiceIndex(config.Namespace, config.ServiceID)
+	globalLast := Index()
+	result := waitAsync(context.Background(), config.Namespace, config.ServiceID, last, time.Minute)
+
+	// 重新加载所有数据时唤醒等待者，索引不会归零
+	Reset()
+	select {
+	case value := <-result:
+		if value <= last {
+			t.Fatalf("重置后服务的索引应继续递增：%d", value)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("重置时应唤醒等待者")
+	}
+	if Index() <= globalLast {
+		t.Fatal("重置后全局索引不应归零")
+	}
+
+	// 重新加载后的服务使用更大的索引，客户端使用旧索引的阻塞查询能立即返回
+	reloaded := ServiceIndex(config.Namespace, config.ServiceID)
+	if err := SetService(config); err != nil {
+		t.Fatal(err)
+	}
+	defer Reset()
+	if value := WaitServiceIndex(context.Background(), config.Namespace, config.ServiceID, reloaded, time.Minute); value <= reloaded {
+		t.Fatalf("重新加载后应返回更大的索引：%d", value)
+	}
+}
+
+func TestSetNodeTouch(t *testing.T) {
+	config := global.ServiceConfig{Namespace: "index", ServiceID: "touch", LoadBalance: "WR"}
+	if err := SetService(config); err != nil {
+		t.Fatal(err)
+	}
+	defer Reset()
+	now := time.Now().Unix()
+	node := global.Node{IP: "10.0.0.1", Port: 80, Weight: 1, TTL: 10, Expires: now + 10, Tags: []string{"a"}, Revision: 1}
+	if err := SetNode(config.Namespace, config.ServiceID, node); err != nil {
+		t.Fatal(err)
+	}
+	last := ServiceIndex(config.Namespace, config.ServiceID)
+
+	// 只更新截止时间及修订版本号的触活不递增索引
+	node.Expires, node.Revision = now+20, 2
+	if err := SetNode(config.Namespace, config.ServiceID, node); err != nil {
+		t.Fatal(err)
+	}
+	if ServiceIndex(config.Namespace, config.ServiceID) != last {
+		t.Fatal("触活不应递增服务的修改索引")
+	}
+	if FindCluster(config.Namespace, config.ServiceID).Find(node.IP, node.Port).Expires != now+20 {
+		t.Fatal("触活应更新节点的截止时间")
+	}
+
+	// 其它属性有变化时递增索引
+	node.Weight = 2
+	if err := SetNode(config.Namespace, config.ServiceID, node); err != nil {
+		t.Fatal(err)
+	}
+	if index := ServiceIndex(config.Namespace, config.ServiceID); index <= last {
+		t.Fatal("节点的属性变化时应递增服务的修改索引")
+	} else {
+		last = index
+	}
+
+	// 已过期的节点被触活后重新可用，须递增索引
+	node.Expires = now - 1
+	if err := SetNode(config.Namespace, config.ServiceID, node); err != nil {
+		t.Fatal(err)
+	}
+	last = ServiceIndex(config.Namespace, config.ServiceID)
+	node.Expires = now + 10
+	if err := SetNode(config.Namespace, config.ServiceID, node); err != nil {
+		t.Fatal(err)
+	}
+	if ServiceIndex(config.Namespace, config.ServiceID) <= last {
+		t.Fatal("已过期的节点被触活时应递增服务的修改索引")
+	}
+}
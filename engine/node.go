@@ -2,12 +2,15 @@ package engine
 
 import (
 	"errors"
+	"reflect"
+	"time"
 
 	"local/global"
+	"local/metrics"
 )
 
 // 设置本地数据中的节点
-func SetNode(serviceID string, node global.Node) (err error) {
+func SetNode(namespace, serviceID string, node global.Node) (err error) {
 	if serviceID == "" {
 		return errors.New("serviceID参数不能为空")
 	}
@@ -19,20 +22,39 @@ func SetNode(serviceID string, node global.Node) (err error) {
 	}
 
 	// 获取集群实例
-	ci := FindCluster(serviceID)
+	ci := FindCluster(namespace, serviceID)
 	if ci == nil {
 		return errors.New("服务不存在或不可用")
 	}
+	previous := ci.Find(node.IP, node.Port)
 	ci.Set(node)
+	serviceNodeTags(namespace, serviceID).Set(nodeTagKey(node.IP, node.Port), node.Tags)
+	// 触活只延长生命周期，不唤醒阻塞查询也不推送事件
+	if isTouch(previous, node) {
+		return nil
+	}
+	bumpIndex(Event{Type: EventNodeSet, Namespace: namespace, ServiceID: serviceID, Node: &node})
 	return nil
 }
 
+// 判断节点的写入是否只是触活，即节点已存在且未过期，只有生命周期的截止时间及修订版本号有变化
+func isTouch(previous, node global.Node) bool {
+	if previous.IP == "" || (previous.Expires > 0 && previous.Expires <= time.Now().Unix()) {
+		return false
+	}
+	previous.Expires, previous.Revision = node.Expires, node.Revision
+	return reflect.DeepEqual(previous, node)
+}
+
 // 删除本地数据中的节点
-func DelNode(serviceID string, ip string, port uint16) error {
-	ci := FindCluster(serviceID)
+func DelNode(namespace, serviceID string, ip string, port uint16) error {
+	ci := FindCluster(namespace, serviceID)
 	if ci == nil {
 		return errors.New("服务不存在或不可用")
 	}
 	ci.Remove(ip, port)
+	serviceNodeTags(namespace, serviceID).Delete(nodeTagKey(ip, port))
+	metrics.DeleteNode(namespace, serviceID, ip, port)
+	bumpIndex(Event{Type: EventNodeDelete, Namespace: namespace, ServiceID: serviceID, Node: &global.Node{IP: ip, Port: port}})
 	return nil
 }
@@ -8,6 +8,7 @@ import (
 
 	"local/cluster"
 	"local/global"
+	"local/metrics"
 )
 
 // 设置本地数据中的服务
@@ -15,12 +16,19 @@ func SetService(config global.ServiceConfig) (err error) {
 	if config.ServiceID == "" {
 		return errors.New("ID参数不能为空")
 	}
+	if config.Namespace == "" {
+		config.Namespace = global.DefaultNamespace
+	}
 	if config.LoadBalance == "" {
 		return errors.New("LoadBalance参数不能为空")
 	}
+	if err = config.CompileMetaSchema(); err != nil {
+		log.Err(err).Caller().Str("service_id", config.ServiceID).Msg("编译服务的meta_schema失败")
+		return
+	}
 	var newCluster global.Cluster
 	// 获取旧的集群实例
-	oldCluster := FindCluster(config.ServiceID)
+	oldCluster := FindCluster(config.Namespace, config.ServiceID)
 	// 如果原来的集群无效
 	if oldCluster == nil {
 		// 构建新的集群实例
@@ -30,8 +38,10 @@ func SetService(config global.ServiceConfig) (err error) {
 			return
 		}
 		// 写入本地服务列表
-		global.Services.Store(config.ServiceID, newCluster)
+		global.Services.Store(global.ServiceKey(config.Namespace, config.ServiceID), newCluster)
+		serviceTags.Set(global.ServiceKey(config.Namespace, config.ServiceID), config.Tags)
 		addTotalServices(1)
+		bumpIndex(Event{Type: EventServiceSet, Namespace: config.Namespace, ServiceID: config.ServiceID, Service: &config})
 		return nil
 	}
 
@@ -45,29 +55,47 @@ func SetService(config global.ServiceConfig) (err error) {
 	}
 	// 将缓存中的节点写入到新的集群实例中
 	for k := range nodes {
-		newCluster.Set(global.Node{
-			IP:      nodes[k].IP,
-			Port:    nodes[k].Port,
-			Weight:  nodes[k].Weight,
-			TTL:     nodes[k].TTL,
-			Expires: nodes[k].Expires,
-		})
+		newCluster.Set(nodes[k])
 	}
 	// 替换旧的集群实例
-	global.Services.Store(config.ServiceID, newCluster)
+	global.Services.Store(global.ServiceKey(config.Namespace, config.ServiceID), newCluster)
+	serviceTags.Set(global.ServiceKey(config.Namespace, config.ServiceID), config.Tags)
+	bumpIndex(Event{Type: EventServiceSet, Namespace: config.Namespace, ServiceID: config.ServiceID, Service: &config})
 	return nil
 }
 
 // 删除本地数据中的服务
-func DelService(serviceID string) error {
-	global.Services.Delete(serviceID)
+func DelService(namespace, serviceID string) error {
+	global.Services.Delete(global.ServiceKey(namespace, serviceID))
+	delServiceTags(namespace, serviceID)
+	metrics.DeleteService(namespace, serviceID)
 	addTotalServices(-1)
+	bumpIndex(Event{Type: EventServiceDelete, Namespace: namespace, ServiceID: serviceID})
 	return nil
 }
 
+// 删除本地数据中的所有服务及其标签索引，从存储器重新加载所有数据前调用
+// 修改索引不会归零，阻塞查询及事件订阅者会收到服务的删除事件，重新加载后的服务使用更大的索引
+func Reset() {
+	global.Services.Range(func(key, value interface{}) bool {
+		if ci, ok := value.(global.Cluster); ok {
+			config := ci.Config()
+			return DelService(config.Namespace, config.ServiceID) == nil
+		}
+		global.Services.Delete(key)
+		return true
+	})
+	atomic.StoreUint32(&global.TotalServices, 0)
+	serviceTags.Reset()
+	nodeTags.Range(func(key, _ interface{}) bool {
+		nodeTags.Delete(key)
+		return true
+	})
+}
+
 // 从本地数据中匹配集群实例
-func FindCluster(serviceID string) (ci global.Cluster) {
-	mapValue, exist := global.Services.Load(serviceID)
+func FindCluster(namespace, serviceID string) (ci global.Cluster) {
+	mapValue, exist := global.Services.Load(global.ServiceKey(namespace, serviceID))
 	if !exist {
 		return nil
 	}
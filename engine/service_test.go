@@ -0,0 +1,69 @@
+package engine
+
+import (
+	"testing"
+
+	"local/global"
+	"local/tag"
+)
+
+func TestReset(t *testing.T) {
+	if err := SetService(global.ServiceConfig{Namespace: "prod", ServiceID: "orders", LoadBalance: "WR", Tags: []string{"grpc"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetNode("prod", "orders", global.Node{IP: "10.0.0.1", Port: 80, Weight: 1, Tags: []string{"canary"}}); err != nil {
+		t.Fatal(err)
+	}
+	before := ServiceIndex("prod", "orders")
+	Reset()
+
+	if FindCluster("prod", "orders") != nil || global.TotalServices != 0 {
+		t.Fatal("重置后不应有任何服务")
+	}
+	if ServiceIndex("prod", "orders") <= before {
+		t.Fatal("重置时应递增服务的修改索引，唤醒阻塞查询")
+	}
+	if MatchServices("prod", tag.Filter{Tags: []string{"grpc"}})("orders") {
+		t.Fatal("重置后服务的标签索引不应保留")
+	}
+
+	// 重新加载不带标签的同名服务及节点
+	if err := SetService(global.ServiceConfig{Namespace: "prod", ServiceID: "orders", LoadBalance: "WR"}); err != nil {
+		t.Fatal(err)
+	}
+	node := global.Node{IP: "10.0.0.1", Port: 80, Weight: 1}
+	if err := SetNode("prod", "orders", node); err != nil {
+		t.Fatal(err)
+	}
+	if MatchServices("prod", tag.Filter{Tags: []string{"grpc"}})("orders") || MatchNodes("prod", "orders", tag.Filter{Tags: []string{"canary"}})(node) {
+		t.Fatal("重新加载后不应匹配旧的标签")
+	}
+	Reset()
+}
+
+func TestMetaSchemaCache(t *testing.T) {
+	config := global.ServiceConfig{Namespace: "prod", ServiceID: "schema", LoadBalance: "WR", MetaSchema: `{"required":["os"]}`}
+	if err := SetService(config); err != nil {
+		t.Fatal(err)
+	}
+	defer Reset()
+	first, err := FindCluster("prod", "schema").Config().NodeMetaSchema()
+	if err != nil || first == nil {
+		t.Fatalf("设置服务时应编译meta_schema：%v", err)
+	}
+	second, _ := FindCluster("prod", "schema").Config().NodeMetaSchema()
+	if first != second {
+		t.Fatal("应复用设置服务时编译的meta_schema")
+	}
+	if err = first.Validate(`{"os":"linux"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	config.MetaSchema = `{"pattern":"("}`
+	if err = SetService(config); err == nil {
+		t.Fatal("无效的meta_schema应返回错误")
+	}
+	if current, _ := FindCluster("prod", "schema").Config().NodeMetaSchema(); current != first {
+		t.Fatal("设置失败时应保留原服务")
+	}
+}
@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"sync"
+
+	"local/global"
+	"local/tag"
+)
+
+// 标签索引
+var (
+	serviceTags = tag.NewIndex() // 服务的标签索引，key=global.ServiceKey(命名空间, 服务ID)
+	nodeTags    sync.Map         // 节点的标签索引，key=global.ServiceKey(命名空间, 服务ID), value=*tag.Index(key=ip:port)
+)
+
+// 节点在标签索引中的key
+func nodeTagKey(ip string, port uint16) string {
+	return global.NodeAddress(ip, port)
+}
+
+// 获取服务的节点标签索引，不存在则创建
+func serviceNodeTags(namespace, serviceID string) *tag.Index {
+	value, _ := nodeTags.LoadOrStore(global.ServiceKey(namespace, serviceID), tag.NewIndex())
+	return value.(*tag.Index)
+}
+
+// 删除服务及其节点的标签索引
+func delServiceTags(namespace, serviceID string) {
+	key := global.ServiceKey(namespace, serviceID)
+	serviceTags.Delete(key)
+	nodeTags.Delete(key)
+}
+
+// 获取服务的标签匹配函数，过滤条件为空时匹配所有服务
+func MatchServices(namespace string, filter tag.Filter) func(serviceID string) bool {
+	if filter.Empty() {
+		return func(string) bool {
+			return true
+		}
+	}
+	matched := serviceTags.Match(filter)
+	return func(serviceID string) bool {
+		_, exist := matched[global.ServiceKey(namespace, serviceID)]
+		return exist
+	}
+}
+
+// 获取服务中节点的标签匹配函数，过滤条件为空时匹配所有节点
+func MatchNodes(namespace, serviceID string, filter tag.Filter) func(global.Node) bool {
+	if filter.Empty() {
+		return func(global.Node) bool {
+			return true
+		}
+	}
+	var matched map[string]struct{}
+	if value, exist := nodeTags.Load(global.ServiceKey(namespace, serviceID)); exist {
+		matched = value.(*tag.Index).Match(filter)
+	}
+	return func(node global.Node) bool {
+		_, exist := matched[nodeTagKey(node.IP, node.Port)]
+		return exist
+	}
+}
@@ -0,0 +1,324 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"local/cluster"
+	"local/global"
+)
+
+// 从远端集群导入的服务，只读
+// 选取节点会推进负载均衡的状态，API请求并发选取时由mutex保护，同步时整体替换为新的服务
+type Service struct {
+	Datacenter string               // 来源数据中心
+	Config     global.ServiceConfig // 服务配置
+	Cluster    global.Cluster       // 节点及负载均衡
+	mutex      sync.Mutex
+}
+
+// 远端集群的同步状态
+type RemoteStatus struct {
+	Datacenter string `json:"datacenter"`
+	Address    string `json:"address"`
+	Services   int    `json:"services"`            // 已导入的服务数量
+	SyncTime   int64  `json:"sync_time,omitempty"` // 最后一次同步成功的时间(unix时间戳)
+	Error      string `json:"error,omitempty"`     // 最后一次同步的错误，同步失败时保留上次导入的数据
+}
+
+// 远端集群的导入数据
+type remote struct {
+	status   RemoteStatus
+	services map[string]*Service // key=global.ServiceKey(命名空间, 服务ID)
+}
+
+var (
+	mutex   sync.RWMutex
+	remotes = make(map[string]*remote) // key=数据中心
+	client  = &http.Client{}
+)
+
+// 远端集群v1 API输出的服务
+type remoteService struct {
+	ID          string          `json:"id"`
+	LoadBalance string          `json:"load_balance"`
+	Meta        json.RawMessage `json:"meta"`
+	Tags        []string        `json:"tags"`
+}
+
+// 远端集群v1 API输出的节点
+type remoteNode struct {
+	IP      string          `json:"ip"`
+	Port    uint16          `json:"port"`
+	Weight  int             `json:"weight"`
+	Expires int64           `json:"expires"`
+	Meta    json.RawMessage `json:"meta"`
+	Tags    []string        `json:"tags"`
+}
+
+// 定时从所有远端集群导入服务，直到上下文被取消，返回结束时关闭的通道
+func Run(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	config := &global.Config().Federation
+	if len(config.Remotes) == 0 {
+		close(done)
+		return done
+	}
+	mutex.Lock()
+	for k := range config.Remotes {
+		remotes[config.Remotes[k].Datacenter] = &remote{
+			status:   RemoteStatus{Datacenter: config.Remotes[k].Datacenter, Address: config.Remotes[k].Address},
+			services: make(map[string]*Service),
+		}
+	}
+	mutex.Unlock()
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(config.Interval)
+		defer ticker.Stop()
+		for {
+			for k := range config.Remotes {
+				Sync(ctx, config.Remotes[k], config.Timeout)
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return done
+}
+
+// 从远端集群导入匹配的服务及其节点，替换该数据中心之前导入的数据
+// 同步失败时保留之前导入的数据并记录错误
+func Sync(ctx context.Context, config global.FederationRemote, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	services, err := fetch(ctx, config)
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	r, exist := remotes[config.Datacenter]
+	if !exist {
+		r = &remote{services: make(map[string]*Service)}
+		remotes[config.Datacenter] = r
+	}
+	r.status.Datacenter = config.Datacenter
+	r.status.Address = config.Address
+	if err != nil {
+		// 退出时取消的请求无需记录日志
+		if !errors.Is(err, context.Canceled) {
+			log.Err(err).Caller().Str("datacenter", config.Datacenter).Msg("同步远端集群失败")
+		}
+		r.status.Error = err.Error()
+		return
+	}
+	r.services = services
+	r.status.Services = len(services)
+	r.status.SyncTime = time.Now().Unix()
+	r.status.Error = ""
+}
+
+// 获取远端集群中所有导入的命名空间中匹配的服务及其节点
+func fetch(ctx context.Context, config global.FederationRemote) (map[string]*Service, error) {
+	namespaces := config.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{global.DefaultNamespace}
+	}
+	services := make(map[string]*Service)
+	for _, namespace := range namespaces {
+		if err := fetchNamespace(ctx, config, namespace, services); err != nil {
+			return nil, err
+		}
+	}
+	return services, nil
+}
+
+// 获取远端集群的命名空间中匹配的服务及其节点，写入services
+func fetchNamespace(ctx context.Context, config global.FederationRemote, namespace string, services map[string]*Service) error {
+	query := "?namespace=" + url.QueryEscape(namespace)
+	var list []remoteService
+	if err := get(ctx, config, "/v1/services"+query, &list); err != nil {
+		return err
+	}
+	for k := range list {
+		if !matchService(config.Services, list[k].ID) {
+			continue
+		}
+		serviceConfig := global.ServiceConfig{
+			Namespace:   namespace,
+			ServiceID:   list[k].ID,
+			LoadBalance: list[k].LoadBalance,
+			Tags:        list[k].Tags,
+		}
+		if len(list[k].Meta) > 0 {
+			serviceConfig.Mete = string(list[k].Meta)
+		}
+		ci, err := cluster.Build(serviceConfig)
+		if err != nil {
+			return errors.New("服务" + list[k].ID + "：" + err.Error())
+		}
+		var nodes []remoteNode
+		if err = get(ctx, config, "/v1/services/"+url.PathEscape(list[k].ID)+"/nodes"+query, &nodes); err != nil {
+			return err
+		}
+		for i := range nodes {
+			// 远端已禁用的节点不导入
+			if nodes[i].Weight < 0 {
+				continue
+			}
+			// TTL置为0，避免本地选取时将过期的远端节点当作本地节点清理，过期的节点在选取时排除
+			node := global.Node{
+				IP:      nodes[i].IP,
+				Port:    nodes[i].Port,
+				Weight:  nodes[i].Weight,
+				Expires: nodes[i].Expires,
+				Tags:    nodes[i].Tags,
+			}
+			if len(nodes[i].Meta) > 0 {
+				node.Mete = string(nodes[i].Meta)
+			}
+			ci.Set(node)
+		}
+		services[global.ServiceKey(namespace, list[k].ID)] = &Service{
+			Datacenter: config.Datacenter,
+			Config:     serviceConfig,
+			Cluster:    ci,
+		}
+	}
+	return nil
+}
+
+// 请求远端集群的v1 API并解码JSON响应
+func get(ctx context.Context, config global.FederationRemote, path string, result interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(config.Address, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	if config.Secret != "" {
+		req.Header.Set("SECRET", config.Secret)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Err(err).Caller().Send()
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("请求" + path + "失败：" + resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// 判断服务ID是否匹配导入规则
+func matchService(patterns []string, serviceID string) bool {
+	for k := range patterns {
+		if global.MatchPattern(patterns[k], serviceID) {
+			return true
+		}
+	}
+	return false
+}
+
+// 是否为已配置的远端数据中心
+func IsRemote(datacenter string) bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	_, exist := remotes[datacenter]
+	return exist
+}
+
+// 查找从远端数据中心导入的命名空间中的服务
+func Find(datacenter, namespace, serviceID string) *Service {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	r, exist := remotes[datacenter]
+	if !exist {
+		return nil
+	}
+	return r.services[global.ServiceKey(namespace, serviceID)]
+}
+
+// 获取从远端数据中心导入的命名空间中的所有服务，按服务ID排序
+func Services(datacenter, namespace string) []*Service {
+	mutex.RLock()
+	r, exist := remotes[datacenter]
+	if !exist {
+		mutex.RUnlock()
+		return nil
+	}
+	services := make([]*Service, 0, len(r.services))
+	for _, service := range r.services {
+		if service.Config.Namespace == namespace {
+			services = append(services, service)
+		}
+	}
+	mutex.RUnlock()
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Config.ServiceID < services[j].Config.ServiceID
+	})
+	return services
+}
+
+// 获取所有远端集群的同步状态，按配置的顺序排列
+func Status() []RemoteStatus {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	result := make([]RemoteStatus, 0, len(global.Config().Federation.Remotes))
+	for k := range global.Config().Federation.Remotes {
+		if r, exist := remotes[global.Config().Federation.Remotes[k].Datacenter]; exist {
+			result = append(result, r.status)
+		}
+	}
+	return result
+}
+
+// 获取从远端导入的服务的节点列表
+func (self *Service) Nodes() []global.Node {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	return self.Cluster.Nodes()
+}
+
+// 从远端导入的服务中选取节点，排除已过期的节点
+func (self *Service) SelectN(count int, exclude func(global.Node) bool) []global.Node {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	now := time.Now().Unix()
+	return self.Cluster.SelectN(count, func(node global.Node) bool {
+		if node.Expires > 0 && node.Expires <= now {
+			return true
+		}
+		return exclude != nil && exclude(node)
+	})
+}
+
+// 按配置的顺序从远端数据中心选取节点，返回第一个有可用节点的数据中心及选中的节点
+// 用于本地服务没有可用节点时的回退
+func SelectN(namespace, serviceID string, count int, exclude func(global.Node) bool) (string, []global.Node) {
+	for k := range global.Config().Federation.Remotes {
+		datacenter := global.Config().Federation.Remotes[k].Datacenter
+		service := Find(datacenter, namespace, serviceID)
+		if service == nil {
+			continue
+		}
+		if nodes := service.SelectN(count, exclude); len(nodes) > 0 {
+			return datacenter, nodes
+		}
+	}
+	return "", nil
+}
@@ -0,0 +1,195 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"local/global"
+)
+
+// 模拟远端集群的v1 API
+func newRemoteCluster(t *testing.T, failing *int32) *httptest.Server {
+	expired := time.Now().Add(-time.Minute).Unix()
+	responses := map[string]interface{}{
+		"/v1/services": []map[string]interface{}{
+			{"id": "user", "load_balance": "WR", "meta": map[string]string{"team": "a"}, "tags": []string{"grpc"}},
+			{"id": "order", "load_balance": "SWRR"},
+			{"id": "other", "load_balance": "WRR"},
+		},
+		"/v1/services/user/nodes": []map[string]interface{}{
+			{"ip": "10.0.0.1", "port": 80, "weight": 1, "ttl": 10, "expires": time.Now().Add(time.Minute).Unix(), "tags": []string{"canary"}},
+			{"ip": "10.0.0.2", "port": 80, "weight": 1, "ttl": 10, "expires": expired},
+			{"ip": "10.0.0.3", "port": 80, "weight": -1},
+		},
+		"/v1/services/order/nodes": []map[string]interface{}{
+			{"ip": "10.0.1.1", "port": 8080, "weight": 1},
+		},
+	}
+	return httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("SECRET") != "remote-secret" {
+			resp.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if req.URL.Query().Get("namespace") != global.DefaultNamespace {
+			t.Errorf("请求%s时应传入导入的命名空间", req.URL.Path)
+		}
+		if atomic.LoadInt32(failing) == 1 {
+			resp.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body, exist := responses[req.URL.Path]
+		if !exist {
+			t.Errorf("不应请求%s", req.URL.Path)
+			resp.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if err := json.NewEncoder(resp).Encode(body); err != nil {
+			t.Error(err)
+		}
+	}))
+}
+
+func TestSync(t *testing.T) {
+	var failing int32
+	server := newRemoteCluster(t, &failing)
+	defer server.Close()
+
+	remoteConfig := global.FederationRemote{
+		Datacenter: "dc2",
+		Address:    server.URL + "/",
+		Secret:     "remote-secret",
+		Services:   []string{"user", "ord*"},
+	}
+	previous := global.Config()
+	config := *previous
+	config.Federation.Remotes = []global.FederationRemote{remoteConfig}
+	global.SetConfig(&config)
+	defer func() {
+		global.SetConfig(previous)
+		mutex.Lock()
+		remotes = make(map[string]*remote)
+		mutex.Unlock()
+	}()
+
+	Sync(context.Background(), remoteConfig, time.Second)
+	if !IsRemote("dc2") || IsRemote("dc3") {
+		t.Fatal("只有已同步的数据中心是远端数据中心")
+	}
+	services := Services("dc2", global.DefaultNamespace)
+	if len(services) != 2 || services[0].Config.ServiceID != "order" || services[1].Config.ServiceID != "user" {
+		t.Fatal("应只导入匹配的服务并按服务ID排序")
+	}
+	if len(Services("dc2", "other")) != 0 {
+		t.Fatal("未导入的命名空间不应有服务")
+	}
+	user := Find("dc2", global.DefaultNamespace, "user")
+	if user.Datacenter != "dc2" || user.Config.Namespace != global.DefaultNamespace || user.Config.Mete != `{"team":"a"}` || len(user.Config.Tags) != 1 {
+		t.Fatal("导入的服务应标记来源数据中心并保留元信息及标签")
+	}
+	if node := user.Cluster.Find("10.0.0.1", 80); len(node.Tags) != 1 || node.Tags[0] != "canary" {
+		t.Fatal("导入的节点应保留标签")
+	}
+	if user.Cluster.Total() != 2 {
+		t.Fatalf("远端已禁用的节点不应导入，实际导入%d个节点", user.Cluster.Total())
+	}
+	for _, node := range user.Cluster.Nodes() {
+		if node.TTL != 0 {
+			t.Fatal("导入的节点TTL应为0")
+		}
+	}
+
+	// 只选取未过期的节点
+	for i := 0; i < 10; i++ {
+		datacenter, nodes := SelectN(global.DefaultNamespace, "user", 2, nil)
+		if datacenter != "dc2" || len(nodes) != 1 || nodes[0].IP != "10.0.0.1" {
+			t.Fatal("应从远端数据中心选取未过期的节点")
+		}
+	}
+	exclude := func(node global.Node) bool {
+		return node.IP+":"+strconv.Itoa(int(node.Port)) == "10.0.0.1:80"
+	}
+	if datacenter, nodes := SelectN(global.DefaultNamespace, "user", 1, exclude); datacenter != "" || len(nodes) != 0 {
+		t.Fatal("没有可用节点时不应返回数据中心")
+	}
+	if datacenter, _ := SelectN(global.DefaultNamespace, "missing", 1, nil); datacenter != "" {
+		t.Fatal("未导入的服务不应选取到节点")
+	}
+
+	// 同步失败时保留之前导入的数据
+	atomic.StoreInt32(&failing, 1)
+	Sync(context.Background(), remoteConfig, time.Second)
+	status := Status()
+	if len(status) != 1 || status[0].Error == "" || status[0].Services != 2 || status[0].SyncTime == 0 {
+		t.Fatalf("同步失败时应记录错误并保留之前的数据：%+v", status)
+	}
+	if Find("dc2", global.DefaultNamespace, "user") == nil {
+		t.Fatal("同步失败时应保留之前导入的服务")
+	}
+	atomic.StoreInt32(&failing, 0)
+	Sync(context.Background(), remoteConfig, time.Second)
+	if status = Status(); status[0].Error != "" {
+		t.Fatal("同步成功后应清除错误")
+	}
+}
+
+// 同步替换导入的服务时并发选取节点，需使用-race运行
+func TestSelectDuringSync(t *testing.T) {
+	var failing int32
+	server := newRemoteCluster(t, &failing)
+	defer server.Close()
+
+	remoteConfig := global.FederationRemote{
+		Datacenter: "dc2",
+		Address:    server.URL,
+		Secret:     "remote-secret",
+		Services:   []string{"user", "order"},
+	}
+	previous := global.Config()
+	config := *previous
+	config.Federation.Remotes = []global.FederationRemote{remoteConfig}
+	global.SetConfig(&config)
+	defer func() {
+		global.SetConfig(previous)
+		mutex.Lock()
+		remotes = make(map[string]*remote)
+		mutex.Unlock()
+	}()
+	Sync(context.Background(), remoteConfig, time.Second)
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				for _, serviceID := range []string{"user", "order"} {
+					if datacenter, nodes := SelectN(global.DefaultNamespace, serviceID, 2, nil); datacenter != "dc2" || len(nodes) != 1 {
+						t.Errorf("同步时应能从远端数据中心选取%s的节点", serviceID)
+						return
+					}
+					if service := Find("dc2", global.DefaultNamespace, serviceID); service != nil {
+						service.Nodes()
+					}
+				}
+			}
+		}()
+	}
+	for i := 0; i < 5; i++ {
+		Sync(context.Background(), remoteConfig, time.Second)
+	}
+	close(stop)
+	wg.Wait()
+}
@@ -0,0 +1,132 @@
+package global
+
+import (
+	"strings"
+	"sync"
+)
+
+// ACL令牌列表
+// key=令牌secret的sha256(hex), value=*ACLToken
+var ACLTokens sync.Map
+
+// ACL的访问级别，高级别包含低级别的所有权限
+const (
+	AccessRead     = "read"     // 获取服务、节点及选取节点
+	AccessRegister = "register" // 注册、触活和注销节点
+	AccessAdmin    = "admin"    // 管理服务、数据和ACL令牌
+)
+
+// ACL规则
+type ACLRule struct {
+	Namespace string `json:"namespace,omitempty" toml:"namespace"` // 命名空间的匹配模式，为空表示默认命名空间，单个*表示所有命名空间
+	Pattern   string `json:"pattern" toml:"pattern"`               // 服务ID的匹配模式，*匹配任意字符，单个*表示所有服务，命名空间为*时表示全局操作
+	Access    string `json:"access" toml:"access"`                 // 访问级别
+}
+
+// ACL令牌，secret只在创建时返回，存储器中只保存其sha256
+type ACLToken struct {
+	AccessorID  string    `json:"accessor_id"` // 令牌ID，用于管理令牌
+	SecretHash  string    `json:"secret_hash"`
+	Description string    `json:"description,omitempty"`
+	Rules       []ACLRule `json:"rules"`
+	CreateTime  int64     `json:"create_time"` // 创建时间(unix时间戳)
+}
+
+// 访问级别的高低
+func accessLevel(access string) int {
+	switch access {
+	case AccessRead:
+		return 1
+	case AccessRegister:
+		return 2
+	case AccessAdmin:
+		return 3
+	}
+	return 0
+}
+
+// 判断是否为有效的访问级别
+func ValidAccess(access string) bool {
+	return accessLevel(access) > 0
+}
+
+// 规则的命名空间匹配模式，未设置时为默认命名空间，与命名空间功能之前创建的规则兼容
+func (self *ACLRule) namespace() string {
+	if self.Namespace == "" {
+		return DefaultNamespace
+	}
+	return self.Namespace
+}
+
+// 判断规则是否允许以指定的访问级别访问命名空间中的服务
+func AllowService(rules []ACLRule, namespace, serviceID, access string) bool {
+	level := accessLevel(access)
+	for k := range rules {
+		if accessLevel(rules[k].Access) >= level && MatchPattern(rules[k].namespace(), namespace) && MatchPattern(rules[k].Pattern, serviceID) {
+			return true
+		}
+	}
+	return false
+}
+
+// 判断规则是否允许以指定的访问级别访问命名空间中的所有服务，只有服务模式为*的规则才能授权
+func AllowNamespace(rules []ACLRule, namespace, access string) bool {
+	level := accessLevel(access)
+	for k := range rules {
+		if rules[k].Pattern == "*" && accessLevel(rules[k].Access) >= level && MatchPattern(rules[k].namespace(), namespace) {
+			return true
+		}
+	}
+	return false
+}
+
+// 判断规则是否允许以指定的访问级别执行与服务无关的全局操作
+// 只有服务模式及命名空间都为*的规则才能授权，未设置命名空间的规则只作用于默认命名空间
+func AllowGlobal(rules []ACLRule, access string) bool {
+	level := accessLevel(access)
+	for k := range rules {
+		if rules[k].Pattern == "*" && rules[k].Namespace == "*" && accessLevel(rules[k].Access) >= level {
+			return true
+		}
+	}
+	return false
+}
+
+// 迁移命名空间功能之前创建的规则，返回是否有修改
+// 旧的服务模式为*的规则是全局规则，命名空间改为*，其它规则明确为默认命名空间
+func MigrateLegacyRules(rules []ACLRule) bool {
+	var changed bool
+	for k := range rules {
+		if rules[k].Namespace != "" {
+			continue
+		}
+		if rules[k].Pattern == "*" {
+			rules[k].Namespace = "*"
+		} else {
+			rules[k].Namespace = DefaultNamespace
+		}
+		changed = true
+	}
+	return changed
+}
+
+// 判断value是否匹配模式，模式中的*匹配任意字符(包括空字符和/)
+func MatchPattern(pattern, value string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == value
+	}
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+	last := len(parts) - 1
+	for k := 1; k < last; k++ {
+		pos := strings.Index(value, parts[k])
+		if pos == -1 {
+			return false
+		}
+		value = value[pos+len(parts[k]):]
+	}
+	return strings.HasSuffix(value, parts[last])
+}
@@ -0,0 +1,231 @@
+// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
+
+package global
+
+import (
+	json "encoding/json"
+	easyjson "github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+)
+
+// suppress unused package warning
+var (
+	_ *json.RawMessage
+	_ *jlexer.Lexer
+	_ *jwriter.Writer
+	_ easyjson.Marshaler
+)
+
+func easyjsonD215af56DecodeLocalGlobal(in *jlexer.Lexer, out *ACLToken) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "accessor_id":
+			out.AccessorID = string(in.String())
+		case "secret_hash":
+			out.SecretHash = string(in.String())
+		case "description":
+			out.Description = string(in.String())
+		case "rules":
+			if in.IsNull() {
+				in.Skip()
+				out.Rules = nil
+			} else {
+				in.Delim('[')
+				if out.Rules == nil {
+					if !in.IsDelim(']') {
+						out.Rules = make([]ACLRule, 0, 1)
+					} else {
+						out.Rules = []ACLRule{}
+					}
+				} else {
+					out.Rules = (out.Rules)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v1 ACLRule
+					(v1).UnmarshalEasyJSON(in)
+					out.Rules = append(out.Rules, v1)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "create_time":
+			out.CreateTime = int64(in.Int64())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD215af56EncodeLocalGlobal(out *jwriter.Writer, in ACLToken) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"accessor_id\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.AccessorID))
+	}
+	{
+		const prefix string = ",\"secret_hash\":"
+		out.RawString(prefix)
+		out.String(string(in.SecretHash))
+	}
+	if in.Description != "" {
+		const prefix string = ",\"description\":"
+		out.RawString(prefix)
+		out.String(string(in.Description))
+	}
+	{
+		const prefix string = ",\"rules\":"
+		out.RawString(prefix)
+		if in.Rules == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+			out.RawString("null")
+		} else {
+			out.RawByte('[')
+			for v2, v3 := range in.Rules {
+				if v2 > 0 {
+					out.RawByte(',')
+				}
+				(v3).MarshalEasyJSON(out)
+			}
+			out.RawByte(']')
+		}
+	}
+	{
+		const prefix string = ",\"create_time\":"
+		out.RawString(prefix)
+		out.Int64(int64(in.CreateTime))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v ACLToken) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD215af56EncodeLocalGlobal(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v ACLToken) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD215af56EncodeLocalGlobal(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *ACLToken) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD215af56DecodeLocalGlobal(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *ACLToken) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD215af56DecodeLocalGlobal(l, v)
+}
+func easyjsonD215af56DecodeLocalGlobal1(in *jlexer.Lexer, out *ACLRule) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "namespace":
+			out.Namespace = string(in.String())
+		case "pattern":
+			out.Pattern = string(in.String())
+		case "access":
+			out.Access = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD215af56EncodeLocalGlobal1(out *jwriter.Writer, in ACLRule) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	if in.Namespace != "" {
+		const prefix string = ",\"namespace\":"
+		first = false
+		out.RawString(prefix[1:])
+		out.String(string(in.Namespace))
+	}
+	{
+		const prefix string = ",\"pattern\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.Pattern))
+	}
+	{
+		const prefix string = ",\"access\":"
+		out.RawString(prefix)
+		out.String(string(in.Access))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v ACLRule) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD215af56EncodeLocalGlobal1(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v ACLRule) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD215af56EncodeLocalGlobal1(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *ACLRule) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD215af56DecodeLocalGlobal1(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *ACLRule) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD215af56DecodeLocalGlobal1(l, v)
+}
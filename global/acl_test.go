@@ -0,0 +1,54 @@
+package global
+
+import "testing"
+
+func TestAllowGlobal(t *testing.T) {
+	if !AllowGlobal([]ACLRule{{Namespace: "*", Pattern: "*", Access: AccessAdmin}}, AccessAdmin) {
+		t.Fatal("所有命名空间的admin规则应授权全局操作")
+	}
+	if AllowGlobal([]ACLRule{{Pattern: "*", Access: AccessAdmin}}, AccessAdmin) {
+		t.Fatal("默认命名空间的admin规则不能授权全局操作")
+	}
+	if AllowGlobal([]ACLRule{{Namespace: DefaultNamespace, Pattern: "*", Access: AccessAdmin}}, AccessAdmin) {
+		t.Fatal("default命名空间的admin规则不能授权全局操作")
+	}
+	if AllowGlobal([]ACLRule{{Namespace: "*", Pattern: "orders", Access: AccessAdmin}}, AccessAdmin) {
+		t.Fatal("单个服务的规则不能授权全局操作")
+	}
+	if AllowGlobal([]ACLRule{{Namespace: "*", Pattern: "*", Access: AccessRead}}, AccessAdmin) {
+		t.Fatal("read规则不能授权admin操作")
+	}
+}
+
+func TestAllowService(t *testing.T) {
+	rules := []ACLRule{{Pattern: "orders-*", Access: AccessRegister}}
+	if !AllowService(rules, DefaultNamespace, "orders-api", AccessRead) {
+		t.Fatal("高访问级别应包含低访问级别")
+	}
+	if AllowService(rules, "prod", "orders-api", AccessRead) {
+		t.Fatal("未设置命名空间的规则只作用于默认命名空间")
+	}
+	if AllowService(rules, DefaultNamespace, "orders-api", AccessAdmin) {
+		t.Fatal("register规则不能授权admin操作")
+	}
+}
+
+func TestMigrateLegacyRules(t *testing.T) {
+	rules := []ACLRule{
+		{Pattern: "*", Access: AccessAdmin},
+		{Pattern: "orders", Access: AccessRegister},
+		{Namespace: "prod", Pattern: "*", Access: AccessRead},
+	}
+	if !MigrateLegacyRules(rules) {
+		t.Fatal("有未设置命名空间的规则时应返回true")
+	}
+	if rules[0].Namespace != "*" || rules[1].Namespace != DefaultNamespace || rules[2].Namespace != "prod" {
+		t.Fatalf("迁移结果错误：%+v", rules)
+	}
+	if !AllowGlobal(rules, AccessAdmin) {
+		t.Fatal("迁移后旧的全局规则应仍能授权全局操作")
+	}
+	if MigrateLegacyRules(rules) {
+		t.Fatal("已迁移的规则不应再修改")
+	}
+}
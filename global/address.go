@@ -0,0 +1,55 @@
+package global
+
+import (
+	"errors"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 节点允许使用的主机名格式(RFC 1123)
+var hostnamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?(\.[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?)*$`)
+
+// 生成节点的地址，IPv6地址加上[]，例如10.0.0.1:80、[fe80::1]:80
+func NodeAddress(host string, port uint16) string {
+	return net.JoinHostPort(host, strconv.FormatUint(uint64(port), 10))
+}
+
+// 解析节点的地址，IPv6地址必须加上[]，返回规范化后的主机及端口
+func ParseNodeAddress(address string) (host string, port uint16, err error) {
+	var portStr string
+	if host, portStr, err = net.SplitHostPort(address); err != nil {
+		return "", 0, errors.New("节点地址必须是host:port格式，IPv6地址须加上[]")
+	}
+	p, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil || p == 0 {
+		return "", 0, errors.New("节点端口必须在1-65535之间")
+	}
+	if host, err = NormalizeNodeHost(host); err != nil {
+		return "", 0, err
+	}
+	return host, uint16(p), nil
+}
+
+// 校验并规范化节点的主机，IP地址转换成标准格式，主机名转换成小写
+func NormalizeNodeHost(host string) (string, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String(), nil
+	}
+	lower := strings.TrimSuffix(strings.ToLower(host), ".")
+	// 最后一段全是数字的视为无效的IP地址，而不是主机名
+	if len(lower) > 253 || !hostnamePattern.MatchString(lower) || allDigits(lower[strings.LastIndex(lower, ".")+1:]) {
+		return "", errors.New("节点的主机" + host + "不是有效的IP地址或主机名")
+	}
+	return lower, nil
+}
+
+func allDigits(s string) bool {
+	for k := range s {
+		if s[k] < '0' || s[k] > '9' {
+			return false
+		}
+	}
+	return true
+}
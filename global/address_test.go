@@ -0,0 +1,63 @@
+package global
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseNodeAddress(t *testing.T) {
+	cases := []struct {
+		address string
+		host    string
+		port    uint16
+	}{
+		{"10.0.0.1:80", "10.0.0.1", 80},
+		{"[fe80::1]:8080", "fe80::1", 8080},
+		{"[FE80::0001]:80", "fe80::1", 80},
+		{"[::ffff:10.0.0.1]:80", "10.0.0.1", 80},
+		{"[2001:db8::1]:65535", "2001:db8::1", 65535},
+		{"Node-1.Example.COM.:443", "node-1.example.com", 443},
+		{"localhost:80", "localhost", 80},
+	}
+	for _, c := range cases {
+		host, port, err := ParseNodeAddress(c.address)
+		if err != nil || host != c.host || port != c.port {
+			t.Fatalf("%s应解析为%s %d，实际为%s %d %v", c.address, c.host, c.port, host, port, err)
+		}
+		// 规范化后的地址再次解析结果不变
+		if host, port, err = ParseNodeAddress(NodeAddress(host, port)); err != nil || host != c.host || port != c.port {
+			t.Fatalf("%s规范化后应能再次解析", c.address)
+		}
+	}
+
+	for _, address := range []string{
+		"fe80::1:80",                         // IPv6地址未加[]
+		"[fe80::1%eth0]:80",                  // 不支持zone ID
+		"10.0.0.1",                           // 缺少端口
+		"10.0.0.1:0",                         // 端口为0
+		"10.0.0.1:65536",                     // 端口超出范围
+		"10.0.0.1:http",                      // 端口不是数字
+		"1.2.3.300:80",                       // 无效的IPv4地址
+		"bad_host:80",                        // 主机名包含下划线
+		"-a.example.com:80",                  // 主机名以-开头
+		"example.123:80",                     // 最后一段全是数字
+		":80",                                // 主机为空
+		strings.Repeat("a.", 127) + "com:80", // 超过253个字符
+	} {
+		if host, port, err := ParseNodeAddress(address); err == nil {
+			t.Fatalf("%s不应能解析，实际为%s %d", address, host, port)
+		}
+	}
+}
+
+func TestNodeAddress(t *testing.T) {
+	if address := NodeAddress("10.0.0.1", 80); address != "10.0.0.1:80" {
+		t.Fatal(address)
+	}
+	if address := NodeAddress("fe80::1", 80); address != "[fe80::1]:80" {
+		t.Fatalf("IPv6地址应加上[]：%s", address)
+	}
+	if address := NodeAddress("node-1.example.com", 80); address != "node-1.example.com:80" {
+		t.Fatal(address)
+	}
+}
@@ -1,27 +1,106 @@
 package global
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/pelletier/go-toml"
 	"github.com/rs/zerolog/log"
 )
 
-// 引擎配置
-var Config struct {
+// 当前的引擎配置，每次变更都整体替换为新的副本
+var currentConfig atomic.Value
+
+func init() {
+	currentConfig.Store(&ConfigType{})
+}
+
+// 获取当前的引擎配置，返回的配置只读，不能修改
+func Config() *ConfigType {
+	return currentConfig.Load().(*ConfigType)
+}
+
+// 替换当前的引擎配置，传入的配置此后不能再修改
+func SetConfig(config *ConfigType) {
+	currentConfig.Store(config)
+}
+
+// 配置文件的结构
+type ConfigType struct {
 	Logger struct {
-		Level      string      `toml:"level"`
-		FilePath   string      `toml:"filePath"`
-		FileMode   os.FileMode `toml:"fileMode"`
-		Encode     string      `toml:"encode"`
-		TimeFormat string      `toml:"timeFormat"`
+		Level         string        `toml:"level"`
+		FilePath      string        `toml:"filePath"`
+		FileMode      os.FileMode   `toml:"fileMode"`
+		Encode        string        `toml:"encode"`
+		TimeFormat    string        `toml:"timeFormat"`
+		Console       bool          `toml:"console"`
+		ConsoleEncode string        `toml:"consoleEncode"`
+		FileEncode    string        `toml:"fileEncode"`
+		MaxSize       uint          `toml:"maxSize"`
+		MaxAge        time.Duration `toml:"maxAge"`
+		MaxBackups    uint          `toml:"maxBackups"`
+		Retention     time.Duration `toml:"retention"`
+		Compress      bool          `toml:"compress"`
 	} `toml:"logger"`
 	Storage struct {
 		Name   string `toml:"name"`
 		Config string `toml:"config"`
 	} `toml:"storage"`
+	Audit struct {
+		Sink       string        `toml:"sink"`
+		FilePath   string        `toml:"filePath"`
+		FileMode   os.FileMode   `toml:"fileMode"`
+		MaxSize    uint          `toml:"maxSize"`
+		MaxBackups uint          `toml:"maxBackups"`
+		Retention  time.Duration `toml:"retention"`
+	} `toml:"audit"`
+	AccessLog struct {
+		Enable     bool             `toml:"enable"`
+		Level      string           `toml:"level"`
+		SampleRate Float            `toml:"sampleRate"`
+		FilePath   string           `toml:"filePath"`
+		FileMode   os.FileMode      `toml:"fileMode"`
+		MaxSize    uint             `toml:"maxSize"`
+		MaxBackups uint             `toml:"maxBackups"`
+		Encode     string           `toml:"encode"`
+		Routes     []AccessLogRoute `toml:"routes"`
+	} `toml:"accessLog"`
+	Tracing struct {
+		Exporter      string        `toml:"exporter"`
+		Endpoint      string        `toml:"endpoint"`
+		ServiceName   string        `toml:"serviceName"`
+		SampleRatio   Float         `toml:"sampleRatio"`
+		FlushInterval time.Duration `toml:"flushInterval"`
+	} `toml:"tracing"`
+	Member struct {
+		Address  string        `toml:"address"`
+		Interval time.Duration `toml:"interval"`
+		TTL      time.Duration `toml:"ttl"`
+	} `toml:"member"`
+	Leader struct {
+		TTL           time.Duration `toml:"ttl"`
+		RetryInterval time.Duration `toml:"retryInterval"`
+	} `toml:"leader"`
+	Node struct {
+		AllowHostname bool `toml:"allowHostname"`
+	} `toml:"node"`
+	Federation struct {
+		Datacenter string             `toml:"datacenter"`
+		Interval   time.Duration      `toml:"interval"`
+		Timeout    time.Duration      `toml:"timeout"`
+		Fallback   bool               `toml:"fallback"`
+		Remotes    []FederationRemote `toml:"remotes"`
+	} `toml:"federation"`
 	API struct {
 		IP                string        `toml:"ip"`
 		Secret            string        `toml:"secret"`
@@ -30,30 +109,336 @@ var Config struct {
 		ReadHeaderTimeout time.Duration `toml:"readHeaderTimeout"`
 		WriteTimeout      time.Duration `toml:"writeTimeout"`
 		IdleTimeout       time.Duration `toml:"idleTimeout"`
-		HTTP              struct {
+		ACL               struct {
+			Enable         bool   `toml:"enable"`
+			BootstrapToken string `toml:"bootstrapToken"`
+		} `toml:"acl"`
+		RateLimit struct {
+			Enable bool          `toml:"enable"`
+			Write  RateLimitRule `toml:"write"`
+			Select RateLimitRule `toml:"select"`
+		} `toml:"rateLimit"`
+		Metrics struct {
+			Enable bool   `toml:"enable"`
+			Token  string `toml:"token"`
+		} `toml:"metrics"`
+		Health struct {
+			Timeout     time.Duration `toml:"timeout"`
+			MaxWatchLag time.Duration `toml:"maxWatchLag"`
+		} `toml:"health"`
+		HTTP struct {
 			Port uint `toml:"port"`
 		} `toml:"http"`
 		HTTPS struct {
-			Port  uint   `toml:"port"`
-			HTTP2 bool   `toml:"http2"`
-			Cert  string `toml:"cert"`
-			Key   string `toml:"key"`
+			Port       uint   `toml:"port"`
+			HTTP2      bool   `toml:"http2"`
+			Cert       string `toml:"cert"`
+			Key        string `toml:"key"`
+			ClientAuth struct {
+				CA            string           `toml:"ca"`
+				Require       bool             `toml:"require"`
+				ServicePrefix string           `toml:"servicePrefix"`
+				Identities    []ClientIdentity `toml:"identities"`
+			} `toml:"clientAuth"`
 		} `toml:"https"`
 	} `toml:"api"`
 }
 
+// 客户端证书的身份及其授权规则
+type ClientIdentity struct {
+	Name  string    `toml:"name"`
+	Rules []ACLRule `toml:"rules"`
+}
+
+// 访问日志中单个路由的记录规则
+type AccessLogRoute struct {
+	Route      string `toml:"route"`      // 路由，例如/v1/services/:serviceID/select，支持*通配符
+	Level      string `toml:"level"`      // 记录级别，disabled表示不记录
+	SampleRate *Float `toml:"sampleRate"` // 采样率(0~1)，为0表示不记录，未设置时使用accessLog.sampleRate
+}
+
+// 联邦中的远端集群
+type FederationRemote struct {
+	Datacenter string   `toml:"datacenter"` // 远端集群所在的数据中心名称
+	Address    string   `toml:"address"`    // 远端集群的API地址，例如http://10.0.1.10:20080
+	Secret     string   `toml:"secret"`     // 访问远端集群API的访问密钥或ACL令牌
+	Services   []string `toml:"services"`   // 导入的服务ID，支持*通配符
+	Namespaces []string `toml:"namespaces"` // 导入的命名空间，为空表示默认命名空间
+}
+
+// 限流规则，按客户端IP及ACL令牌分别计数
+type RateLimitRule struct {
+	Rate  Float `toml:"rate" json:"rate"`   // 每秒允许的请求数，为0表示不限流
+	Burst int   `toml:"burst" json:"burst"` // 允许的突发请求数
+}
+
+// 配置中的小数，TOML中的整数也能解析，例如rate=20
+// go-toml不会将整数转换为float64，通过TextUnmarshaler接收其文本形式
+type Float float64
+
+func (self *Float) UnmarshalText(text []byte) error {
+	value, err := strconv.ParseFloat(string(text), 64)
+	if err != nil {
+		return errors.New("不是有效的数字")
+	}
+	*self = Float(value)
+	return nil
+}
+
+// JSON中只接受数字，实现TextUnmarshaler后encoding/json默认只接受字符串
+func (self *Float) UnmarshalJSON(data []byte) error {
+	var value float64
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*self = Float(value)
+	return nil
+}
+
+// 默认配置，配置文件、环境变量及命令行参数中未设置的配置项使用默认值
+func DefaultConfig() ConfigType {
+	var config ConfigType
+	config.Logger.Level = "info"
+	config.Logger.Encode = "console"
+	config.Logger.TimeFormat = "y-m-d h:i:s"
+	config.Logger.FileMode = 0600
+	config.Logger.MaxSize = 100
+	config.Logger.MaxBackups = 7
+	config.Storage.Name = "etcd"
+	config.Storage.Config = `{"endpoints": ["http://127.0.0.1:2379"], "key_prefix": "/tsing-center"}`
+	config.Audit.FilePath = "./audit.log"
+	config.Audit.FileMode = 0600
+	config.Audit.MaxSize = 100
+	config.Audit.MaxBackups = 5
+	config.AccessLog.Level = "info"
+	config.AccessLog.FileMode = 0600
+	config.AccessLog.MaxSize = 100
+	config.AccessLog.MaxBackups = 5
+	config.AccessLog.Encode = "json"
+	config.Tracing.ServiceName = "tsing-center"
+	config.Tracing.SampleRatio = 0.1
+	config.Tracing.FlushInterval = 5 * time.Second
+	config.Member.Interval = 5 * time.Second
+	config.Member.TTL = 15 * time.Second
+	config.Leader.TTL = 15 * time.Second
+	config.Leader.RetryInterval = 5 * time.Second
+	config.AccessLog.SampleRate = 1
+	config.Federation.Interval = 10 * time.Second
+	config.Federation.Timeout = 5 * time.Second
+	config.Federation.Fallback = true
+	config.API.QuitWaitTimeout = 10 * time.Second
+	config.API.ReadTimeout = 10 * time.Second
+	config.API.ReadHeaderTimeout = 10 * time.Second
+	config.API.WriteTimeout = 10 * time.Second
+	config.API.IdleTimeout = 10 * time.Second
+	config.API.RateLimit.Write = RateLimitRule{Rate: 20, Burst: 40}
+	config.API.RateLimit.Select = RateLimitRule{Rate: 200, Burst: 400}
+	config.API.Health.Timeout = 3 * time.Second
+	config.API.Health.MaxWatchLag = 10 * time.Second
+	config.API.HTTP.Port = 20080
+	config.API.HTTPS.Cert = "./server.cert"
+	config.API.HTTPS.Key = "./server.key"
+	config.API.HTTPS.HTTP2 = true
+	config.API.HTTPS.ClientAuth.ServicePrefix = "svc-"
+	return config
+}
+
 // 加载配置文件
 func LoadConfigFile(configPath string) error {
-	file, err := os.Open(filepath.Clean(configPath))
+	config, err := ParseConfigFile(configPath)
 	if err != nil {
-		log.Err(err).Caller().Send()
 		return err
 	}
-	err = toml.NewDecoder(file).Decode(&Config)
+	SetConfig(config)
+	return nil
+}
+
+// 解析并校验配置文件，不影响当前的配置
+// 依次使用默认值、配置文件、环境变量及命令行参数中的值，后者优先
+func ParseConfigFile(configPath string) (*ConfigType, error) {
+	file, err := os.Open(filepath.Clean(configPath))
 	if err != nil {
 		log.Err(err).Caller().Send()
-		return err
+		return nil, err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Err(err).Caller().Send()
+		}
+	}()
+	config := DefaultConfig()
+	if err = toml.NewDecoder(file).Strict(true).Decode(&config); err != nil {
+		log.Err(err).Caller().Send()
+		return nil, err
+	}
+	if err = applyOverrides(&config); err != nil {
+		log.Err(err).Caller().Send()
+		return nil, err
+	}
+	if err = config.Validate(); err != nil {
+		log.Err(err).Caller().Send()
+		return nil, err
+	}
+	return &config, nil
+}
+
+// 校验配置，返回所有无效的配置项，每行一条
+func (self *ConfigType) Validate() error {
+	var messages []string
+	check := func(valid bool, message string) {
+		if !valid {
+			messages = append(messages, message)
+		}
+	}
+	oneOf := func(key, value string, values ...string) {
+		for k := range values {
+			if value == values[k] {
+				return
+			}
+		}
+		message := key + "的值" + strconv.Quote(value) + "无效，目前只支持" + strings.Join(values, "|")
+		if values[0] == "" {
+			message = key + "的值" + strconv.Quote(value) + "无效，目前只支持留空或" + strings.Join(values[1:], "|")
+		}
+		messages = append(messages, message)
+	}
+
+	// logger
+	oneOf("logger.level", strings.ToLower(self.Logger.Level), "", "debug", "info", "warn", "error", "empty")
+	oneOf("logger.encode", self.Logger.Encode, "json", "console")
+	oneOf("logger.consoleEncode", self.Logger.ConsoleEncode, "", "json", "console")
+	oneOf("logger.fileEncode", self.Logger.FileEncode, "", "json", "console")
+	check(self.Logger.TimeFormat != "", "logger.timeFormat不能为空")
+	check(self.Logger.MaxAge >= 0, "logger.maxAge不能为负数")
+	check(self.Logger.Retention >= 0, "logger.retention不能为负数")
+
+	// storage
+	check(self.Storage.Name != "", "storage.name不能为空")
+	check(json.Valid([]byte(self.Storage.Config)), "storage.config不是有效的JSON")
+
+	// audit
+	oneOf("audit.sink", self.Audit.Sink, "", "file", "storage")
+	check(self.Audit.Sink != "file" || self.Audit.FilePath != "", "audit.sink为file时audit.filePath不能为空")
+	check(self.Audit.Retention >= 0, "audit.retention不能为负数")
+
+	// accessLog
+	oneOf("accessLog.level", strings.ToLower(self.AccessLog.Level), "", "debug", "info", "warn", "error", "disabled")
+	oneOf("accessLog.encode", self.AccessLog.Encode, "", "json", "console")
+	check(self.AccessLog.SampleRate >= 0 && self.AccessLog.SampleRate <= 1, "accessLog.sampleRate必须在0到1之间")
+	for k := range self.AccessLog.Routes {
+		route := &self.AccessLog.Routes[k]
+		prefix := "accessLog.routes[" + strconv.Itoa(k) + "]"
+		check(route.Route != "", prefix+".route不能为空")
+		oneOf(prefix+".level", strings.ToLower(route.Level), "", "debug", "info", "warn", "error", "disabled")
+		check(route.SampleRate == nil || (*route.SampleRate >= 0 && *route.SampleRate <= 1), prefix+".sampleRate必须在0到1之间")
+	}
+
+	// tracing
+	oneOf("tracing.exporter", self.Tracing.Exporter, "", "otlp")
+	check(self.Tracing.SampleRatio >= 0 && self.Tracing.SampleRatio <= 1, "tracing.sampleRatio必须在0到1之间")
+	check(self.Tracing.Exporter != "otlp" || self.Tracing.Endpoint != "", "使用otlp导出追踪数据时tracing.endpoint不能为空")
+	check(self.Tracing.Exporter != "otlp" || self.Tracing.FlushInterval > 0, "使用otlp导出追踪数据时tracing.flushInterval必须大于0")
+
+	// member
+	check(self.Member.Interval > 0, "member.interval必须大于0")
+	check(self.Member.TTL >= time.Second && self.Member.TTL > self.Member.Interval, "member.ttl必须不小于1s且大于member.interval")
+
+	// leader
+	check(self.Leader.TTL >= time.Second, "leader.ttl必须不小于1s")
+	check(self.Leader.RetryInterval > 0, "leader.retryInterval必须大于0")
+
+	// federation
+	check(self.Federation.Interval > 0, "federation.interval必须大于0")
+	check(self.Federation.Timeout > 0, "federation.timeout必须大于0")
+	check(len(self.Federation.Remotes) == 0 || self.Federation.Datacenter != "", "配置了远端集群时federation.datacenter不能为空")
+	datacenters := map[string]struct{}{self.Federation.Datacenter: {}}
+	for k := range self.Federation.Remotes {
+		remote := &self.Federation.Remotes[k]
+		prefix := "federation.remotes[" + strconv.Itoa(k) + "]"
+		_, exist := datacenters[remote.Datacenter]
+		check(remote.Datacenter != "" && !exist, prefix+".datacenter不能为空，且不能与当前或其它远端集群的数据中心相同")
+		datacenters[remote.Datacenter] = struct{}{}
+		address, err := url.Parse(remote.Address)
+		check(err == nil && (address.Scheme == "http" || address.Scheme == "https") && address.Host != "", prefix+".address必须是http或https地址")
+		check(len(remote.Services) > 0, prefix+".services不能为空")
+		for _, namespace := range remote.Namespaces {
+			check(ValidNamespace(namespace), prefix+".namespaces中的"+strconv.Quote(namespace)+"不是有效的命名空间")
+		}
 	}
 
+	// api
+	check(self.API.QuitWaitTimeout > 0, "api.quitWaitTimeout必须大于0")
+	check(self.API.ReadTimeout >= 0, "api.readTimeout不能为负数")
+	check(self.API.ReadHeaderTimeout >= 0, "api.readHeaderTimeout不能为负数")
+	check(self.API.WriteTimeout >= 0, "api.writeTimeout不能为负数")
+	check(self.API.IdleTimeout >= 0, "api.idleTimeout不能为负数")
+	check(!self.API.ACL.Enable || self.API.ACL.BootstrapToken != "", "启用ACL时api.acl.bootstrapToken不能为空")
+	check(self.API.RateLimit.Write.Rate >= 0 && self.API.RateLimit.Write.Burst >= 0, "api.rateLimit.write中的rate和burst不能为负数")
+	check(self.API.RateLimit.Select.Rate >= 0 && self.API.RateLimit.Select.Burst >= 0, "api.rateLimit.select中的rate和burst不能为负数")
+	check(self.API.Health.Timeout > 0, "api.health.timeout必须大于0")
+	check(self.API.Health.MaxWatchLag >= 0, "api.health.maxWatchLag不能为负数")
+	check(self.API.HTTP.Port <= 65535, "api.http.port必须在0到65535之间")
+	check(self.API.HTTPS.Port <= 65535, "api.https.port必须在0到65535之间")
+	check(self.API.HTTP.Port == 0 || self.API.HTTP.Port != self.API.HTTPS.Port, "api.http.port与api.https.port不能相同")
+	if self.API.HTTPS.Port > 0 {
+		if _, err := tls.LoadX509KeyPair(self.API.HTTPS.Cert, self.API.HTTPS.Key); err != nil {
+			messages = append(messages, "无法加载api.https.cert("+self.API.HTTPS.Cert+")及api.https.key("+self.API.HTTPS.Key+")："+err.Error())
+		}
+	}
+	clientAuth := &self.API.HTTPS.ClientAuth
+	check(!clientAuth.Require || clientAuth.CA != "", "要求客户端证书时api.https.clientAuth.ca不能为空")
+	if clientAuth.CA != "" {
+		if caBytes, err := ioutil.ReadFile(filepath.Clean(clientAuth.CA)); err != nil {
+			messages = append(messages, "无法读取api.https.clientAuth.ca("+clientAuth.CA+")："+err.Error())
+		} else {
+			check(x509.NewCertPool().AppendCertsFromPEM(caBytes), "api.https.clientAuth.ca("+clientAuth.CA+")中没有有效的证书")
+		}
+	}
+	for k := range clientAuth.Identities {
+		prefix := "api.https.clientAuth.identities[" + strconv.Itoa(k) + "]"
+		check(clientAuth.Identities[k].Name != "", prefix+".name不能为空")
+		for _, rule := range clientAuth.Identities[k].Rules {
+			check(rule.Pattern != "", prefix+".rules中的pattern不能为空")
+			check(ValidAccess(rule.Access), prefix+".rules中的access"+strconv.Quote(rule.Access)+"无效，目前只支持read|register|admin")
+		}
+	}
+
+	if len(messages) > 0 {
+		return errors.New(strings.Join(messages, "\n"))
+	}
 	return nil
 }
+
+// 输出配置时替换敏感值的字符串
+const redacted = "******"
+
+// 返回隐藏了访问密钥、令牌及存储器配置中密码等敏感值的配置，用于输出配置
+func (self ConfigType) Redacted() ConfigType {
+	values := []*string{&self.API.Secret, &self.API.ACL.BootstrapToken, &self.API.Metrics.Token}
+	remotes := make([]FederationRemote, len(self.Federation.Remotes))
+	copy(remotes, self.Federation.Remotes)
+	self.Federation.Remotes = remotes
+	for k := range remotes {
+		values = append(values, &remotes[k].Secret)
+	}
+	for _, value := range values {
+		if *value != "" {
+			*value = redacted
+		}
+	}
+	var storageConfig map[string]interface{}
+	if err := json.Unmarshal([]byte(self.Storage.Config), &storageConfig); err != nil {
+		self.Storage.Config = redacted
+		return self
+	}
+	for key := range storageConfig {
+		name := strings.ToLower(key)
+		if strings.Contains(name, "password") || strings.Contains(name, "secret") || strings.Contains(name, "token") {
+			storageConfig[key] = redacted
+		}
+	}
+	if data, err := json.Marshal(storageConfig); err == nil {
+		self.Storage.Config = string(data)
+	}
+	return self
+}
@@ -0,0 +1,185 @@
+package global
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// 将内容写入临时的配置文件，返回其路径
+func writeConfigFile(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "tsing-center-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	configPath := filepath.Join(dir, "config.toml")
+	if err = ioutil.WriteFile(configPath, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return configPath
+}
+
+func TestParseExampleConfig(t *testing.T) {
+	config, err := ParseConfigFile("../config.toml")
+	if err != nil {
+		t.Fatalf("示例配置文件应能解析：%v", err)
+	}
+	// 文件权限须按八进制解析，与默认值一致
+	defaults := DefaultConfig()
+	if config.Audit.FileMode != defaults.Audit.FileMode {
+		t.Fatalf("审计日志文件的权限应为%o，实际为%o", defaults.Audit.FileMode, config.Audit.FileMode)
+	}
+	if config.AccessLog.FileMode != defaults.AccessLog.FileMode {
+		t.Fatalf("访问日志文件的权限应为%o，实际为%o", defaults.AccessLog.FileMode, config.AccessLog.FileMode)
+	}
+}
+
+func TestFloat(t *testing.T) {
+	config, err := ParseConfigFile(writeConfigFile(t, `
+[api.rateLimit]
+write={rate=20, burst=40}
+select={rate=0.5, burst=1}
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.API.RateLimit.Write.Rate != 20 || config.API.RateLimit.Select.Rate != 0.5 {
+		t.Fatalf("整数及小数都应能解析：%+v", config.API.RateLimit)
+	}
+	if _, err = ParseConfigFile(writeConfigFile(t, `
+[api.rateLimit]
+write={rate="fast", burst=40}
+`)); err == nil {
+		t.Fatal("非数字的值应返回错误")
+	}
+
+	var rule RateLimitRule
+	if err = json.Unmarshal([]byte(`{"rate":20,"burst":40}`), &rule); err != nil || rule.Rate != 20 {
+		t.Fatalf("JSON中的数字应能解析：%+v %v", rule, err)
+	}
+	if err = json.Unmarshal([]byte(`{"rate":"20"}`), &rule); err == nil {
+		t.Fatal("JSON中的字符串不应解析为数字")
+	}
+}
+
+func TestAccessLogSampleRate(t *testing.T) {
+	config, err := ParseConfigFile(writeConfigFile(t, `
+[accessLog]
+sampleRate=0
+[[accessLog.routes]]
+route="/v1/services"
+sampleRate=1
+[[accessLog.routes]]
+route="/v1/namespaces"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes := config.AccessLog.Routes
+	if config.AccessLog.SampleRate != 0 || len(routes) != 2 {
+		t.Fatalf("配置解析错误：%+v", config.AccessLog)
+	}
+	if routes[0].SampleRate == nil || *routes[0].SampleRate != 1 || routes[1].SampleRate != nil {
+		t.Fatal("未设置采样率的路由应为nil")
+	}
+	if DefaultConfig().AccessLog.SampleRate != 1 {
+		t.Fatal("默认应全部记录")
+	}
+}
+
+func TestTracingSampleRatio(t *testing.T) {
+	config, err := ParseConfigFile(writeConfigFile(t, "[tracing]\nsampleRatio=1\n"))
+	if err != nil || config.Tracing.SampleRatio != 1 {
+		t.Fatalf("整数的采样比例应能解析：%v", err)
+	}
+}
+
+func TestStrict(t *testing.T) {
+	_, err := ParseConfigFile(writeConfigFile(t, "[api]\nsecrets=\"123456\"\n"))
+	if err == nil || !strings.Contains(err.Error(), "api.secrets") {
+		t.Fatalf("未知的配置项应返回错误：%v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	config := DefaultConfig()
+	if err := config.Validate(); err != nil {
+		t.Fatalf("默认配置应有效：%v", err)
+	}
+
+	config.Logger.Level = "verbose"
+	config.Storage.Config = "{"
+	config.AccessLog.SampleRate = 2
+	config.Member.TTL = config.Member.Interval
+	config.API.ACL.Enable = true
+	config.API.HTTP.Port = 70000
+	config.Federation.Remotes = []FederationRemote{{Datacenter: "dc2", Address: "ftp://dc2"}}
+	err := config.Validate()
+	if err == nil {
+		t.Fatal("无效的配置应返回错误")
+	}
+	// 返回所有无效的配置项，每行一条
+	messages := strings.Split(err.Error(), "\n")
+	for _, key := range []string{
+		"logger.level",
+		"storage.config",
+		"accessLog.sampleRate",
+		"member.ttl",
+		"api.acl.bootstrapToken",
+		"api.http.port",
+		"federation.datacenter",
+		"federation.remotes[0].address",
+		"federation.remotes[0].services",
+	} {
+		var found bool
+		for _, message := range messages {
+			if strings.Contains(message, key) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("缺少%s的错误信息：%v", key, messages)
+		}
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	config := DefaultConfig()
+	config.API.Secret = "secret"
+	config.API.ACL.BootstrapToken = "bootstrap"
+	config.Storage.Config = `{"endpoints":["http://127.0.0.1:2379"],"password":"p","client_secret":"s","api_token":"t"}`
+	config.Federation.Remotes = []FederationRemote{{Datacenter: "dc2", Secret: "remote"}}
+
+	redactedConfig := config.Redacted()
+	if redactedConfig.API.Secret != redacted || redactedConfig.API.ACL.BootstrapToken != redacted || redactedConfig.API.Metrics.Token != "" {
+		t.Fatal("应隐藏已设置的密钥及令牌，未设置的保持为空")
+	}
+	if redactedConfig.Federation.Remotes[0].Secret != redacted {
+		t.Fatal("应隐藏远端集群的密钥")
+	}
+	var storageConfig map[string]interface{}
+	if err := json.Unmarshal([]byte(redactedConfig.Storage.Config), &storageConfig); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"password", "client_secret", "api_token"} {
+		if storageConfig[key] != redacted {
+			t.Fatalf("应隐藏存储器配置中的%s", key)
+		}
+	}
+	if _, ok := storageConfig["endpoints"].([]interface{}); !ok {
+		t.Fatal("不应修改存储器配置中的其它值")
+	}
+	// 不影响原配置
+	if config.API.Secret != "secret" || config.Federation.Remotes[0].Secret != "remote" || !strings.Contains(config.Storage.Config, `"p"`) {
+		t.Fatal("不应修改原配置")
+	}
+	config.Storage.Config = "{"
+	if config.Redacted().Storage.Config != redacted {
+		t.Fatal("无法解析的存储器配置应整体隐藏")
+	}
+}
@@ -0,0 +1,47 @@
+package global
+
+import (
+	"context"
+	"sync"
+)
+
+// 后台任务，进程退出时等待其完成
+var background struct {
+	sync.Mutex
+	wg     sync.WaitGroup
+	closed bool // 已开始退出，不再启动新任务
+}
+
+// 启动后台任务，开始退出后不再启动并返回false
+func Go(fn func()) bool {
+	background.Lock()
+	defer background.Unlock()
+	if background.closed {
+		return false
+	}
+	background.wg.Add(1)
+	go func() {
+		defer background.wg.Done()
+		fn()
+	}()
+	return true
+}
+
+// 停止启动新的后台任务并等待已启动的任务完成，超时则返回ctx的错误
+func WaitBackground(ctx context.Context) error {
+	background.Lock()
+	background.closed = true
+	background.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		background.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
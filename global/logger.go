@@ -0,0 +1,39 @@
+package global
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// 支持的日志记录级别
+var logLevels = map[string]zerolog.Level{
+	"debug":    zerolog.DebugLevel,
+	"info":     zerolog.InfoLevel,
+	"warn":     zerolog.WarnLevel,
+	"error":    zerolog.ErrorLevel,
+	"empty":    zerolog.NoLevel,
+	"disabled": zerolog.Disabled,
+}
+
+// 设置日志的记录级别，empty表示不显示级别，disabled表示禁用日志
+func SetLogLevel(level string) error {
+	value, exists := logLevels[strings.ToLower(level)]
+	if !exists {
+		return errors.New("未知的日志级别：" + level + "，目前只支持debug|info|warn|error|empty|disabled")
+	}
+	zerolog.SetGlobalLevel(value)
+	return nil
+}
+
+// 获取日志当前的记录级别
+func LogLevel() string {
+	current := zerolog.GlobalLevel()
+	for name, value := range logLevels {
+		if value == current {
+			return name
+		}
+	}
+	return current.String()
+}
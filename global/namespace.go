@@ -0,0 +1,32 @@
+package global
+
+import "regexp"
+
+// 默认命名空间，未指定命名空间的请求及命名空间功能之前的数据使用该命名空间
+const DefaultNamespace = "default"
+
+// 命名空间的格式，只允许小写字母、数字、-及_，以字母或数字开头
+var namespacePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// 存储器中键前缀下的保留名称，不能用作命名空间
+var reservedNamespaces = map[string]struct{}{
+	"services": {},
+	"nodes":    {},
+	"acl":      {},
+	"audit":    {},
+	"members":  {},
+	"election": {},
+}
+
+// 判断是否为有效的命名空间
+func ValidNamespace(namespace string) bool {
+	if _, reserved := reservedNamespaces[namespace]; reserved {
+		return false
+	}
+	return namespacePattern.MatchString(namespace)
+}
+
+// 服务在本地服务列表中的key，命名空间中不包含/
+func ServiceKey(namespace, serviceID string) string {
+	return namespace + "/" + serviceID
+}
@@ -0,0 +1,171 @@
+package global
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// 覆盖配置项的环境变量前缀，例如api.https.port对应TSING_CENTER_API_HTTPS_PORT
+const EnvPrefix = "TSING_CENTER_"
+
+// 通过命令行参数设置的配置项，key为配置项的路径，例如api.https.port
+var flagValues = map[string]string{}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// 遍历配置中的所有配置项，入参(配置项的路径, 配置项的值)
+// 结构体逐层展开，切片作为单个配置项
+func walkConfig(value reflect.Value, prefix string, fn func(string, reflect.Value)) {
+	valueType := value.Type()
+	for k := 0; k < valueType.NumField(); k++ {
+		name := strings.Split(valueType.Field(k).Tag.Get("toml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		field := value.Field(k)
+		if field.Kind() == reflect.Struct {
+			walkConfig(field, name, fn)
+			continue
+		}
+		fn(name, field)
+	}
+}
+
+// 获取配置项对应的环境变量名，驼峰命名的单词之间以下划线分隔
+func EnvName(key string) string {
+	var name strings.Builder
+	name.WriteString(EnvPrefix)
+	for k, r := range key {
+		switch {
+		case r == '.':
+			name.WriteByte('_')
+		case unicode.IsUpper(r):
+			if k > 0 && key[k-1] != '.' {
+				name.WriteByte('_')
+			}
+			name.WriteRune(r)
+		default:
+			name.WriteRune(unicode.ToUpper(r))
+		}
+	}
+	return name.String()
+}
+
+// 将字符串解析为配置项的值，切片使用JSON格式，时长使用Go的时长格式，例如10s
+func setConfigValue(field reflect.Value, raw string) error {
+	if field.Type() == durationType {
+		value, err := time.ParseDuration(raw)
+		if err != nil {
+			return errors.New("不是有效的时长，例如10s")
+		}
+		field.SetInt(int64(value))
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return errors.New("不是有效的布尔值，例如true或false")
+		}
+		field.SetBool(value)
+	case reflect.Int, reflect.Int64:
+		value, err := strconv.ParseInt(raw, 10, field.Type().Bits())
+		if err != nil {
+			return errors.New("不是有效的整数")
+		}
+		field.SetInt(value)
+	case reflect.Uint, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value, err := strconv.ParseUint(raw, 10, field.Type().Bits())
+		if err != nil {
+			return errors.New("不是有效的非负整数")
+		}
+		field.SetUint(value)
+	case reflect.Float64:
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return errors.New("不是有效的数字")
+		}
+		field.SetFloat(value)
+	case reflect.Slice:
+		value := reflect.New(field.Type())
+		if err := json.Unmarshal([]byte(raw), value.Interface()); err != nil {
+			return errors.New("不是有效的JSON数组：" + err.Error())
+		}
+		field.Set(value.Elem())
+	default:
+		return errors.New("不支持的类型" + field.Type().String())
+	}
+	return nil
+}
+
+// 使用环境变量及命令行参数覆盖配置项，命令行参数的优先级最高
+func applyOverrides(config *ConfigType) error {
+	var messages []string
+	walkConfig(reflect.ValueOf(config).Elem(), "", func(key string, field reflect.Value) {
+		if raw, exists := os.LookupEnv(EnvName(key)); exists {
+			if err := setConfigValue(field, raw); err != nil {
+				messages = append(messages, "环境变量"+EnvName(key)+"的值无效："+err.Error())
+			}
+		}
+		if raw, exists := flagValues[key]; exists {
+			if err := setConfigValue(field, raw); err != nil {
+				messages = append(messages, "命令行参数-"+key+"的值无效："+err.Error())
+			}
+		}
+	})
+	if len(messages) > 0 {
+		return errors.New(strings.Join(messages, "\n"))
+	}
+	return nil
+}
+
+// 命令行参数对应的配置项
+type flagValue struct {
+	key    string
+	field  reflect.Value // 用于校验参数值的零值
+	isBool bool
+}
+
+func (self *flagValue) String() string {
+	if self == nil {
+		return ""
+	}
+	return flagValues[self.key]
+}
+
+// 校验并保存参数值，加载配置文件时再覆盖配置项
+func (self *flagValue) Set(raw string) error {
+	if err := setConfigValue(reflect.New(self.field.Type()).Elem(), raw); err != nil {
+		return err
+	}
+	flagValues[self.key] = raw
+	return nil
+}
+
+func (self *flagValue) IsBoolFlag() bool {
+	return self.isBool
+}
+
+// 为每个配置项注册命令行参数，参数名为配置项的路径，例如-api.https.port=443
+func RegisterFlags(flagSet *flag.FlagSet) {
+	var config ConfigType
+	walkConfig(reflect.ValueOf(&config).Elem(), "", func(key string, field reflect.Value) {
+		usage := "覆盖配置项" + key + "，也可使用环境变量" + EnvName(key)
+		if field.Kind() == reflect.Slice {
+			usage += "，值为JSON数组"
+		}
+		flagSet.Var(&flagValue{key: key, field: field, isBool: field.Kind() == reflect.Bool}, key, usage)
+	})
+}
@@ -0,0 +1,113 @@
+package global
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnvName(t *testing.T) {
+	for key, expected := range map[string]string{
+		"api.https.port":           "TSING_CENTER_API_HTTPS_PORT",
+		"api.rateLimit.enable":     "TSING_CENTER_API_RATE_LIMIT_ENABLE",
+		"logger.timeFormat":        "TSING_CENTER_LOGGER_TIME_FORMAT",
+		"api.acl.bootstrapToken":   "TSING_CENTER_API_ACL_BOOTSTRAP_TOKEN",
+		"federation.remotes":       "TSING_CENTER_FEDERATION_REMOTES",
+		"api.https.clientAuth.ca":  "TSING_CENTER_API_HTTPS_CLIENT_AUTH_CA",
+		"accessLog.sampleRate":     "TSING_CENTER_ACCESS_LOG_SAMPLE_RATE",
+		"api.health.maxWatchLag":   "TSING_CENTER_API_HEALTH_MAX_WATCH_LAG",
+		"api.rateLimit.write.rate": "TSING_CENTER_API_RATE_LIMIT_WRITE_RATE",
+	} {
+		if actual := EnvName(key); actual != expected {
+			t.Errorf("%s的环境变量应为%s，实际为%s", key, expected, actual)
+		}
+	}
+}
+
+func TestSetConfigValue(t *testing.T) {
+	var config ConfigType
+	cases := []struct {
+		field    reflect.Value
+		raw      string
+		expected interface{}
+	}{
+		{reflect.ValueOf(&config.API.Secret).Elem(), "abc", "abc"},
+		{reflect.ValueOf(&config.API.ACL.Enable).Elem(), "true", true},
+		{reflect.ValueOf(&config.API.HTTP.Port).Elem(), "8080", uint(8080)},
+		{reflect.ValueOf(&config.API.ReadTimeout).Elem(), "1m", time.Minute},
+		{reflect.ValueOf(&config.AccessLog.SampleRate).Elem(), "0.5", Float(0.5)},
+		{reflect.ValueOf(&config.Federation.Remotes).Elem(), `[{"Datacenter":"dc2"}]`, []FederationRemote{{Datacenter: "dc2"}}},
+	}
+	for _, c := range cases {
+		if err := setConfigValue(c.field, c.raw); err != nil {
+			t.Fatalf("%q应能解析：%v", c.raw, err)
+		}
+		if !reflect.DeepEqual(c.field.Interface(), c.expected) {
+			t.Fatalf("%q解析后应为%v，实际为%v", c.raw, c.expected, c.field.Interface())
+		}
+	}
+	invalid := []struct {
+		field reflect.Value
+		raw   string
+	}{
+		{reflect.ValueOf(&config.API.ACL.Enable).Elem(), "yes"},
+		{reflect.ValueOf(&config.API.HTTP.Port).Elem(), "-1"},
+		{reflect.ValueOf(&config.API.ReadTimeout).Elem(), "10"},
+		{reflect.ValueOf(&config.AccessLog.SampleRate).Elem(), "half"},
+		{reflect.ValueOf(&config.Federation.Remotes).Elem(), "dc2"},
+	}
+	for _, c := range invalid {
+		if err := setConfigValue(c.field, c.raw); err == nil {
+			t.Fatalf("%q不应能解析为%s", c.raw, c.field.Type())
+		}
+	}
+}
+
+func TestApplyOverrides(t *testing.T) {
+	configPath := writeConfigFile(t, "[api]\nsecret=\"file\"\n[api.http]\nport=8080\n")
+	os.Setenv(EnvName("api.secret"), "env")
+	os.Setenv(EnvName("api.http.port"), "9090")
+	os.Setenv(EnvName("api.readTimeout"), "30s")
+	defer func() {
+		os.Unsetenv(EnvName("api.secret"))
+		os.Unsetenv(EnvName("api.http.port"))
+		os.Unsetenv(EnvName("api.readTimeout"))
+	}()
+
+	// 命令行参数的优先级最高
+	flagSet := flag.NewFlagSet("test", flag.ContinueOnError)
+	flagSet.SetOutput(ioutil.Discard)
+	RegisterFlags(flagSet)
+	if err := flagSet.Parse([]string{"-api.http.port=7070", "-api.acl.enable", "-api.acl.bootstrapToken=root"}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		for key := range flagValues {
+			delete(flagValues, key)
+		}
+	}()
+
+	config, err := ParseConfigFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.API.Secret != "env" || config.API.ReadTimeout != 30*time.Second {
+		t.Fatalf("环境变量应覆盖配置文件：%q %v", config.API.Secret, config.API.ReadTimeout)
+	}
+	if config.API.HTTP.Port != 7070 || !config.API.ACL.Enable || config.API.ACL.BootstrapToken != "root" {
+		t.Fatalf("命令行参数应覆盖环境变量及配置文件：%+v", config.API.ACL)
+	}
+
+	// 无效的值在解析命令行参数时返回错误
+	if err = flagSet.Parse([]string{"-api.http.port=http"}); err == nil {
+		t.Fatal("无效的命令行参数应返回错误")
+	}
+	os.Setenv(EnvName("api.http.port"), "http")
+	if _, err = ParseConfigFile(configPath); err == nil || !strings.Contains(err.Error(), EnvName("api.http.port")) {
+		t.Fatalf("无效的环境变量应返回错误：%v", err)
+	}
+}
@@ -0,0 +1,85 @@
+package global
+
+import (
+	"reflect"
+)
+
+// 配置项及其取值函数
+type configItem struct {
+	key   string
+	value func(*ConfigType) interface{}
+}
+
+// 可在运行时重新加载的配置项
+var liveItems = []configItem{
+	{"logger", func(c *ConfigType) interface{} {
+		logger := c.Logger
+		logger.TimeFormat = ""
+		return logger
+	}},
+	{"accessLog", func(c *ConfigType) interface{} { return c.AccessLog }},
+	{"api.secret", func(c *ConfigType) interface{} { return c.API.Secret }},
+	{"api.rateLimit", func(c *ConfigType) interface{} { return c.API.RateLimit }},
+	{"api.https.cert", func(c *ConfigType) interface{} { return c.API.HTTPS.Cert }},
+	{"api.https.key", func(c *ConfigType) interface{} { return c.API.HTTPS.Key }},
+}
+
+// 需要重启进程才能生效的配置项
+var restartItems = []configItem{
+	{"logger.timeFormat", func(c *ConfigType) interface{} { return c.Logger.TimeFormat }},
+	{"storage", func(c *ConfigType) interface{} { return c.Storage }},
+	{"audit", func(c *ConfigType) interface{} { return c.Audit }},
+	{"tracing", func(c *ConfigType) interface{} { return c.Tracing }},
+	{"member", func(c *ConfigType) interface{} { return c.Member }},
+	{"leader", func(c *ConfigType) interface{} { return c.Leader }},
+	{"node", func(c *ConfigType) interface{} { return c.Node }},
+	{"federation", func(c *ConfigType) interface{} { return c.Federation }},
+	{"api.ip", func(c *ConfigType) interface{} { return c.API.IP }},
+	{"api.quitWaitTimeout", func(c *ConfigType) interface{} { return c.API.QuitWaitTimeout }},
+	{"api.readTimeout", func(c *ConfigType) interface{} { return c.API.ReadTimeout }},
+	{"api.readHeaderTimeout", func(c *ConfigType) interface{} { return c.API.ReadHeaderTimeout }},
+	{"api.writeTimeout", func(c *ConfigType) interface{} { return c.API.WriteTimeout }},
+	{"api.idleTimeout", func(c *ConfigType) interface{} { return c.API.IdleTimeout }},
+	{"api.acl", func(c *ConfigType) interface{} { return c.API.ACL }},
+	{"api.metrics", func(c *ConfigType) interface{} { return c.API.Metrics }},
+	{"api.health", func(c *ConfigType) interface{} { return c.API.Health }},
+	{"api.http.port", func(c *ConfigType) interface{} { return c.API.HTTP.Port }},
+	{"api.https.port", func(c *ConfigType) interface{} { return c.API.HTTPS.Port }},
+	{"api.https.http2", func(c *ConfigType) interface{} { return c.API.HTTPS.HTTP2 }},
+	{"api.https.clientAuth", func(c *ConfigType) interface{} { return c.API.HTTPS.ClientAuth }},
+}
+
+// 比较新旧配置，返回有变化的配置项
+func changedItems(items []configItem, old, new *ConfigType) (keys []string) {
+	for k := range items {
+		if !reflect.DeepEqual(items[k].value(old), items[k].value(new)) {
+			keys = append(keys, items[k].key)
+		}
+	}
+	return
+}
+
+// 比较新旧配置，返回有变化且可在运行时生效的配置项
+func LiveChanges(old, new *ConfigType) []string {
+	return changedItems(liveItems, old, new)
+}
+
+// 比较新旧配置，返回有变化但需要重启进程才能生效的配置项
+func RestartChanges(old, new *ConfigType) []string {
+	return changedItems(restartItems, old, new)
+}
+
+// 以可在运行时生效的配置项替换当前配置中的对应项，须在各配置项生效后调用
+// 在当前配置的副本上修改后整体替换，不影响正在读取原配置的请求
+func ApplyLive(new *ConfigType) {
+	config := *Config()
+	timeFormat := config.Logger.TimeFormat
+	config.Logger = new.Logger
+	config.Logger.TimeFormat = timeFormat
+	config.AccessLog = new.AccessLog
+	config.API.Secret = new.API.Secret
+	config.API.RateLimit = new.API.RateLimit
+	config.API.HTTPS.Cert = new.API.HTTPS.Cert
+	config.API.HTTPS.Key = new.API.HTTPS.Key
+	SetConfig(&config)
+}
@@ -0,0 +1,89 @@
+package global
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestLiveChanges(t *testing.T) {
+	old := DefaultConfig()
+	new := DefaultConfig()
+	new.Logger.TimeFormat = "timestamp"
+	new.API.Secret = "changed"
+	new.API.WriteTimeout *= 2
+	if keys := LiveChanges(&old, &new); len(keys) != 1 || keys[0] != "api.secret" {
+		t.Fatalf("logger.timeFormat不应视为可在运行时生效：%v", keys)
+	}
+	keys := RestartChanges(&old, &new)
+	if len(keys) != 2 || keys[0] != "logger.timeFormat" || keys[1] != "api.writeTimeout" {
+		t.Fatalf("需要重启的配置项不正确：%v", keys)
+	}
+}
+
+func TestApplyLive(t *testing.T) {
+	previous := Config()
+	defer SetConfig(previous)
+	old := DefaultConfig()
+	SetConfig(&old)
+
+	new := DefaultConfig()
+	new.Logger.Level = "warn"
+	new.Logger.TimeFormat = "timestamp"
+	new.API.Secret = "changed"
+	new.API.HTTPS.Cert = "./new.cert"
+	new.API.WriteTimeout *= 2
+	ApplyLive(&new)
+
+	current := Config()
+	if current == &old {
+		t.Fatal("应替换为新的配置而不是修改原配置")
+	}
+	if old.API.Secret == "changed" || old.Logger.Level == "warn" {
+		t.Fatal("不应修改原配置")
+	}
+	if current.API.Secret != "changed" || current.Logger.Level != "warn" || current.API.HTTPS.Cert != "./new.cert" {
+		t.Fatalf("可在运行时生效的配置项未应用：%+v", current.API)
+	}
+	if current.Logger.TimeFormat != old.Logger.TimeFormat || current.API.WriteTimeout != old.API.WriteTimeout {
+		t.Fatal("需要重启的配置项不应被应用")
+	}
+}
+
+// 使用-race运行时检查重新加载配置与读取配置之间没有数据竞争
+func TestApplyLiveConcurrent(t *testing.T) {
+	previous := Config()
+	defer SetConfig(previous)
+	initial := DefaultConfig()
+	SetConfig(&initial)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				config := Config()
+				_ = config.API.Secret
+				_ = config.API.RateLimit.Write.Rate
+				_ = config.Logger.Level
+				_ = config.AccessLog.Enable
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		new := DefaultConfig()
+		new.API.RateLimit.Write.Rate = Float(i)
+		ApplyLive(&new)
+	}
+	close(done)
+	wg.Wait()
+	if Config().API.RateLimit.Write.Rate != 99 {
+		t.Fatal("应保留最后一次应用的配置")
+	}
+}
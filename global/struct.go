@@ -1,49 +1,122 @@
 package global
 
 import (
+	"context"
+	"errors"
 	"sync"
 
 	"github.com/bwmarrin/snowflake"
+
+	"local/meta"
 )
 
+// 版本号，构建时通过-ldflags "-X local/global.Version=x.y.z"设置
+var Version = "dev"
+
 var (
 	SnowflakeNode *snowflake.Node
 
 	Storage StorageType // 存储器实例
 
 	// 服务列表，服务是个集群的抽象概念
-	// key=服务ID, value=集群实例
+	// key=ServiceKey(命名空间, 服务ID), value=集群实例
 	Services      sync.Map
 	TotalServices uint32 // 服务总数
 )
 
 // 服务配置，用作集群构建时的参数
 type ServiceConfig struct {
-	ServiceID   string `json:"service_id"`     // 服务ID
-	LoadBalance string `json:"load_balance"`   // 负载
-	Mete        string `json:"mete,omitempty"` // 元信息(JSON字符串)
+	Namespace   string   `json:"-"`                     // 命名空间，由存储器的key决定，不写入value
+	ServiceID   string   `json:"service_id"`            // 服务ID
+	LoadBalance string   `json:"load_balance"`          // 负载
+	Mete        string   `json:"mete,omitempty"`        // 元信息(JSON字符串)
+	Tags        []string `json:"tags,omitempty"`        // 标签，已去重并排序
+	MetaSchema  string   `json:"meta_schema,omitempty"` // 节点元信息的JSON Schema，注册节点时校验
+	Revision    int64    `json:"-"`                     // 存储器中的修订版本号，不写入存储器
+
+	metaSchema *meta.Schema // 编译后的MetaSchema，设置服务时编译一次
+}
+
+// 编译并缓存服务的MetaSchema，设置服务时调用，注册节点时不再重复编译
+func (self *ServiceConfig) CompileMetaSchema() (err error) {
+	self.metaSchema = nil
+	if self.MetaSchema == "" {
+		return nil
+	}
+	self.metaSchema, err = meta.CompileSchema(StrToBytes(self.MetaSchema))
+	return
+}
+
+// 获取编译后的MetaSchema，未设置时返回nil，未缓存时临时编译
+func (self ServiceConfig) NodeMetaSchema() (*meta.Schema, error) {
+	if self.metaSchema != nil || self.MetaSchema == "" {
+		return self.metaSchema, nil
+	}
+	return meta.CompileSchema(StrToBytes(self.MetaSchema))
 }
 
 // 节点属性
 type Node struct {
-	IP      string `json:"ip"`             // 节点IP
-	Port    uint16 `json:"port"`           // 节点端口
-	Weight  int    `json:"weight"`         // 节点权重
-	TTL     uint   `json:"ttl"`            // TTL(秒)
-	Expires int64  `json:"expires"`        // 生命周期截止时间(unix时间戳)，值为0表示一直有效
-	Mete    string `json:"mete,omitempty"` // 元信息(JSON字符串)
+	IP       string   `json:"ip"`             // 节点IP
+	Port     uint16   `json:"port"`           // 节点端口
+	Weight   int      `json:"weight"`         // 节点权重
+	TTL      uint     `json:"ttl"`            // TTL(秒)
+	Expires  int64    `json:"expires"`        // 生命周期截止时间(unix时间戳)，值为0表示一直有效
+	Mete     string   `json:"mete,omitempty"` // 元信息(JSON字符串)
+	Tags     []string `json:"tags,omitempty"` // 标签，已去重并排序
+	Revision int64    `json:"-"`              // 存储器中的修订版本号，不写入存储器
+}
+
+// 集群成员，即服务中心的实例
+//
+//easyjson:skip
+type Member struct {
+	ID         string `json:"id"`          // 成员ID，即存储器的客户端ID
+	Address    string `json:"address"`     // API服务的地址
+	Version    string `json:"version"`     // 版本号
+	StartTime  int64  `json:"start_time"`  // 启动时间(unix时间戳)
+	Revision   int64  `json:"revision"`    // 监听已同步到的修订版本号
+	Lagging    bool   `json:"lagging"`     // 监听是否落后于存储器
+	UpdateTime int64  `json:"update_time"` // 最后一次更新的时间(unix时间戳)
 }
 
+// 节点的批量操作类型
+const (
+	NodeOperationSet    = "set"
+	NodeOperationDelete = "delete"
+)
+
+// 节点的批量操作
+//
+//easyjson:skip
+type NodeOperation struct {
+	Action    string // 操作类型
+	Namespace string // 命名空间
+	ServiceID string // 服务ID
+	Node      Node   // 节点，删除操作只需要IP和Port
+}
+
+// 写入或删除存储器数据时对修订版本号的比较方式
+// 值为0表示要求数据不存在，大于0表示要求修订版本号一致
+const (
+	AnyRevision    int64 = -1 // 不比较修订版本号
+	ExistsRevision int64 = -2 // 只要求数据已存在
+)
+
+// 存储器中数据的修订版本号与期望的不一致
+var ErrRevisionMismatch = errors.New("修订版本号不一致")
+
 // 集群接口
 type Cluster interface {
-	Config() ServiceConfig       // 获得配置
-	Set(Node)                    // 设置节点
-	Touch(string, uint16, int64) // 触活节点，入参(ip, port, expires)
-	Remove(string, uint16)       // 移除节点，入参(ip, port)
-	Select() Node                // 选取节点
-	Total() int                  // 节点总数
-	Nodes() []Node               // 节点列表
-	Find(string, uint16) Node    // 查找某个节点，入参(ip, port)
+	Config() ServiceConfig               // 获得配置
+	Set(Node)                            // 设置节点
+	Touch(string, uint16, int64)         // 触活节点，入参(ip, port, expires)
+	Remove(string, uint16)               // 移除节点，入参(ip, port)
+	Select() Node                        // 选取节点
+	SelectN(int, func(Node) bool) []Node // 按算法顺序选取多个不重复的节点，入参(数量, 排除节点的判断函数)
+	Total() int                          // 节点总数
+	Nodes() []Node                       // 节点列表
+	Find(string, uint16) Node            // 查找某个节点，入参(ip, port)
 }
 
 // 存储器接口
@@ -51,17 +124,46 @@ type StorageType interface {
 	LoadAll() error // 从存储器加载所有数据到本地
 	SaveAll() error // 将本地所有数据保存到存储器
 
-	LoadService([]byte) error          // 从存储器加载单个服务数据，入参(ServiceConfig的json字节码)
-	SaveService(ServiceConfig) error   // 将本地单个服务保存到存储器
-	DeleteLocalService(string) error   // 删除本地单个服务，入参(存储器key)
-	DeleteStorageService(string) error // 删除存储器中单个服务
+	LoadService(string, []byte, int64) error             // 从存储器加载单个服务数据，入参(存储器key, ServiceConfig的json字节码, 修订版本号)
+	SaveService(ServiceConfig) error                     // 将本地单个服务保存到存储器
+	SaveServiceCAS(ServiceConfig, int64) (int64, error)  // 比较修订版本号后保存服务，入参(服务配置, 期望的修订版本号)，返回写入后的修订版本号
+	DeleteLocalService(string) error                     // 删除本地单个服务，入参(存储器key)
+	DeleteStorageService(string, string) error           // 删除存储器中单个服务，入参(命名空间, 编码后的服务ID)
+	DeleteStorageServiceCAS(string, string, int64) error // 比较修订版本号后删除存储器中单个服务，入参(命名空间, 编码后的服务ID, 期望的修订版本号)
+
+	LoadNode(string, []byte, int64) error                             // 从存储器加载单个节点数据，入参(存储器key，存储器数据, 修订版本号)
+	SaveNode(string, string, Node) error                              // 将本地单个节点保存到存储器，入参(命名空间, 服务id, 节点)
+	SaveNodeCAS(string, string, Node, int64) (int64, error)           // 比较修订版本号后保存节点，入参(命名空间, 服务id, 节点, 期望的修订版本号)，返回写入后的修订版本号
+	DeleteLocalNode(string) error                                     // 删除本地单个节点，入参(存储器key)
+	DeleteStorageNode(string, string, string, uint16) error           // 删除存储器中单个节点，入参(命名空间, 服务id, ip, port)
+	DeleteStorageNodeCAS(string, string, string, uint16, int64) error // 比较修订版本号后删除存储器中单个节点，入参(命名空间, 服务id, ip, port, 期望的修订版本号)
+
+	BatchNodes([]NodeOperation) []error // 批量写入或删除存储器中的节点，返回与入参一一对应的错误
 
-	LoadNode(string, []byte) error                  // 从存储器加载单个节点数据，入参(存储器key，存储器数据)
-	SaveNode(string, Node) error                    // 将本地单个节点保存到存储器
-	DeleteLocalNode(string) error                   // 删除本地单个节点，入参(存储器key)
-	DeleteStorageNode(string, string, uint16) error // 删除存储器中单个节点，入参(服务id, ip, port)
+	LoadToken([]byte) error          // 从存储器加载单个ACL令牌，入参(ACLToken的json字节码)
+	SaveToken(ACLToken) error        // 将ACL令牌保存到存储器
+	DeleteLocalToken(string) error   // 删除本地单个ACL令牌，入参(存储器key)
+	DeleteStorageToken(string) error // 删除存储器中单个ACL令牌，入参(令牌secret的sha256)
 
-	Clean(string, []Node) error // 清理已失效的节点
+	WriteAudit(int64, []byte) error                         // 写入审计记录，入参(记录时间的unix纳秒, 记录的json字节码)
+	ReadAudit(int64, string, int) ([][]byte, string, error) // 按时间倒序分页读取指定时间(unix纳秒)及之后的审计记录，入参(since, 上一页返回的游标, 每页数量)，返回的游标为空表示没有更多记录
+
+	Clean(string, string, []Node) error // 清理已失效的节点，入参(命名空间, 服务id, 节点)
+
+	Ping(context.Context) (int64, error) // 检查存储器是否可用，返回存储器中数据的最新修订版本号
+
+	MemberID() string               // 当前实例的成员ID
+	SaveMember(Member, int64) error // 注册或更新当前实例的成员信息，入参(成员信息, 租约的秒数)，租约到期后自动删除
+	DeleteMember() error            // 注销当前实例
+	Members() ([]Member, error)     // 获取所有存活的成员
+
+	Watch(context.Context) // 监听存储器的数据变更，直到上下文被取消
+	Close() error          // 关闭存储器的连接
+}
 
-	Watch() // 监听存储器的数据变更
+// 支持领导者选举的存储器实现的接口，用于只需在一个实例上运行的后台任务
+type Elector interface {
+	Campaign(context.Context, int64) (<-chan struct{}, error) // 参与选举，入参(上下文, 会话的秒数)，阻塞直到当选，返回失去领导权时关闭的通道
+	Resign(context.Context) error                             // 放弃领导权
+	Leader(context.Context) (string, error)                   // 获取当前领导者的成员ID
 }
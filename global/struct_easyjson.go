@@ -42,6 +42,31 @@ func easyjson9f2eff5fDecodeLocalGlobal(in *jlexer.Lexer, out *ServiceConfig) {
 			out.LoadBalance = string(in.String())
 		case "mete":
 			out.Mete = string(in.String())
+		case "tags":
+			if in.IsNull() {
+				in.Skip()
+				out.Tags = nil
+			} else {
+				in.Delim('[')
+				if out.Tags == nil {
+					if !in.IsDelim(']') {
+						out.Tags = make([]string, 0, 4)
+					} else {
+						out.Tags = []string{}
+					}
+				} else {
+					out.Tags = (out.Tags)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v1 string
+					v1 = string(in.String())
+					out.Tags = append(out.Tags, v1)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "meta_schema":
+			out.MetaSchema = string(in.String())
 		default:
 			in.SkipRecursive()
 		}
@@ -71,6 +96,25 @@ func easyjson9f2eff5fEncodeLocalGlobal(out *jwriter.Writer, in ServiceConfig) {
 		out.RawString(prefix)
 		out.String(string(in.Mete))
 	}
+	if len(in.Tags) != 0 {
+		const prefix string = ",\"tags\":"
+		out.RawString(prefix)
+		{
+			out.RawByte('[')
+			for v2, v3 := range in.Tags {
+				if v2 > 0 {
+					out.RawByte(',')
+				}
+				out.String(string(v3))
+			}
+			out.RawByte(']')
+		}
+	}
+	if in.MetaSchema != "" {
+		const prefix string = ",\"meta_schema\":"
+		out.RawString(prefix)
+		out.String(string(in.MetaSchema))
+	}
 	out.RawByte('}')
 }
 
@@ -128,6 +172,29 @@ func easyjson9f2eff5fDecodeLocalGlobal1(in *jlexer.Lexer, out *Node) {
 			out.Expires = int64(in.Int64())
 		case "mete":
 			out.Mete = string(in.String())
+		case "tags":
+			if in.IsNull() {
+				in.Skip()
+				out.Tags = nil
+			} else {
+				in.Delim('[')
+				if out.Tags == nil {
+					if !in.IsDelim(']') {
+						out.Tags = make([]string, 0, 4)
+					} else {
+						out.Tags = []string{}
+					}
+				} else {
+					out.Tags = (out.Tags)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v4 string
+					v4 = string(in.String())
+					out.Tags = append(out.Tags, v4)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
 		default:
 			in.SkipRecursive()
 		}
@@ -172,6 +239,20 @@ func easyjson9f2eff5fEncodeLocalGlobal1(out *jwriter.Writer, in Node) {
 		out.RawString(prefix)
 		out.String(string(in.Mete))
 	}
+	if len(in.Tags) != 0 {
+		const prefix string = ",\"tags\":"
+		out.RawString(prefix)
+		{
+			out.RawByte('[')
+			for v5, v6 := range in.Tags {
+				if v5 > 0 {
+					out.RawByte(',')
+				}
+				out.String(string(v6))
+			}
+			out.RawByte(']')
+		}
+	}
 	out.RawByte('}')
 }
 
@@ -0,0 +1,138 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"local/global"
+)
+
+// 组件的状态
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+	StatusLagging     = "lagging"
+)
+
+// 单个组件的状态
+type Component struct {
+	Status   string `json:"status"`
+	Revision int64  `json:"revision,omitempty"` // 修订版本号
+	Lag      int64  `json:"lag,omitempty"`      // 监听落后于存储器的修订版本数
+	Error    string `json:"error,omitempty"`
+}
+
+// 就绪检查的结果
+type Report struct {
+	Status     string               `json:"status"`
+	Components map[string]Component `json:"components,omitempty"`
+}
+
+// 是否已完成数据加载
+var loaded int32
+
+// 监听的状态
+var watch struct {
+	sync.Mutex
+	running    bool
+	revision   int64     // 监听已同步的修订版本号
+	target     int64     // 监听尚未同步到的存储器修订版本号
+	targetTime time.Time // 发现监听落后的时间
+}
+
+// 可替换的当前时间，便于测试
+var now = time.Now
+
+// 标记已完成从存储器加载数据
+func SetLoaded() {
+	atomic.StoreInt32(&loaded, 1)
+}
+
+// 标记监听已开始
+func WatchStarted() {
+	watch.Lock()
+	watch.running = true
+	watch.Unlock()
+}
+
+// 标记监听已停止
+func WatchStopped() {
+	watch.Lock()
+	watch.running = false
+	watch.Unlock()
+}
+
+// 记录监听已同步到的修订版本号
+func WatchProgress(revision int64) {
+	watch.Lock()
+	if revision > watch.revision {
+		watch.revision = revision
+	}
+	if watch.target != 0 && watch.revision >= watch.target {
+		watch.target = 0
+	}
+	watch.Unlock()
+}
+
+// 检查监听的状态，入参(存储器的最新修订版本号, 允许落后的时长)
+// 监听落后于存储器的时长超过maxLag时视为落后，避免刚写入的数据尚未推送时误判
+func CheckWatch(storageRevision int64, maxLag time.Duration) Component {
+	watch.Lock()
+	defer watch.Unlock()
+	if !watch.running {
+		return Component{Status: StatusUnavailable, Revision: watch.revision, Error: "监听未运行"}
+	}
+	result := Component{Status: StatusOK, Revision: watch.revision}
+	if storageRevision <= watch.revision {
+		watch.target = 0
+		return result
+	}
+	result.Lag = storageRevision - watch.revision
+	if watch.target == 0 {
+		watch.target = storageRevision
+		watch.targetTime = now()
+	}
+	if now().Sub(watch.targetTime) > maxLag {
+		result.Status = StatusLagging
+	}
+	return result
+}
+
+// 存活检查，进程能响应即为存活
+func Live() Report {
+	return Report{Status: StatusOK, Components: map[string]Component{}}
+}
+
+// 就绪检查，要求已完成数据加载、存储器可用且监听正在运行并未落后
+func Ready(ctx context.Context, storage global.StorageType, maxLag time.Duration) Report {
+	report := Report{Status: StatusOK, Components: make(map[string]Component, 3)}
+
+	if atomic.LoadInt32(&loaded) == 1 {
+		report.Components["data"] = Component{Status: StatusOK}
+	} else {
+		report.Components["data"] = Component{Status: StatusUnavailable, Error: "数据尚未加载"}
+	}
+
+	var revision int64
+	if storage == nil {
+		report.Components["storage"] = Component{Status: StatusUnavailable, Error: "存储器未构建"}
+	} else if rev, err := storage.Ping(ctx); err != nil {
+		report.Components["storage"] = Component{Status: StatusUnavailable, Error: err.Error()}
+	} else {
+		revision = rev
+		report.Components["storage"] = Component{Status: StatusOK, Revision: rev}
+	}
+
+	// 存储器不可用时无法判断监听是否落后
+	report.Components["watch"] = CheckWatch(revision, maxLag)
+
+	for _, component := range report.Components {
+		if component.Status != StatusOK {
+			report.Status = StatusUnavailable
+			break
+		}
+	}
+	return report
+}
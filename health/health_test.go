@@ -0,0 +1,73 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"local/global"
+)
+
+// 只实现Ping的存储器
+type pingStorage struct {
+	global.StorageType
+	revision int64
+	err      error
+}
+
+func (self *pingStorage) Ping(context.Context) (int64, error) {
+	return self.revision, self.err
+}
+
+func TestCheckWatch(t *testing.T) {
+	current := time.Now()
+	now = func() time.Time { return current }
+	defer func() { now = time.Now }()
+
+	if result := CheckWatch(0, time.Second); result.Status != StatusUnavailable {
+		t.Fatalf("监听未运行时状态应为%s，实际为%s", StatusUnavailable, result.Status)
+	}
+	WatchStarted()
+	defer WatchStopped()
+	WatchProgress(10)
+
+	if result := CheckWatch(10, time.Second); result.Status != StatusOK {
+		t.Fatalf("监听已同步时状态应为%s，实际为%s", StatusOK, result.Status)
+	}
+	// 刚发现落后时不视为落后
+	result := CheckWatch(15, time.Second)
+	if result.Status != StatusOK || result.Lag != 5 {
+		t.Fatalf("刚发现落后时状态应为%s，落后5，实际为%s，落后%d", StatusOK, result.Status, result.Lag)
+	}
+	current = current.Add(2 * time.Second)
+	if result = CheckWatch(16, time.Second); result.Status != StatusLagging {
+		t.Fatalf("落后超时后状态应为%s，实际为%s", StatusLagging, result.Status)
+	}
+	// 同步到发现落后时的修订版本号后重新计时
+	WatchProgress(15)
+	if result = CheckWatch(16, time.Second); result.Status != StatusOK {
+		t.Fatalf("同步后状态应为%s，实际为%s", StatusOK, result.Status)
+	}
+}
+
+func TestReady(t *testing.T) {
+	WatchStarted()
+	defer WatchStopped()
+	WatchProgress(20)
+	storage := &pingStorage{revision: 20}
+
+	report := Ready(context.Background(), storage, time.Second)
+	if report.Status != StatusUnavailable || report.Components["data"].Status != StatusUnavailable {
+		t.Fatal("数据未加载时不应就绪")
+	}
+	SetLoaded()
+	if report = Ready(context.Background(), storage, time.Second); report.Status != StatusOK {
+		t.Fatalf("应已就绪，实际为%+v", report)
+	}
+	storage.err = errors.New("connection refused")
+	report = Ready(context.Background(), storage, time.Second)
+	if report.Status != StatusUnavailable || report.Components["storage"].Error != "connection refused" {
+		t.Fatalf("存储器不可用时不应就绪，实际为%+v", report)
+	}
+}
@@ -0,0 +1,172 @@
+package leader
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"local/global"
+	"local/storage"
+)
+
+// 只在当前实例为领导者时运行的任务，失去领导权或进程退出时上下文被取消，任务应随之返回
+type Task func(ctx context.Context)
+
+// 领导权的状态
+type Status struct {
+	Supported bool     `json:"supported"`       // 存储器是否支持选举，不支持时由当前实例运行所有任务
+	Leader    bool     `json:"leader"`          // 当前实例是否为领导者
+	Since     int64    `json:"since,omitempty"` // 成为领导者的时间(unix时间戳)
+	MemberID  string   `json:"member_id"`       // 当前实例的成员ID
+	LeaderID  string   `json:"leader_id"`       // 当前领导者的成员ID，没有领导者时为空
+	Tasks     []string `json:"tasks"`           // 已注册的任务名称
+}
+
+// 当前实例持有的领导权
+type leadership struct {
+	ctx   context.Context
+	wg    sync.WaitGroup
+	since int64
+}
+
+var (
+	mutex     sync.Mutex
+	tasks     = make(map[string]Task)
+	current   *leadership
+	supported bool
+)
+
+// 注册任务，当前实例已是领导者时立即运行
+func Register(name string, task Task) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	tasks[name] = task
+	if current != nil {
+		start(current, name, task)
+	}
+}
+
+// 运行任务，调用方须持有mutex
+func start(l *leadership, name string, task Task) {
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		log.Debug().Str("task", name).Msg("开始运行领导者任务")
+		task(l.ctx)
+		log.Debug().Str("task", name).Msg("领导者任务已结束")
+	}()
+}
+
+// 当前实例是否为领导者
+func IsLeader() bool {
+	mutex.Lock()
+	defer mutex.Unlock()
+	return current != nil
+}
+
+// 获取领导权的状态，存储器支持选举时从存储器获取当前领导者
+func Get(ctx context.Context) (status Status, err error) {
+	mutex.Lock()
+	status.Supported = supported
+	status.Leader = current != nil
+	if current != nil {
+		status.Since = current.since
+	}
+	status.Tasks = make([]string, 0, len(tasks))
+	for name := range tasks {
+		status.Tasks = append(status.Tasks, name)
+	}
+	mutex.Unlock()
+	sort.Strings(status.Tasks)
+
+	status.MemberID = global.Storage.MemberID()
+	elector, ok := storage.Elector(global.Storage)
+	if !ok {
+		if status.Leader {
+			status.LeaderID = status.MemberID
+		}
+		return
+	}
+	status.LeaderID, err = elector.Leader(ctx)
+	return
+}
+
+// 参与选举并在当选后运行已注册的任务，直到上下文被取消后放弃领导权，返回结束时关闭的通道
+// 存储器不支持选举时，当前实例直接成为领导者
+func Run(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	elector, ok := storage.Elector(global.Storage)
+	mutex.Lock()
+	supported = ok
+	mutex.Unlock()
+	go func() {
+		defer close(done)
+		if !ok {
+			log.Warn().Msg("存储器不支持选举，由当前实例运行所有领导者任务")
+			lead(ctx, nil)
+			return
+		}
+		ttl := int64(math.Ceil(global.Config().Leader.TTL.Seconds()))
+		campaign(ctx, elector, ttl, global.Config().Leader.RetryInterval)
+	}()
+	return done
+}
+
+// 循环参与选举，失去领导权后重新参与
+func campaign(ctx context.Context, elector global.Elector, ttl int64, retryInterval time.Duration) {
+	for {
+		lost, err := elector.Campaign(ctx, ttl)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Err(err).Caller().Msg("参与选举失败")
+			select {
+			case <-time.After(retryInterval):
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+		log.Info().Msg("当前实例成为领导者")
+		lead(ctx, lost)
+
+		// 失去领导权时会话已失效，也需要清理本地的会话
+		resignCtx, resignCancel := context.WithTimeout(context.Background(), retryInterval)
+		if err = elector.Resign(resignCtx); err != nil {
+			log.Err(err).Caller().Msg("放弃领导权失败")
+		}
+		resignCancel()
+		if ctx.Err() != nil {
+			return
+		}
+		log.Warn().Msg("当前实例失去领导权，重新参与选举")
+	}
+}
+
+// 持有领导权并运行所有任务，直到失去领导权或上下文被取消，等待所有任务结束后返回
+func lead(ctx context.Context, lost <-chan struct{}) {
+	leadCtx, cancel := context.WithCancel(ctx)
+	l := &leadership{ctx: leadCtx, since: time.Now().Unix()}
+	mutex.Lock()
+	current = l
+	for name, task := range tasks {
+		start(l, name, task)
+	}
+	mutex.Unlock()
+
+	select {
+	case <-lost:
+	case <-ctx.Done():
+	}
+
+	mutex.Lock()
+	current = nil
+	mutex.Unlock()
+	cancel()
+	l.wg.Wait()
+}
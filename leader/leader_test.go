@@ -0,0 +1,107 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 依次当选的选举器，每次当选返回新的失去领导权通道
+type testElector struct {
+	elected chan chan struct{}
+	resigns int32
+	fails   int32
+}
+
+func (self *testElector) Campaign(ctx context.Context, ttl int64) (<-chan struct{}, error) {
+	if atomic.AddInt32(&self.fails, -1) >= 0 {
+		return nil, errors.New("unavailable")
+	}
+	select {
+	case lost := <-self.elected:
+		return lost, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (self *testElector) Resign(context.Context) error {
+	atomic.AddInt32(&self.resigns, 1)
+	return nil
+}
+
+func (self *testElector) Leader(context.Context) (string, error) {
+	return "", nil
+}
+
+func waitFor(t *testing.T, condition func() bool, message string) {
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal(message)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCampaign(t *testing.T) {
+	var running int32
+	Register("test", func(ctx context.Context) {
+		atomic.AddInt32(&running, 1)
+		<-ctx.Done()
+		atomic.AddInt32(&running, -1)
+	})
+	defer func() {
+		mutex.Lock()
+		delete(tasks, "test")
+		mutex.Unlock()
+	}()
+
+	elector := &testElector{elected: make(chan chan struct{}), fails: 1}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		campaign(ctx, elector, 1, time.Millisecond)
+		close(done)
+	}()
+
+	if IsLeader() || atomic.LoadInt32(&running) != 0 {
+		t.Fatal("当选前不应运行任务")
+	}
+	lost := make(chan struct{})
+	elector.elected <- lost
+	waitFor(t, func() bool { return IsLeader() && atomic.LoadInt32(&running) == 1 }, "当选后应运行任务")
+
+	// 当选后注册的任务立即运行
+	var late int32
+	Register("late", func(ctx context.Context) {
+		atomic.StoreInt32(&late, 1)
+		<-ctx.Done()
+	})
+	defer func() {
+		mutex.Lock()
+		delete(tasks, "late")
+		mutex.Unlock()
+	}()
+	waitFor(t, func() bool { return atomic.LoadInt32(&late) == 1 }, "当选后注册的任务应立即运行")
+
+	// 失去领导权后停止任务并重新参与选举
+	close(lost)
+	waitFor(t, func() bool { return !IsLeader() && atomic.LoadInt32(&running) == 0 }, "失去领导权后应停止任务")
+	waitFor(t, func() bool { return atomic.LoadInt32(&elector.resigns) == 1 }, "失去领导权后应清理会话")
+
+	elector.elected <- make(chan struct{})
+	waitFor(t, func() bool { return IsLeader() && atomic.LoadInt32(&running) == 1 }, "重新当选后应运行任务")
+
+	// 退出时停止任务并放弃领导权
+	cancel()
+	<-done
+	if IsLeader() || atomic.LoadInt32(&running) != 0 {
+		t.Fatal("退出后不应运行任务")
+	}
+	if atomic.LoadInt32(&elector.resigns) != 2 {
+		t.Fatal("退出时应放弃领导权")
+	}
+}
@@ -4,14 +4,35 @@ import (
 	"errors"
 	"io"
 	"os"
-	"strings"
+	"sync/atomic"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
 	"local/global"
+	"local/rotate"
 )
 
+// 日志的输出目标
+type logOutput struct {
+	writer zerolog.LevelWriter
+	file   *rotate.File // 日志文件，未设置时为nil
+}
+
+// 当前的日志输出，重新加载配置时替换
+var currentLogOutput atomic.Value
+
+// 将日志写入当前输出的writer，替换输出时无需修改log.Logger
+type switchWriter struct{}
+
+func (switchWriter) Write(p []byte) (int, error) {
+	return currentLogOutput.Load().(*logOutput).writer.Write(p)
+}
+
+func (switchWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	return currentLogOutput.Load().(*logOutput).writer.WriteLevel(level, p)
+}
+
 func setDefaultLogger() {
 	zerolog.SetGlobalLevel(zerolog.DebugLevel)
 	zerolog.TimeFieldFormat = global.FormatTime("y-m-d h:i:s")
@@ -23,73 +44,119 @@ func setDefaultLogger() {
 
 // 根据配置文件设置logger
 func setLogger() error {
-	// 设置级别
-	level := strings.ToLower(global.Config.Logger.Level)
-	switch level {
-	case "info":
-		zerolog.SetGlobalLevel(zerolog.InfoLevel)
-	case "warn":
-		zerolog.SetGlobalLevel(zerolog.WarnLevel)
-	case "error":
-		zerolog.SetGlobalLevel(zerolog.ErrorLevel)
-	case "empty":
-		zerolog.SetGlobalLevel(zerolog.NoLevel)
-	case "debug":
-		zerolog.SetGlobalLevel(zerolog.DebugLevel)
-	default:
-		zerolog.SetGlobalLevel(zerolog.Disabled)
-		return nil
-	}
+	config := &global.Config().Logger
 
-	// 设置时间格式
-	if global.Config.Logger.TimeFormat == "timestamp" {
+	// 设置时间格式，只在启动时设置
+	if config.TimeFormat == "timestamp" {
 		zerolog.TimeFieldFormat = zerolog.TimeFormatUnix
 	} else {
-		zerolog.TimeFieldFormat = global.FormatTime(global.Config.Logger.TimeFormat)
+		zerolog.TimeFieldFormat = global.FormatTime(config.TimeFormat)
 	}
 
-	// 设置日志输出方式
-	var output io.Writer
-	var logFile *os.File
-	var err error
-	// 设置日志文件
-	if global.Config.Logger.FilePath != "" {
-		// 输出到文件
-		if global.Config.Logger.FileMode == 0 {
-			global.Config.Logger.FileMode = os.FileMode(0600)
+	output, err := newLogOutput(global.Config())
+	if err != nil {
+		return err
+	}
+	if err = setLogLevel(global.Config()); err != nil {
+		_ = output.close()
+		return err
+	}
+	currentLogOutput.Store(output)
+	log.Logger = log.Output(switchWriter{})
+	return nil
+}
+
+// 重新加载logger的配置，替换日志输出并关闭原日志文件
+func reloadLogger(output *logOutput, config *global.ConfigType) error {
+	if err := setLogLevel(config); err != nil {
+		return err
+	}
+	previous, _ := currentLogOutput.Load().(*logOutput)
+	currentLogOutput.Store(output)
+	if previous != nil {
+		return previous.close()
+	}
+	return nil
+}
+
+// 设置记录级别，留空则禁用logger
+func setLogLevel(config *global.ConfigType) error {
+	if config.Logger.Level == "" {
+		zerolog.SetGlobalLevel(zerolog.Disabled)
+		return nil
+	}
+	return global.SetLogLevel(config.Logger.Level)
+}
+
+// 根据配置创建日志输出，未设置日志文件或启用了控制台时输出到控制台，两者可使用不同的编码
+func newLogOutput(c *global.ConfigType) (*logOutput, error) {
+	config := &c.Logger
+	var (
+		outputs []io.Writer
+		result  logOutput
+	)
+	if config.FilePath == "" || config.Console {
+		output, err := encodeOutput(os.Stdout, firstEncode(config.ConsoleEncode, config.Encode), false)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, output)
+	}
+	if config.FilePath != "" {
+		file, err := rotate.OpenOptions(config.FilePath, rotate.Options{
+			Mode:       config.FileMode,
+			MaxSize:    int64(config.MaxSize) * 1024 * 1024,
+			MaxAge:     config.MaxAge,
+			MaxBackups: int(config.MaxBackups),
+			Retention:  config.Retention,
+			Compress:   config.Compress,
+		})
+		if err != nil {
+			return nil, err
 		}
-		logFile, err = os.OpenFile(global.Config.Logger.FilePath, os.O_RDWR|os.O_CREATE|os.O_APPEND, global.Config.Logger.FileMode)
-		if nil != err {
-			return err
+		output, err := encodeOutput(file, firstEncode(config.FileEncode, config.Encode), true)
+		if err != nil {
+			_ = file.Close()
+			return nil, err
 		}
+		result.file = file
+		outputs = append(outputs, output)
 	}
-	switch global.Config.Logger.Encode {
+	result.writer = zerolog.MultiLevelWriter(outputs...)
+	return &result, nil
+}
+
+// 关闭日志文件
+func (self *logOutput) close() error {
+	if self.file == nil {
+		return nil
+	}
+	return self.file.Close()
+}
+
+// 返回第一个非空的编码
+func firstEncode(encodes ...string) string {
+	for _, encode := range encodes {
+		if encode != "" {
+			return encode
+		}
+	}
+	return ""
+}
+
+// 按编码包装日志输出，写入文件时不使用颜色
+func encodeOutput(out io.Writer, encode string, noColor bool) (io.Writer, error) {
+	switch encode {
 	// console编码
 	case "console":
-		if logFile != nil {
-			output = zerolog.ConsoleWriter{
-				Out:        logFile,
-				NoColor:    true,
-				TimeFormat: zerolog.TimeFieldFormat,
-			}
-		} else {
-			output = zerolog.ConsoleWriter{
-				Out:        os.Stdout,
-				TimeFormat: zerolog.TimeFieldFormat,
-			}
-		}
+		return zerolog.ConsoleWriter{
+			Out:        out,
+			NoColor:    noColor,
+			TimeFormat: zerolog.TimeFieldFormat,
+		}, nil
 	// json编码
 	case "json":
-		if logFile != nil {
-			output = logFile
-		} else {
-			output = os.Stdout
-		}
-	default:
-		return errors.New("从配置文件的logger.encode中获得了未知的参数，目前只支持json|console")
+		return out, nil
 	}
-
-	log.Logger = log.Output(output)
-
-	return nil
+	return nil, errors.New("从配置文件的logger中获得了未知的编码：" + encode + "，目前只支持json|console")
 }
@@ -2,16 +2,30 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"flag"
+	"io/ioutil"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
+	"syscall"
 	"time"
 
+	"local/accesslog"
 	"local/api"
+	"local/audit"
+	"local/federation"
 	"local/global"
+	"local/health"
+	"local/leader"
+	"local/member"
 	"local/storage"
+	"local/tracing"
 
 	"github.com/bwmarrin/snowflake"
 	"github.com/dxvgef/tsing"
@@ -21,7 +35,6 @@ import (
 
 func main() {
 	var (
-		configFile     string
 		err            error
 		apiHttpServer  *http.Server
 		apiHttpsServer *http.Server
@@ -31,8 +44,15 @@ func main() {
 	setDefaultLogger()
 
 	// --------------------- 加载配置文件 ----------------------
+	var checkConfig bool
 	flag.StringVar(&configFile, "c", "./config.toml", "配置文件路径")
+	flag.BoolVar(&checkConfig, "check-config", false, "校验配置并输出生效的配置(隐藏敏感值)后退出")
+	// 每个配置项都可以通过命令行参数覆盖，例如-api.http.port=20081
+	global.RegisterFlags(flag.CommandLine)
 	flag.Parse()
+	if checkConfig {
+		os.Exit(checkConfigFile())
+	}
 	err = global.LoadConfigFile(configFile)
 	if err != nil {
 		log.Fatal().Err(err).Caller().Msg("加载配置文件失败")
@@ -46,6 +66,18 @@ func main() {
 		return
 	}
 
+	// --------------------- 配置追踪 ----------------------
+	if err = setTracing(); err != nil {
+		log.Fatal().Err(err).Caller().Msg("配置追踪失败")
+		return
+	}
+
+	// --------------------- 配置访问日志 ----------------------
+	if err = accesslog.Init(); err != nil {
+		log.Fatal().Err(err).Caller().Msg("初始化访问日志失败")
+		return
+	}
+
 	// --------------------- 配置snowflake id ----------------------
 	snowflake.Epoch = time.Now().Unix()
 	global.SnowflakeNode, err = snowflake.NewNode(int64(time.Now().Hour()))
@@ -55,22 +87,50 @@ func main() {
 	}
 
 	// --------------------- 根据配置构建存储器 ----------------------
-	global.Storage, err = storage.Build(global.Config.Storage.Name, global.Config.Storage.Config)
+	global.Storage, err = storage.Build(global.Config().Storage.Name, global.Config().Storage.Config)
 	if err != nil {
 		log.Fatal().Err(err).Caller().Msg("构建存储器失败")
 		return
 	}
+	// 初始化审计日志，写入存储器时依赖已构建的存储器
+	if err = audit.Init(); err != nil {
+		log.Fatal().Err(err).Caller().Msg("初始化审计日志失败")
+		return
+	}
 	// 从存储器中加载所有数据
 	if err = global.Storage.LoadAll(); err != nil {
 		log.Fatal().Err(err).Caller().Msg("加载数据失败")
 		return
 	}
+	health.SetLoaded()
+
+	// 监听存储中的数据变更，退出时取消
+	watchCtx, cancelWatch := context.WithCancel(context.Background())
+	watchDone := watchStorage(watchCtx)
+
+	// 在存储器中注册为集群成员并定时更新，退出时注销
+	memberCtx, cancelMember := context.WithCancel(context.Background())
+	memberDone := member.Run(memberCtx)
+
+	// 从远端数据中心的集群导入服务
+	federationCtx, cancelFederation := context.WithCancel(context.Background())
+	federationDone := federation.Run(federationCtx)
+
+	// 参与领导者选举，当选后运行只需在一个实例上运行的后台任务
+	leaderCtx, cancelLeader := context.WithCancel(context.Background())
+	leaderDone := leader.Run(leaderCtx)
 
-	// 监听存储中的数据变更
-	watchStorage()
+	// 可通过API重新加载配置文件
+	api.ConfigReloader = reloadConfig
+
+	// 请求的上下文，退出时取消，使阻塞查询及事件推送等长连接结束
+	serverCtx, cancelServer := context.WithCancel(context.Background())
+	baseContext := func(net.Listener) context.Context {
+		return serverCtx
+	}
 
 	// 启动API服务
-	if global.Config.API.HTTP.Port > 0 || global.Config.API.HTTPS.Port > 0 {
+	if global.Config().API.HTTP.Port > 0 || global.Config().API.HTTPS.Port > 0 {
 		var (
 			apiEngineConfig tsing.Config
 			rootPath        string
@@ -81,6 +141,9 @@ func main() {
 		apiEngineConfig.EventSource = true
 		apiEngineConfig.EventTrace = true
 		apiEngineConfig.EventHandlerError = true
+		// v1 API的路径参数使用URL编码
+		apiEngineConfig.UseRawPath = true
+		apiEngineConfig.UnescapePathValues = true
 		rootPath, err = os.Getwd()
 		if err == nil {
 			apiEngineConfig.RootPath = rootPath
@@ -88,19 +151,22 @@ func main() {
 		apiEngine := tsing.New(apiEngineConfig)
 		// 设置路由
 		api.SetRouter(apiEngine)
+		// 记录每个请求的指标
+		apiHandler := api.Instrument(apiEngine)
 		// 启动api http服务
-		if global.Config.API.HTTP.Port > 0 {
+		if global.Config().API.HTTP.Port > 0 {
+			apiHttpServer = &http.Server{
+				Addr:              net.JoinHostPort(global.Config().API.IP, strconv.FormatUint(uint64(global.Config().API.HTTP.Port), 10)),
+				Handler:           apiHandler,
+				ReadTimeout:       global.Config().API.ReadTimeout,
+				WriteTimeout:      global.Config().API.WriteTimeout,
+				IdleTimeout:       global.Config().API.IdleTimeout,
+				ReadHeaderTimeout: global.Config().API.ReadHeaderTimeout,
+				BaseContext:       baseContext,
+			}
 			go func() {
-				apiHttpServer = &http.Server{
-					Addr:              global.Config.API.IP + ":" + strconv.FormatUint(uint64(global.Config.API.HTTP.Port), 10),
-					Handler:           apiEngine,
-					ReadTimeout:       global.Config.API.ReadTimeout,
-					WriteTimeout:      global.Config.API.WriteTimeout,
-					IdleTimeout:       global.Config.API.IdleTimeout,
-					ReadHeaderTimeout: global.Config.API.ReadHeaderTimeout,
-				}
 				log.Info().Str("addr", apiHttpServer.Addr).Msg("API HTTP服务")
-				if err = apiHttpServer.ListenAndServe(); err != nil {
+				if err := apiHttpServer.ListenAndServe(); err != nil {
 					if err == http.ErrServerClosed {
 						log.Info().Msg("API HTTP服务已关闭")
 						return
@@ -112,24 +178,32 @@ func main() {
 		}
 
 		// 启动api https服务
-		if global.Config.API.HTTPS.Port > 0 {
-			go func() {
-				apiHttpsServer = &http.Server{
-					Addr:              global.Config.API.IP + ":" + strconv.FormatUint(uint64(global.Config.API.HTTPS.Port), 10),
-					Handler:           apiEngine,
-					ReadTimeout:       global.Config.API.ReadTimeout,
-					WriteTimeout:      global.Config.API.WriteTimeout,
-					IdleTimeout:       global.Config.API.IdleTimeout,
-					ReadHeaderTimeout: global.Config.API.ReadHeaderTimeout,
-				}
-				if global.Config.API.HTTPS.HTTP2 {
-					if err = http2.ConfigureServer(apiHttpsServer, &http2.Server{}); err != nil {
-						log.Fatal().Err(err).Caller().Msg("启动API HTTP2支持失败")
-						return
-					}
+		if global.Config().API.HTTPS.Port > 0 {
+			tlsConfig, err := apiTLSConfig()
+			if err != nil {
+				log.Fatal().Err(err).Caller().Msg("配置API HTTPS服务的TLS失败")
+				return
+			}
+			apiHttpsServer = &http.Server{
+				Addr:              net.JoinHostPort(global.Config().API.IP, strconv.FormatUint(uint64(global.Config().API.HTTPS.Port), 10)),
+				Handler:           apiHandler,
+				ReadTimeout:       global.Config().API.ReadTimeout,
+				WriteTimeout:      global.Config().API.WriteTimeout,
+				IdleTimeout:       global.Config().API.IdleTimeout,
+				ReadHeaderTimeout: global.Config().API.ReadHeaderTimeout,
+				TLSConfig:         tlsConfig,
+				BaseContext:       baseContext,
+			}
+			if global.Config().API.HTTPS.HTTP2 {
+				if err = http2.ConfigureServer(apiHttpsServer, &http2.Server{}); err != nil {
+					log.Fatal().Err(err).Caller().Msg("启动API HTTP2支持失败")
+					return
 				}
-				log.Info().Bool("HTTP2", global.Config.API.HTTPS.HTTP2).Str("addr", apiHttpsServer.Addr).Msg("API HTTPS服务")
-				if err = apiHttpsServer.ListenAndServeTLS(global.Config.API.HTTPS.Cert, global.Config.API.HTTPS.Key); err != nil {
+			}
+			go func() {
+				log.Info().Bool("HTTP2", global.Config().API.HTTPS.HTTP2).Str("addr", apiHttpsServer.Addr).Msg("API HTTPS服务")
+				// 证书通过GetCertificate获取，以便重新加载配置时更新
+				if err := apiHttpsServer.ListenAndServeTLS("", ""); err != nil {
 					if err == http.ErrServerClosed {
 						log.Info().Msg("API HTTPS服务已关闭")
 						return
@@ -141,35 +215,136 @@ func main() {
 		}
 	}
 
-	// 阻塞并等待退出超时
+	// 收到SIGHUP信号时重新加载配置文件
+	watchReloadSignal()
+
+	// 阻塞并等待退出信号，systemd及容器使用SIGTERM
 	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt)
-	<-quit
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	sig := <-quit
+	log.Info().Str("signal", sig.String()).Msg("开始退出")
 
-	ctx, cancel := context.WithTimeout(context.Background(), global.Config.API.QuitWaitTimeout)
+	// 以下所有步骤共用退出等待超时时间
+	ctx, cancel := context.WithTimeout(context.Background(), global.Config().API.QuitWaitTimeout)
 	defer cancel()
 
-	// 关闭API HTTP服务
-	if global.Config.API.HTTP.Port > 0 {
-		if err := apiHttpServer.Shutdown(ctx); err != nil {
-			log.Fatal().Err(err).Caller().Msg("启动API HTTP服务失败")
-			return
+	// 停止接受新请求，取消长连接的上下文，并等待处理中的请求完成
+	cancelServer()
+	for _, server := range []*http.Server{apiHttpServer, apiHttpsServer} {
+		if server == nil {
+			continue
 		}
-	}
-	// 关闭API HTTPS服务
-	if global.Config.API.HTTPS.Port > 0 {
-		if err := apiHttpsServer.Shutdown(ctx); err != nil {
-			log.Fatal().Err(err).Caller().Msg("启动API HTTPS服务失败")
-			return
+		if err := server.Shutdown(ctx); err != nil {
+			log.Err(err).Caller().Str("addr", server.Addr).Msg("关闭API服务失败")
 		}
 	}
 
+	// 停止从远端集群导入服务
+	cancelFederation()
+	select {
+	case <-federationDone:
+	case <-ctx.Done():
+		log.Error().Msg("等待停止导入远端服务超时")
+	}
+
+	// 停止领导者任务并放弃领导权，使其它实例尽快当选
+	cancelLeader()
+	select {
+	case <-leaderDone:
+	case <-ctx.Done():
+		log.Error().Msg("等待放弃领导权超时")
+	}
+
+	// 注销集群成员
+	cancelMember()
+	select {
+	case <-memberDone:
+	case <-ctx.Done():
+		log.Error().Msg("等待注销集群成员超时")
+	}
+
+	// 停止监听存储器的数据变更
+	cancelWatch()
+	select {
+	case <-watchDone:
+	case <-ctx.Done():
+		log.Error().Msg("等待停止监听数据变更超时")
+	}
+
+	// 等待选取节点时触发的清理完成
+	if err := global.WaitBackground(ctx); err != nil {
+		log.Err(err).Caller().Msg("等待清理失效节点超时")
+	}
+
+	// 发送缓存中的追踪数据
+	if err := tracing.Shutdown(); err != nil {
+		log.Err(err).Caller().Msg("关闭追踪数据的导出器失败")
+	}
+	if err := accesslog.Close(); err != nil {
+		log.Err(err).Caller().Msg("关闭访问日志文件失败")
+	}
+
+	// 关闭存储器的连接
+	if err := global.Storage.Close(); err != nil {
+		log.Err(err).Caller().Msg("关闭存储器失败")
+	}
+
 	log.Info().Msg("进程已退出")
 }
 
-func watchStorage() {
+// 根据配置设置追踪数据的导出器
+func setTracing() error {
+	config := &global.Config().Tracing
+	switch config.Exporter {
+	case "":
+		return tracing.Setup(nil, 0)
+	case "otlp":
+		serviceName := config.ServiceName
+		if serviceName == "" {
+			serviceName = "tsing-center"
+		}
+		return tracing.Setup(tracing.NewOTLPExporter(config.Endpoint, serviceName, config.FlushInterval), float64(config.SampleRatio))
+	}
+	return errors.New("从配置文件的tracing.exporter中获得了未知的参数，目前只支持otlp")
+}
+
+// 构建API HTTPS服务的TLS配置，证书可在重新加载配置时更新，配置了客户端CA时验证客户端证书
+func apiTLSConfig() (*tls.Config, error) {
+	var config tls.Config
+	cert, err := loadCertificate(global.Config())
+	if err != nil {
+		return nil, err
+	}
+	apiCertificate.Store(cert)
+	config.GetCertificate = getCertificate
+	clientAuth := &global.Config().API.HTTPS.ClientAuth
+	if clientAuth.CA == "" {
+		return &config, nil
+	}
+	caBytes, err := ioutil.ReadFile(filepath.Clean(clientAuth.CA))
+	if err != nil {
+		return nil, err
+	}
+	config.ClientCAs = x509.NewCertPool()
+	if !config.ClientCAs.AppendCertsFromPEM(caBytes) {
+		return nil, errors.New("客户端CA文件中没有有效的证书")
+	}
+	if clientAuth.Require {
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	} else {
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return &config, nil
+}
+
+// 开始监听数据变更，返回监听停止时关闭的通道
+func watchStorage(ctx context.Context) <-chan struct{} {
 	log.Info().Msg("开始监听数据变更")
+	done := make(chan struct{})
 	go func() {
-		global.Storage.Watch()
+		defer close(done)
+		global.Storage.Watch(ctx)
+		log.Info().Msg("已停止监听数据变更")
 	}()
+	return done
 }
@@ -0,0 +1,116 @@
+package member
+
+import (
+	"context"
+	"math"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"local/global"
+	"local/health"
+)
+
+// 进程的启动时间
+var startTime = time.Now().Unix()
+
+// 成员的状态
+type Status struct {
+	global.Member
+	Self bool  `json:"self"` // 是否为当前实例
+	Lag  int64 `json:"lag"`  // 落后于所有成员中最新修订版本号的版本数
+}
+
+// 当前实例的API服务地址，未配置时根据监听地址或主机名及端口生成
+func Address() string {
+	if global.Config().Member.Address != "" {
+		return global.Config().Member.Address
+	}
+	host := global.Config().API.IP
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		if hostname, err := os.Hostname(); err == nil {
+			host = hostname
+		}
+	}
+	if global.Config().API.HTTP.Port > 0 {
+		return "http://" + net.JoinHostPort(host, strconv.FormatUint(uint64(global.Config().API.HTTP.Port), 10))
+	}
+	return "https://" + net.JoinHostPort(host, strconv.FormatUint(uint64(global.Config().API.HTTPS.Port), 10))
+}
+
+// 在存储器中注册当前实例并定时更新，直到上下文被取消后注销，返回注销完成时关闭的通道
+func Run(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(global.Config().Member.Interval)
+		defer ticker.Stop()
+		for {
+			update(ctx)
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				if err := global.Storage.DeleteMember(); err != nil {
+					log.Err(err).Caller().Msg("注销成员失败")
+				}
+				return
+			}
+		}
+	}()
+	return done
+}
+
+// 更新当前实例的成员信息，存储器不可用时不更新，租约到期后其它实例将不再看到本实例
+func update(ctx context.Context) {
+	pingCtx, cancel := context.WithTimeout(ctx, global.Config().API.Health.Timeout)
+	defer cancel()
+	revision, err := global.Storage.Ping(pingCtx)
+	if err != nil {
+		log.Err(err).Caller().Msg("存储器不可用，未更新成员信息")
+		return
+	}
+	watch := health.CheckWatch(revision, global.Config().API.Health.MaxWatchLag)
+	member := global.Member{
+		Address:    Address(),
+		Version:    global.Version,
+		StartTime:  startTime,
+		Revision:   watch.Revision,
+		Lagging:    watch.Status != health.StatusOK,
+		UpdateTime: time.Now().Unix(),
+	}
+	ttl := int64(math.Ceil(global.Config().Member.TTL.Seconds()))
+	if err = global.Storage.SaveMember(member, ttl); err != nil {
+		log.Err(err).Caller().Msg("更新成员信息失败")
+	}
+}
+
+// 获取所有存活的成员，按ID排序
+func List(storage global.StorageType) ([]Status, error) {
+	members, err := storage.Members()
+	if err != nil {
+		return nil, err
+	}
+	var latest int64
+	for k := range members {
+		if members[k].Revision > latest {
+			latest = members[k].Revision
+		}
+	}
+	self := storage.MemberID()
+	result := make([]Status, len(members))
+	for k := range members {
+		result[k] = Status{
+			Member: members[k],
+			Self:   members[k].ID == self,
+			Lag:    latest - members[k].Revision,
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
@@ -0,0 +1,51 @@
+package member
+
+import (
+	"testing"
+
+	"local/global"
+)
+
+// 只实现成员相关方法的存储器
+type testStorage struct {
+	global.StorageType
+	members []global.Member
+}
+
+func (self *testStorage) MemberID() string {
+	return "b"
+}
+
+func (self *testStorage) Members() ([]global.Member, error) {
+	return self.members, nil
+}
+
+func TestList(t *testing.T) {
+	storage := &testStorage{members: []global.Member{
+		{ID: "c", Revision: 7},
+		{ID: "a", Revision: 10},
+		{ID: "b", Revision: 9, Lagging: true},
+	}}
+	list, err := List(storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 {
+		t.Fatalf("应有3个成员，实际为%d", len(list))
+	}
+	lags := map[string]int64{"a": 0, "b": 1, "c": 3}
+	for k := range list {
+		if k > 0 && list[k-1].ID >= list[k].ID {
+			t.Fatal("成员应按ID排序")
+		}
+		if list[k].Lag != lags[list[k].ID] {
+			t.Fatalf("成员%s落后的版本数应为%d，实际为%d", list[k].ID, lags[list[k].ID], list[k].Lag)
+		}
+		if list[k].Self != (list[k].ID == "b") {
+			t.Fatalf("成员%s的self标记错误", list[k].ID)
+		}
+	}
+	if !list[1].Lagging {
+		t.Fatal("应保留成员上报的落后状态")
+	}
+}
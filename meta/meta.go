@@ -0,0 +1,172 @@
+package meta
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// 解析元信息，元信息必须是JSON对象，空字符串返回nil
+func Parse(value string) (map[string]interface{}, error) {
+	if value == "" {
+		return nil, nil
+	}
+	v, err := decode([]byte(value))
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, nil
+	}
+	object, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("元信息必须是JSON对象")
+	}
+	return object, nil
+}
+
+// 校验元信息并返回压缩后的JSON字符串，空值或null返回空字符串
+func Normalize(raw []byte) (string, error) {
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return "", nil
+	}
+	object, err := Parse(string(raw))
+	if err != nil {
+		return "", err
+	}
+	return encode(object)
+}
+
+// 按JSON Merge Patch(RFC 7386)更新元信息，值为null的键被删除，patch为null时清空元信息
+func Merge(value string, patch []byte) (string, error) {
+	p, err := decode(patch)
+	if err != nil {
+		return "", err
+	}
+	if p == nil {
+		return "", nil
+	}
+	changes, ok := p.(map[string]interface{})
+	if !ok {
+		return "", errors.New("元信息必须是JSON对象")
+	}
+	object, err := Parse(value)
+	if err != nil {
+		// 旧版本写入的元信息可能不是JSON对象，直接被替换
+		object = nil
+	}
+	return encode(mergeObject(object, changes))
+}
+
+func mergeObject(target, patch map[string]interface{}) map[string]interface{} {
+	if target == nil {
+		target = make(map[string]interface{}, len(patch))
+	}
+	for key, value := range patch {
+		if value == nil {
+			delete(target, key)
+			continue
+		}
+		if child, ok := value.(map[string]interface{}); ok {
+			current, _ := target[key].(map[string]interface{})
+			target[key] = mergeObject(current, child)
+			continue
+		}
+		target[key] = value
+	}
+	return target
+}
+
+// 解码JSON，数字保留原始文本
+func decode(raw []byte) (v interface{}, err error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err = decoder.Decode(&v); err != nil {
+		return nil, errors.New("元信息不是有效的JSON")
+	}
+	if decoder.More() {
+		return nil, errors.New("元信息不是有效的JSON")
+	}
+	return v, nil
+}
+
+// 编码成JSON字符串，空对象返回空字符串
+func encode(object map[string]interface{}) (string, error) {
+	if len(object) == 0 {
+		return "", nil
+	}
+	bs, err := json.Marshal(object)
+	if err != nil {
+		return "", err
+	}
+	return string(bs), nil
+}
+
+// 元信息的过滤条件，所有条件都满足时匹配
+type Filter []Condition
+
+// 单个过滤条件
+type Condition struct {
+	Key    string
+	Value  string // 要求相等的值，字符串比较原值，其它类型比较JSON文本
+	Exists bool   // 为true时只要求键存在
+}
+
+// 解析过滤条件，values为meta参数的值，key:value表示相等，key表示存在
+func ParseFilter(values []string) (Filter, error) {
+	var filter Filter
+	for _, value := range values {
+		if value == "" {
+			return nil, errors.New("meta参数不能为空")
+		}
+		pos := strings.Index(value, ":")
+		if pos == -1 {
+			filter = append(filter, Condition{Key: value, Exists: true})
+			continue
+		}
+		if pos == 0 {
+			return nil, errors.New("meta参数的键不能为空")
+		}
+		filter = append(filter, Condition{Key: value[:pos], Value: value[pos+1:]})
+	}
+	return filter, nil
+}
+
+// 是否需要过滤
+func (self Filter) Empty() bool {
+	return len(self) == 0
+}
+
+// 判断元信息是否满足过滤条件，不是JSON对象的元信息不满足任何条件
+func (self Filter) Match(value string) bool {
+	if self.Empty() {
+		return true
+	}
+	object, err := Parse(value)
+	if err != nil {
+		return false
+	}
+	for _, condition := range self {
+		v, exist := object[condition.Key]
+		if !exist {
+			return false
+		}
+		if !condition.Exists && text(v) != condition.Value {
+			return false
+		}
+	}
+	return true
+}
+
+// 值用于比较的文本
+func text(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	bs, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(bs)
+}
@@ -0,0 +1,242 @@
+package meta
+
+import (
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	value, err := Normalize([]byte(` {"b": 1, "a": "x"} `))
+	if err != nil || value != `{"a":"x","b":1}` {
+		t.Fatalf("应压缩元信息：%s %v", value, err)
+	}
+	for _, raw := range []string{"", "null", "{}"} {
+		if value, err = Normalize([]byte(raw)); err != nil || value != "" {
+			t.Fatalf("%q应返回空字符串", raw)
+		}
+	}
+	for _, raw := range []string{"[1]", `"x"`, "{", `{}{}`} {
+		if _, err = Normalize([]byte(raw)); err == nil {
+			t.Fatalf("%q不是JSON对象，应返回错误", raw)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	value, err := Merge(`{"env":"test","zone":{"a":1,"b":2},"old":true}`, []byte(`{"env":"prod","zone":{"b":null,"c":3},"old":null}`))
+	if err != nil || value != `{"env":"prod","zone":{"a":1,"c":3}}` {
+		t.Fatalf("应按键合并元信息：%s %v", value, err)
+	}
+	if value, err = Merge(`{"env":"test"}`, []byte("null")); err != nil || value != "" {
+		t.Fatal("patch为null时应清空元信息")
+	}
+	if value, err = Merge(`{"env":"test"}`, []byte(`{"env":null}`)); err != nil || value != "" {
+		t.Fatal("删除所有键后应返回空字符串")
+	}
+	if value, err = Merge(`[1]`, []byte(`{"env":"test"}`)); err != nil || value != `{"env":"test"}` {
+		t.Fatal("不是JSON对象的旧元信息应被替换")
+	}
+	if _, err = Merge("", []byte(`[1]`)); err == nil {
+		t.Fatal("patch不是JSON对象时应返回错误")
+	}
+	if value, err = Merge("", []byte(`{"big":12345678901234567890}`)); err != nil || value != `{"big":12345678901234567890}` {
+		t.Fatalf("应保留数字的原始文本：%s", value)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	filter, err := ParseFilter([]string{"env:prod", "canary", "port:8080", "ready:true"})
+	if err != nil || len(filter) != 4 || !filter[1].Exists {
+		t.Fatalf("解析过滤条件失败：%+v %v", filter, err)
+	}
+	if !filter.Match(`{"env":"prod","canary":null,"port":8080,"ready":true}`) {
+		t.Fatal("应匹配相等及存在的条件")
+	}
+	if filter.Match(`{"env":"prod","port":8080,"ready":true}`) {
+		t.Fatal("缺少键时不应匹配")
+	}
+	if filter.Match(`{"env":"test","canary":1,"port":8080,"ready":true}`) {
+		t.Fatal("值不相等时不应匹配")
+	}
+	if filter.Match(`[1]`) || filter.Match("") {
+		t.Fatal("不是JSON对象的元信息不应匹配")
+	}
+	if !(Filter{}).Match("") {
+		t.Fatal("空的过滤条件应匹配所有")
+	}
+	if _, err = ParseFilter([]string{":x"}); err == nil {
+		t.Fatal("键为空时应返回错误")
+	}
+}
+
+func TestSchema(t *testing.T) {
+	schema, err := CompileSchema([]byte(`{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"type": "object",
+		"required": ["env"],
+		"properties": {
+			"env": {"type": "string", "enum": ["test", "prod"]},
+			"port": {"type": "integer", "minimum": 1, "maximum": 65535},
+			"zone": {"type": "string", "pattern": "^[a-z]+-[0-9]$"},
+			"labels": {"type": "array", "items": {"type": "string"}, "maxItems": 2}
+		},
+		"additionalProperties": false
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = schema.Validate(`{"env":"prod","port":8080,"zone":"cn-1","labels":["a"]}`); err != nil {
+		t.Fatal(err)
+	}
+	invalid := map[string]string{
+		"":                                      "meta.env",
+		`{"env":"dev"}`:                         "meta.env",
+		`{"env":"prod","port":1.5}`:             "meta.port",
+		`{"env":"prod","port":70000}`:           "meta.port",
+		`{"env":"prod","zone":"CN"}`:            "meta.zone",
+		`{"env":"prod","labels":[1]}`:           "meta.labels[0]",
+		`{"env":"prod","labels":["a","b","c"]}`: "meta.labels",
+		`{"env":"prod","other":1}`:              "meta.other",
+	}
+	for value, path := range invalid {
+		err = schema.Validate(value)
+		if e, ok := err.(*ValidationError); !ok || e.Path != path {
+			t.Fatalf("%s应在%s校验失败，实际为%v", value, path, err)
+		}
+	}
+	for _, raw := range []string{`[]`, `{"type":"uuid"}`, `{"minLength":-1}`, `{"pattern":"("}`, `{"$ref":"#"}`} {
+		if _, err = CompileSchema([]byte(raw)); err == nil {
+			t.Fatalf("%s应编译失败", raw)
+		}
+	}
+}
+
+// 编译schema，失败时终止测试
+func mustCompile(t *testing.T, raw string) *Schema {
+	schema, err := CompileSchema([]byte(raw))
+	if err != nil {
+		t.Fatalf("%s应能编译：%v", raw, err)
+	}
+	return schema
+}
+
+// 校验valid中的值都通过，invalid中的值都在指定位置校验失败
+func checkSchema(t *testing.T, schema *Schema, valid []string, invalid map[string]string) {
+	for _, value := range valid {
+		if err := schema.Validate(value); err != nil {
+			t.Fatalf("%s应校验通过：%v", value, err)
+		}
+	}
+	for value, path := range invalid {
+		err := schema.Validate(value)
+		if e, ok := err.(*ValidationError); !ok || e.Path != path {
+			t.Fatalf("%s应在%s校验失败，实际为%v", value, path, err)
+		}
+	}
+}
+
+func TestSchemaExclusive(t *testing.T) {
+	schema := mustCompile(t, `{"properties": {"ratio": {"exclusiveMinimum": 0, "exclusiveMaximum": 1}}}`)
+	checkSchema(t, schema,
+		[]string{`{"ratio":0.5}`, `{"ratio":0.0000000000000000000001}`, `{"ratio":0.9999999999999999999999}`, `{}`},
+		map[string]string{
+			`{"ratio":0}`:   "meta.ratio",
+			`{"ratio":1}`:   "meta.ratio",
+			`{"ratio":1.0}`: "meta.ratio",
+			`{"ratio":-1}`:  "meta.ratio",
+		})
+	// 与minimum/maximum不同，边界值本身不满足约束
+	schema = mustCompile(t, `{"properties": {"ratio": {"minimum": 0, "maximum": 1}}}`)
+	checkSchema(t, schema,
+		[]string{`{"ratio":0}`, `{"ratio":1}`},
+		map[string]string{`{"ratio":1.0000000000000000000001}`: "meta.ratio"})
+	if _, err := CompileSchema([]byte(`{"exclusiveMaximum": true}`)); err == nil {
+		t.Fatal("draft-04的布尔值exclusiveMaximum应编译失败")
+	}
+}
+
+func TestSchemaInteger(t *testing.T) {
+	schema := mustCompile(t, `{"properties": {"id": {"type": "integer"}}}`)
+	checkSchema(t, schema,
+		[]string{
+			`{"id":1}`,
+			`{"id":1.0}`,
+			`{"id":-9007199254740993}`,
+			`{"id":123456789012345678901234567890}`,
+			`{"id":1e30}`,
+			`{"id":1.5e1}`,
+		},
+		map[string]string{
+			`{"id":1.5}`:                      "meta.id",
+			`{"id":1.0000000000000000000001}`: "meta.id",
+			`{"id":12345678901234567890.5}`:   "meta.id",
+			`{"id":1e-30}`:                    "meta.id",
+			`{"id":"1"}`:                      "meta.id",
+		})
+	// 超过float64精度的数字按数值比较
+	schema = mustCompile(t, `{"properties": {"id": {"enum": [9007199254740993]}, "max": {"maximum": 9007199254740992}}}`)
+	checkSchema(t, schema,
+		[]string{`{"id":9007199254740993}`, `{"id":9007199254740993.0}`, `{"max":9007199254740992}`},
+		map[string]string{
+			`{"id":9007199254740992}`:  "meta.id",
+			`{"max":9007199254740993}`: "meta.max",
+		})
+}
+
+func TestSchemaAdditionalProperties(t *testing.T) {
+	schema := mustCompile(t, `{
+		"properties": {"env": {"type": "string"}},
+		"additionalProperties": {"type": "integer", "minimum": 0}
+	}`)
+	checkSchema(t, schema,
+		[]string{`{"env":"prod"}`, `{"env":"prod","cpu":2,"memory":4096}`},
+		map[string]string{
+			`{"cpu":"2"}`:           "meta.cpu",
+			`{"cpu":-1}`:            "meta.cpu",
+			`{"env":1}`:             "meta.env",
+			`{"env":"a","gpu":1.5}`: "meta.gpu",
+		})
+	// additionalProperties为true时不限制
+	checkSchema(t, mustCompile(t, `{"properties": {"env": {}}, "additionalProperties": true}`),
+		[]string{`{"other":[1,2]}`}, nil)
+	if _, err := CompileSchema([]byte(`{"additionalProperties": "no"}`)); err == nil {
+		t.Fatal("additionalProperties只能是布尔值或schema")
+	}
+}
+
+func TestSchemaNestedRequired(t *testing.T) {
+	schema := mustCompile(t, `{
+		"required": ["deploy"],
+		"properties": {
+			"deploy": {
+				"type": "object",
+				"required": ["region", "zones"],
+				"properties": {
+					"region": {"type": "string", "minLength": 2, "maxLength": 8},
+					"zones": {"type": "array", "minItems": 1, "items": {"type": "object", "required": ["name"]}}
+				}
+			}
+		}
+	}`)
+	checkSchema(t, schema,
+		[]string{`{"deploy":{"region":"cn","zones":[{"name":"a"}]}}`},
+		map[string]string{
+			`{}`:                         "meta.deploy",
+			`{"deploy":{}}`:              "meta.deploy.region",
+			`{"deploy":{"region":"cn"}}`: "meta.deploy.zones",
+			`{"deploy":{"region":"c","zones":[{"name":1}]}}`: "meta.deploy.region",
+			`{"deploy":{"region":"cn","zones":[]}}`:          "meta.deploy.zones",
+			`{"deploy":{"region":"cn","zones":[{}]}}`:        "meta.deploy.zones[0].name",
+			`{"deploy":"cn"}`: "meta.deploy",
+		})
+}
+
+func TestSchemaObjectSize(t *testing.T) {
+	schema := mustCompile(t, `{"minProperties": 1, "maxProperties": 2, "properties": {"a": {"const": "x"}}}`)
+	checkSchema(t, schema,
+		[]string{`{"a":"x"}`, `{"a":"x","b":1}`},
+		map[string]string{
+			`{}`:                    "meta",
+			`{"a":"x","b":1,"c":2}`: "meta",
+			`{"a":"y"}`:             "meta.a",
+		})
+}
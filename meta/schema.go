@@ -0,0 +1,375 @@
+package meta
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// 元信息的JSON Schema，只支持以下关键字：
+// type, enum, const, properties, required, additionalProperties, minProperties, maxProperties,
+// items, minItems, maxItems, minLength, maxLength, pattern, minimum, maximum, exclusiveMinimum, exclusiveMaximum
+// 以及不参与校验的$schema, $id, title, description, default, examples
+type Schema struct {
+	types         []string
+	enum          []interface{}
+	constant      interface{}
+	hasConst      bool
+	properties    map[string]*Schema
+	required      []string
+	additional    *Schema // additionalProperties为schema时的约束
+	noAdditional  bool    // additionalProperties为false
+	minProperties *int
+	maxProperties *int
+	items         *Schema
+	minItems      *int
+	maxItems      *int
+	minLength     *int
+	maxLength     *int
+	pattern       *regexp.Regexp
+	minimum       *big.Float
+	maximum       *big.Float
+	exclusiveMin  *big.Float
+	exclusiveMax  *big.Float
+}
+
+// 校验失败的错误
+type ValidationError struct {
+	Path    string // 不满足约束的位置，如meta.port
+	Message string
+}
+
+func (self *ValidationError) Error() string {
+	return self.Path + self.Message
+}
+
+// 支持的类型
+var schemaTypes = map[string]struct{}{
+	"object": {}, "array": {}, "string": {}, "number": {}, "integer": {}, "boolean": {}, "null": {},
+}
+
+// 不参与校验的关键字
+var annotations = map[string]struct{}{
+	"$schema": {}, "$id": {}, "title": {}, "description": {}, "default": {}, "examples": {},
+}
+
+// 编译JSON Schema，包含不支持的关键字时返回错误
+func CompileSchema(raw []byte) (*Schema, error) {
+	v, err := decode(raw)
+	if err != nil {
+		return nil, errors.New("meta_schema不是有效的JSON")
+	}
+	return compile(v, "meta_schema")
+}
+
+func compile(v interface{}, path string) (*Schema, error) {
+	object, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New(path + "必须是JSON对象")
+	}
+	schema := &Schema{}
+	for key, value := range object {
+		var err error
+		switch key {
+		case "type":
+			err = schema.compileType(value, path)
+		case "enum":
+			list, ok := value.([]interface{})
+			if !ok || len(list) == 0 {
+				err = errors.New(path + ".enum必须是非空数组")
+			}
+			schema.enum = list
+		case "const":
+			schema.constant = value
+			schema.hasConst = true
+		case "properties":
+			properties, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, errors.New(path + ".properties必须是JSON对象")
+			}
+			schema.properties = make(map[string]*Schema, len(properties))
+			for name, property := range properties {
+				if schema.properties[name], err = compile(property, path+".properties."+name); err != nil {
+					return nil, err
+				}
+			}
+		case "required":
+			list, ok := value.([]interface{})
+			if !ok {
+				return nil, errors.New(path + ".required必须是字符串数组")
+			}
+			for k := range list {
+				name, ok := list[k].(string)
+				if !ok {
+					return nil, errors.New(path + ".required必须是字符串数组")
+				}
+				schema.required = append(schema.required, name)
+			}
+		case "additionalProperties":
+			if allow, ok := value.(bool); ok {
+				schema.noAdditional = !allow
+				break
+			}
+			schema.additional, err = compile(value, path+".additionalProperties")
+		case "items":
+			schema.items, err = compile(value, path+".items")
+		case "minProperties":
+			schema.minProperties, err = compileCount(value, path+"."+key)
+		case "maxProperties":
+			schema.maxProperties, err = compileCount(value, path+"."+key)
+		case "minItems":
+			schema.minItems, err = compileCount(value, path+"."+key)
+		case "maxItems":
+			schema.maxItems, err = compileCount(value, path+"."+key)
+		case "minLength":
+			schema.minLength, err = compileCount(value, path+"."+key)
+		case "maxLength":
+			schema.maxLength, err = compileCount(value, path+"."+key)
+		case "pattern":
+			expr, ok := value.(string)
+			if !ok {
+				return nil, errors.New(path + ".pattern必须是字符串")
+			}
+			if schema.pattern, err = regexp.Compile(expr); err != nil {
+				err = errors.New(path + ".pattern不是有效的正则表达式")
+			}
+		case "minimum":
+			schema.minimum, err = compileNumber(value, path+"."+key)
+		case "maximum":
+			schema.maximum, err = compileNumber(value, path+"."+key)
+		case "exclusiveMinimum":
+			schema.exclusiveMin, err = compileNumber(value, path+"."+key)
+		case "exclusiveMaximum":
+			schema.exclusiveMax, err = compileNumber(value, path+"."+key)
+		default:
+			if _, exist := annotations[key]; !exist {
+				err = errors.New(path + "不支持" + key + "关键字")
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return schema, nil
+}
+
+func (self *Schema) compileType(value interface{}, path string) error {
+	switch t := value.(type) {
+	case string:
+		self.types = []string{t}
+	case []interface{}:
+		for k := range t {
+			name, ok := t[k].(string)
+			if !ok {
+				return errors.New(path + ".type必须是字符串或字符串数组")
+			}
+			self.types = append(self.types, name)
+		}
+	default:
+		return errors.New(path + ".type必须是字符串或字符串数组")
+	}
+	for _, name := range self.types {
+		if _, exist := schemaTypes[name]; !exist {
+			return errors.New(path + ".type不支持" + name)
+		}
+	}
+	return nil
+}
+
+func compileCount(value interface{}, path string) (*int, error) {
+	n, ok := value.(json.Number)
+	if ok {
+		if i, err := n.Int64(); err == nil && i >= 0 {
+			count := int(i)
+			return &count, nil
+		}
+	}
+	return nil, errors.New(path + "必须是非负整数")
+}
+
+func compileNumber(value interface{}, path string) (*big.Float, error) {
+	if n, ok := value.(json.Number); ok {
+		if f, ok := parseNumber(n); ok {
+			return f, nil
+		}
+	}
+	return nil, errors.New(path + "必须是数字")
+}
+
+// 解析JSON数字，精度随数字的位数增加，避免超过float64精度的数字被舍入
+// 例如1.0000000000000000000001不能被视为整数，10.00000000000000000001不能满足maximum=10
+func parseNumber(n json.Number) (*big.Float, bool) {
+	return new(big.Float).SetPrec(uint(len(n))*4 + 64).SetString(n.String())
+}
+
+// 校验元信息，未设置元信息时按空对象校验
+func (self *Schema) Validate(value string) error {
+	var v interface{} = map[string]interface{}{}
+	if value != "" {
+		var err error
+		if v, err = decode([]byte(value)); err != nil {
+			return err
+		}
+	}
+	return self.validate(v, "meta")
+}
+
+func (self *Schema) validate(v interface{}, path string) error {
+	if len(self.types) > 0 && !self.matchType(v) {
+		return &ValidationError{Path: path, Message: "的类型必须是" + strings.Join(self.types, "|")}
+	}
+	if self.hasConst && !equal(v, self.constant) {
+		return &ValidationError{Path: path, Message: "的值不符合const约束"}
+	}
+	if self.enum != nil {
+		found := false
+		for k := range self.enum {
+			if equal(v, self.enum[k]) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return &ValidationError{Path: path, Message: "的值不在enum中"}
+		}
+	}
+	switch value := v.(type) {
+	case map[string]interface{}:
+		return self.validateObject(value, path)
+	case []interface{}:
+		if self.minItems != nil && len(value) < *self.minItems {
+			return &ValidationError{Path: path, Message: "的元素数量不符合minItems约束"}
+		}
+		if self.maxItems != nil && len(value) > *self.maxItems {
+			return &ValidationError{Path: path, Message: "的元素数量不符合maxItems约束"}
+		}
+		if self.items != nil {
+			for k := range value {
+				if err := self.items.validate(value[k], path+"["+strconv.Itoa(k)+"]"); err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		length := utf8.RuneCountInString(value)
+		if self.minLength != nil && length < *self.minLength {
+			return &ValidationError{Path: path, Message: "的长度不符合minLength约束"}
+		}
+		if self.maxLength != nil && length > *self.maxLength {
+			return &ValidationError{Path: path, Message: "的长度不符合maxLength约束"}
+		}
+		if self.pattern != nil && !self.pattern.MatchString(value) {
+			return &ValidationError{Path: path, Message: "的值不符合pattern约束"}
+		}
+	case json.Number:
+		return self.validateNumber(value, path)
+	}
+	return nil
+}
+
+func (self *Schema) validateObject(object map[string]interface{}, path string) error {
+	if self.minProperties != nil && len(object) < *self.minProperties {
+		return &ValidationError{Path: path, Message: "的属性数量不符合minProperties约束"}
+	}
+	if self.maxProperties != nil && len(object) > *self.maxProperties {
+		return &ValidationError{Path: path, Message: "的属性数量不符合maxProperties约束"}
+	}
+	for _, name := range self.required {
+		if _, exist := object[name]; !exist {
+			return &ValidationError{Path: path + "." + name, Message: "是必需的"}
+		}
+	}
+	for name, value := range object {
+		if property, exist := self.properties[name]; exist {
+			if err := property.validate(value, path+"."+name); err != nil {
+				return err
+			}
+			continue
+		}
+		if self.noAdditional {
+			return &ValidationError{Path: path + "." + name, Message: "不是允许的属性"}
+		}
+		if self.additional != nil {
+			if err := self.additional.validate(value, path+"."+name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (self *Schema) validateNumber(n json.Number, path string) error {
+	f, ok := parseNumber(n)
+	if !ok {
+		return &ValidationError{Path: path, Message: "不是有效的数字"}
+	}
+	if self.minimum != nil && f.Cmp(self.minimum) < 0 {
+		return &ValidationError{Path: path, Message: "的值不符合minimum约束"}
+	}
+	if self.maximum != nil && f.Cmp(self.maximum) > 0 {
+		return &ValidationError{Path: path, Message: "的值不符合maximum约束"}
+	}
+	if self.exclusiveMin != nil && f.Cmp(self.exclusiveMin) <= 0 {
+		return &ValidationError{Path: path, Message: "的值不符合exclusiveMinimum约束"}
+	}
+	if self.exclusiveMax != nil && f.Cmp(self.exclusiveMax) >= 0 {
+		return &ValidationError{Path: path, Message: "的值不符合exclusiveMaximum约束"}
+	}
+	return nil
+}
+
+// 判断值是否为允许的类型之一
+func (self *Schema) matchType(v interface{}) bool {
+	for _, name := range self.types {
+		switch name {
+		case "object":
+			if _, ok := v.(map[string]interface{}); ok {
+				return true
+			}
+		case "array":
+			if _, ok := v.([]interface{}); ok {
+				return true
+			}
+		case "string":
+			if _, ok := v.(string); ok {
+				return true
+			}
+		case "number":
+			if _, ok := v.(json.Number); ok {
+				return true
+			}
+		case "integer":
+			if n, ok := v.(json.Number); ok {
+				if f, ok := parseNumber(n); ok && f.IsInt() {
+					return true
+				}
+			}
+		case "boolean":
+			if _, ok := v.(bool); ok {
+				return true
+			}
+		case "null":
+			if v == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 比较两个JSON值是否相等，数字按数值比较
+func equal(a, b interface{}) bool {
+	na, okA := a.(json.Number)
+	nb, okB := b.(json.Number)
+	if okA && okB {
+		fa, okA := parseNumber(na)
+		fb, okB := parseNumber(nb)
+		return okA && okB && fa.Cmp(fb) == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,198 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"local/global"
+)
+
+// 指标名称的前缀
+const namespace = "tsing_center"
+
+var (
+	registry = prometheus.NewRegistry()
+
+	apiRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_requests_total",
+		Help:      "API请求数",
+	}, []string{"route", "method", "status"})
+	apiDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "API请求的处理时间",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	selects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "select_total",
+		Help:      "选取节点的请求数",
+	}, []string{"namespace", "service"})
+	selectedNodes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "selected_nodes_total",
+		Help:      "节点被选中的次数，只统计本地数据中心的节点",
+	}, []string{"namespace", "service", "node"})
+
+	storageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_operation_duration_seconds",
+		Help:      "存储器操作的耗时",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+	storageErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "storage_operation_errors_total",
+		Help:      "存储器操作的失败次数",
+	}, []string{"operation"})
+
+	watchEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "watch_events_total",
+		Help:      "从存储器监听到并应用到本地数据的变更事件数",
+	}, []string{"type", "result"})
+)
+
+func init() {
+	registry.MustRegister(
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		apiRequests,
+		apiDuration,
+		selects,
+		selectedNodes,
+		storageDuration,
+		storageErrors,
+		watchEvents,
+		&clusterCollector{},
+	)
+}
+
+// 输出指标的HTTP处理器
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// 记录API请求
+func ObserveRequest(route, method string, status int, duration time.Duration) {
+	apiRequests.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+	apiDuration.WithLabelValues(route, method).Observe(duration.Seconds())
+}
+
+// 已产生selected_nodes_total的节点，删除节点或服务时删除对应的指标
+var selectedSeries = struct {
+	sync.Mutex
+	nodes map[string]map[string]struct{} // 服务键名 -> 节点地址
+}{nodes: make(map[string]map[string]struct{})}
+
+// 记录选取节点的请求及选中的节点
+// 从远端数据中心选取的节点(datacenter不为空)只计入请求数，其节点不随本地的删除操作清理
+func ObserveSelect(ns, serviceID, datacenter string, nodes []global.Node) {
+	selects.WithLabelValues(ns, serviceID).Inc()
+	if datacenter != "" || len(nodes) == 0 {
+		return
+	}
+	key := global.ServiceKey(ns, serviceID)
+	selectedSeries.Lock()
+	defer selectedSeries.Unlock()
+	addresses, exist := selectedSeries.nodes[key]
+	if !exist {
+		addresses = make(map[string]struct{}, len(nodes))
+		selectedSeries.nodes[key] = addresses
+	}
+	for k := range nodes {
+		address := global.NodeAddress(nodes[k].IP, nodes[k].Port)
+		addresses[address] = struct{}{}
+		selectedNodes.WithLabelValues(ns, serviceID, address).Inc()
+	}
+}
+
+// 删除节点的指标
+func DeleteNode(ns, serviceID, ip string, port uint16) {
+	key := global.ServiceKey(ns, serviceID)
+	address := global.NodeAddress(ip, port)
+	selectedSeries.Lock()
+	defer selectedSeries.Unlock()
+	if _, exist := selectedSeries.nodes[key][address]; !exist {
+		return
+	}
+	selectedNodes.DeleteLabelValues(ns, serviceID, address)
+	delete(selectedSeries.nodes[key], address)
+	if len(selectedSeries.nodes[key]) == 0 {
+		delete(selectedSeries.nodes, key)
+	}
+}
+
+// 删除服务及其所有节点的指标
+func DeleteService(ns, serviceID string) {
+	selects.DeleteLabelValues(ns, serviceID)
+	key := global.ServiceKey(ns, serviceID)
+	selectedSeries.Lock()
+	defer selectedSeries.Unlock()
+	for address := range selectedSeries.nodes[key] {
+		selectedNodes.DeleteLabelValues(ns, serviceID, address)
+	}
+	delete(selectedSeries.nodes, key)
+}
+
+// 记录存储器操作的耗时及错误
+func ObserveStorage(operation string, start time.Time, err error) {
+	storageDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		storageErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+// 记录应用到本地数据的变更事件，eventType为put或delete
+func ObserveWatchEvent(eventType string, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	watchEvents.WithLabelValues(eventType, result).Inc()
+}
+
+// 在采集时统计服务及节点数量
+type clusterCollector struct{}
+
+var (
+	totalServicesDesc = prometheus.NewDesc(namespace+"_services", "服务总数", nil, nil)
+	nodesDesc         = prometheus.NewDesc(namespace+"_nodes", "服务中的节点数，state为healthy(未过期)或expired(已过期但尚未清理)", []string{"namespace", "service", "state"}, nil)
+)
+
+func (self *clusterCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- totalServicesDesc
+	ch <- nodesDesc
+}
+
+func (self *clusterCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(totalServicesDesc, prometheus.GaugeValue, float64(atomic.LoadUint32(&global.TotalServices)))
+	now := time.Now().Unix()
+	global.Services.Range(func(_, value interface{}) bool {
+		ci, ok := value.(global.Cluster)
+		if !ok {
+			return true
+		}
+		var healthy, expired int
+		nodes := ci.Nodes()
+		for k := range nodes {
+			if nodes[k].Expires == 0 || nodes[k].Expires > now {
+				healthy++
+			} else {
+				expired++
+			}
+		}
+		config := ci.Config()
+		ch <- prometheus.MustNewConstMetric(nodesDesc, prometheus.GaugeValue, float64(healthy), config.Namespace, config.ServiceID, "healthy")
+		ch <- prometheus.MustNewConstMetric(nodesDesc, prometheus.GaugeValue, float64(expired), config.Namespace, config.ServiceID, "expired")
+		return true
+	})
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"local/global"
+)
+
+func TestDeleteNode(t *testing.T) {
+	defer DeleteService("prod", "orders")
+	nodes := []global.Node{{IP: "10.0.0.1", Port: 80}, {IP: "10.0.0.2", Port: 80}}
+	ObserveSelect("prod", "orders", "", nodes)
+	ObserveSelect("prod", "orders", "", nodes[:1])
+	if count := testutil.CollectAndCount(selectedNodes); count != 2 {
+		t.Fatalf("应为每个选中的节点记录指标：%d", count)
+	}
+	if value := testutil.ToFloat64(selectedNodes.WithLabelValues("prod", "orders", "10.0.0.1:80")); value != 2 {
+		t.Fatalf("节点被选中的次数不正确：%v", value)
+	}
+
+	DeleteNode("prod", "orders", "10.0.0.1", 80)
+	if count := testutil.CollectAndCount(selectedNodes); count != 1 {
+		t.Fatalf("删除节点后应删除其指标：%d", count)
+	}
+	// 删除不存在的节点不影响其它指标
+	DeleteNode("prod", "orders", "10.0.0.3", 80)
+	DeleteNode("test", "orders", "10.0.0.2", 80)
+	if count := testutil.CollectAndCount(selectedNodes); count != 1 {
+		t.Fatalf("不应删除其它节点的指标：%d", count)
+	}
+}
+
+func TestDeleteService(t *testing.T) {
+	nodes := []global.Node{{IP: "10.0.0.1", Port: 80}, {IP: "10.0.0.2", Port: 80}}
+	ObserveSelect("prod", "orders", "", nodes)
+	ObserveSelect("test", "orders", "", nodes[:1])
+	defer DeleteService("test", "orders")
+
+	DeleteService("prod", "orders")
+	if count := testutil.CollectAndCount(selectedNodes); count != 1 {
+		t.Fatalf("删除服务后应删除其所有节点的指标：%d", count)
+	}
+	if count := testutil.CollectAndCount(selects); count != 1 {
+		t.Fatalf("删除服务后应删除其请求数的指标：%d", count)
+	}
+	if _, exist := selectedSeries.nodes[global.ServiceKey("prod", "orders")]; exist {
+		t.Fatal("删除服务后不应保留其节点记录")
+	}
+}
+
+func TestObserveSelectRemote(t *testing.T) {
+	defer DeleteService("prod", "orders")
+	ObserveSelect("prod", "orders", "dc2", []global.Node{{IP: "10.0.2.1", Port: 80}})
+	if count := testutil.CollectAndCount(selectedNodes); count != 0 {
+		t.Fatalf("不应记录远端数据中心的节点：%d", count)
+	}
+	if value := testutil.ToFloat64(selects.WithLabelValues("prod", "orders")); value != 1 {
+		t.Fatalf("远端数据中心的选取仍应计入请求数：%v", value)
+	}
+}
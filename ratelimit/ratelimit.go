@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// 清理空闲令牌桶的间隔
+const cleanInterval = time.Minute
+
+// 令牌桶
+type bucket struct {
+	tokens float64   // 剩余的令牌数
+	last   time.Time // 最后一次补充令牌的时间
+}
+
+// 按key(如客户端IP或ACL令牌)分别计数的令牌桶限流器
+type Limiter struct {
+	mutex     sync.Mutex
+	rate      float64 // 每秒补充的令牌数，小于等于0表示不限流
+	burst     int     // 允许的突发请求数
+	capacity  float64 // 令牌桶的容量，burst小于1时使用rate向上取整的值
+	buckets   map[string]*bucket
+	lastClean time.Time
+	now       func() time.Time
+}
+
+// 新建限流器，burst小于1时使用rate向上取整的值
+func New(rate float64, burst int) *Limiter {
+	limiter := &Limiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+	limiter.SetLimit(rate, burst)
+	return limiter
+}
+
+// 修改限流参数，已有令牌桶中超出新容量的令牌被丢弃
+func (self *Limiter) SetLimit(rate float64, burst int) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	self.rate = rate
+	self.burst = burst
+	self.capacity = float64(burst)
+	if self.capacity < 1 {
+		self.capacity = math.Max(1, math.Ceil(rate))
+	}
+	for _, b := range self.buckets {
+		if b.tokens > self.capacity {
+			b.tokens = self.capacity
+		}
+	}
+}
+
+// 获取限流参数
+func (self *Limiter) Limit() (rate float64, burst int) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	return self.rate, self.burst
+}
+
+// 所有key的令牌桶都有令牌时，从每个令牌桶中取出一个令牌
+// 任意一个令牌桶的令牌不足时不取出任何令牌，返回false及需要等待的最长时间
+func (self *Limiter) Allow(keys ...string) (bool, time.Duration) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	if self.rate <= 0 {
+		return true, 0
+	}
+	now := self.now()
+	self.clean(now)
+	var (
+		buckets = make([]*bucket, len(keys))
+		denied  bool
+		wait    time.Duration
+	)
+	for k := range keys {
+		b, exist := self.buckets[keys[k]]
+		if !exist {
+			b = &bucket{tokens: self.capacity, last: now}
+			self.buckets[keys[k]] = b
+		} else {
+			b.tokens = math.Min(self.capacity, b.tokens+now.Sub(b.last).Seconds()*self.rate)
+			b.last = now
+		}
+		if b.tokens < 1 {
+			denied = true
+			if w := time.Duration((1 - b.tokens) / self.rate * float64(time.Second)); w > wait {
+				wait = w
+			}
+		}
+		buckets[k] = b
+	}
+	if denied {
+		return false, wait
+	}
+	for k := range buckets {
+		buckets[k].tokens--
+	}
+	return true, 0
+}
+
+// 删除已补满的令牌桶，补满的令牌桶与新建的没有区别，避免key过多时占用内存
+func (self *Limiter) clean(now time.Time) {
+	if now.Sub(self.lastClean) < cleanInterval {
+		return
+	}
+	self.lastClean = now
+	for key, b := range self.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*self.rate >= self.capacity {
+			delete(self.buckets, key)
+		}
+	}
+}
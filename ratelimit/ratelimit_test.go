@@ -0,0 +1,99 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllow(t *testing.T) {
+	now := time.Now()
+	limiter := New(2, 3)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := limiter.Allow("a"); !ok {
+			t.Fatalf("第%d次请求应在突发容量内", i+1)
+		}
+	}
+	ok, wait := limiter.Allow("a")
+	if ok {
+		t.Fatal("令牌耗尽后应被限流")
+	}
+	if wait != 500*time.Millisecond {
+		t.Fatalf("等待时间应为500ms，实际为%s", wait)
+	}
+	// 不同的key使用各自的令牌桶
+	if ok, _ = limiter.Allow("b"); !ok {
+		t.Fatal("其它key不应被限流")
+	}
+
+	now = now.Add(wait)
+	if ok, _ = limiter.Allow("a"); !ok {
+		t.Fatal("等待后应补充令牌")
+	}
+	if ok, _ = limiter.Allow("a"); ok {
+		t.Fatal("补充的令牌只够一次请求")
+	}
+}
+
+func TestSetLimit(t *testing.T) {
+	now := time.Now()
+	limiter := New(1, 5)
+	limiter.now = func() time.Time { return now }
+	limiter.Allow("a")
+
+	// 缩小容量后，已有令牌桶中超出的令牌被丢弃
+	limiter.SetLimit(1, 1)
+	if ok, _ := limiter.Allow("a"); !ok {
+		t.Fatal("应保留新容量内的令牌")
+	}
+	if ok, _ := limiter.Allow("a"); ok {
+		t.Fatal("超出新容量的令牌应被丢弃")
+	}
+
+	// rate为0表示不限流
+	limiter.SetLimit(0, 0)
+	for i := 0; i < 100; i++ {
+		if ok, _ := limiter.Allow("a"); !ok {
+			t.Fatal("不限流时不应拒绝请求")
+		}
+	}
+}
+
+func TestClean(t *testing.T) {
+	now := time.Now()
+	limiter := New(10, 10)
+	limiter.now = func() time.Time { return now }
+	limiter.Allow("a")
+	now = now.Add(cleanInterval)
+	limiter.Allow("b")
+	if _, exist := limiter.buckets["a"]; exist {
+		t.Fatal("已补满的令牌桶应被清理")
+	}
+	if _, exist := limiter.buckets["b"]; !exist {
+		t.Fatal("未补满的令牌桶不应被清理")
+	}
+}
+
+func TestAllowKeys(t *testing.T) {
+	now := time.Now()
+	limiter := New(1, 2)
+	limiter.now = func() time.Time { return now }
+
+	// 耗尽身份的令牌桶
+	limiter.Allow("id:a")
+	limiter.Allow("id:a")
+	ok, wait := limiter.Allow("ip:1", "id:a")
+	if ok || wait != time.Second {
+		t.Fatalf("任意一个令牌桶的令牌不足时应被限流：%v %s", ok, wait)
+	}
+	// 被限流的请求不应取走其它令牌桶中的令牌
+	for i := 0; i < 2; i++ {
+		if ok, _ = limiter.Allow("ip:1", "id:b"); !ok {
+			t.Fatalf("同一IP的其它身份的第%d次请求不应被限流", i+1)
+		}
+	}
+	if ok, _ = limiter.Allow("ip:1", "id:c"); ok {
+		t.Fatal("IP的令牌桶耗尽后应被限流")
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/pelletier/go-toml"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+
+	"local/accesslog"
+	"local/api"
+	"local/global"
+)
+
+// 配置文件的路径
+var configFile string
+
+// 避免同时重新加载配置
+var reloadMutex sync.Mutex
+
+// API HTTPS服务当前使用的证书，重新加载配置时更新
+var apiCertificate atomic.Value
+
+// 读取API HTTPS服务的证书
+func loadCertificate(config *global.ConfigType) (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(config.API.HTTPS.Cert, config.API.HTTPS.Key)
+	if err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// 在TLS握手时获取当前的证书
+func getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return apiCertificate.Load().(*tls.Certificate), nil
+}
+
+// 收到SIGHUP信号时重新加载配置文件
+func watchReloadSignal() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	go func() {
+		for range ch {
+			log.Info().Msg("收到SIGHUP信号，重新加载配置文件")
+			_, _, _ = reloadConfig()
+		}
+	}()
+}
+
+// 重新加载配置文件，应用可在运行时生效的配置项，返回已生效及需要重启进程才能生效的配置项
+// 配置文件无效或无法应用时返回错误，并保留原配置
+// 证书文件总是重新读取，以便在不修改路径的情况下更新证书
+func reloadConfig() (applied, restartRequired []string, err error) {
+	reloadMutex.Lock()
+	defer reloadMutex.Unlock()
+
+	config, err := global.ParseConfigFile(configFile)
+	if err != nil {
+		log.Err(err).Msg("配置文件无效，已保留原配置")
+		return nil, nil, err
+	}
+	applied = global.LiveChanges(global.Config(), config)
+	restartRequired = global.RestartChanges(global.Config(), config)
+
+	// 先准备所有可能失败的配置项，任意一项失败时不应用任何变更
+	var cert *tls.Certificate
+	if global.Config().API.HTTPS.Port > 0 {
+		if cert, err = loadCertificate(config); err != nil {
+			log.Err(err).Msg("读取证书失败，已保留原配置")
+			return nil, nil, err
+		}
+	}
+	var output *logOutput
+	if containsKey(applied, "logger") {
+		if output, err = newLogOutput(config); err != nil {
+			log.Err(err).Msg("配置日志记录器失败，已保留原配置")
+			return nil, nil, err
+		}
+	}
+	if containsKey(applied, "accessLog") {
+		if err = accesslog.Apply(config); err != nil {
+			if output != nil {
+				_ = output.close()
+			}
+			log.Err(err).Msg("配置访问日志失败，已保留原配置")
+			return nil, nil, err
+		}
+	}
+
+	if output != nil {
+		if err = reloadLogger(output, config); err != nil {
+			log.Err(err).Caller().Msg("关闭原日志文件失败")
+		}
+	}
+	api.ApplyConfig(config)
+	if cert != nil {
+		apiCertificate.Store(cert)
+	}
+	global.ApplyLive(config)
+
+	log.Info().Strs("applied", applied).Msg("已重新加载配置文件")
+	if len(restartRequired) > 0 {
+		log.Warn().Strs("restart_required", restartRequired).Msg("以下配置项需要重启进程才能生效")
+	}
+	return applied, restartRequired, nil
+}
+
+// 判断配置项列表中是否包含指定的配置项
+func containsKey(keys []string, key string) bool {
+	for k := range keys {
+		if keys[k] == key {
+			return true
+		}
+	}
+	return false
+}
+
+// 校验配置文件并输出生效的配置，返回进程的退出码
+func checkConfigFile() int {
+	// 错误直接输出到标准错误，不重复记录日志
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	config, err := global.ParseConfigFile(configFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "配置无效：")
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	data, err := toml.Marshal(config.Redacted())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		return 1
+	}
+	fmt.Println("# 生效的配置，已合并默认值、配置文件、环境变量及命令行参数")
+	fmt.Print(string(data))
+	return 0
+}
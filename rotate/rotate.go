@@ -0,0 +1,222 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// 压缩的历史文件的扩展名
+const compressSuffix = ".gz"
+
+// 滚动文件的选项
+type Options struct {
+	Mode       os.FileMode   // 文件权限，为0表示0600
+	MaxSize    int64         // 单个文件的最大字节数，为0表示不按大小滚动
+	MaxAge     time.Duration // 当前文件的最长写入时长，超过后滚动，为0表示不按时间滚动
+	MaxBackups int           // 保留的历史文件数量
+	Retention  time.Duration // 历史文件的保留时间，超过后在滚动时删除，为0表示不按时间删除
+	Compress   bool          // 使用gzip压缩历史文件，压缩后的文件名为path.N.gz
+}
+
+// 按大小及时间滚动的文件
+// 文件超过maxSize或写入时长超过maxAge时重命名为path.1，原有的path.N依次重命名为path.N+1，超过maxBackups的文件被删除
+type File struct {
+	path     string
+	options  Options
+	mutex    sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	now      func() time.Time // 可替换的当前时间，便于测试
+}
+
+// 打开按大小滚动的文件，不存在则创建
+func Open(path string, mode os.FileMode, maxSize int64, maxBackups int) (*File, error) {
+	return OpenOptions(path, Options{Mode: mode, MaxSize: maxSize, MaxBackups: maxBackups})
+}
+
+// 按选项打开滚动文件，不存在则创建
+func OpenOptions(path string, options Options) (*File, error) {
+	if options.Mode == 0 {
+		options.Mode = os.FileMode(0600)
+	}
+	f := &File{
+		path:    filepath.Clean(path),
+		options: options,
+		now:     time.Now,
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	f.removeExpired()
+	return f, nil
+}
+
+// 打开当前文件
+func (self *File) open() error {
+	file, err := os.OpenFile(self.path, os.O_RDWR|os.O_CREATE|os.O_APPEND, self.options.Mode)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	self.file = file
+	self.size = info.Size()
+	self.openedAt = self.now()
+	return nil
+}
+
+// 写入数据，写入后超过大小限制或写入时长超过限制时先滚动文件
+func (self *File) Write(p []byte) (n int, err error) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	if self.file == nil {
+		return 0, os.ErrClosed
+	}
+	if self.size > 0 && self.shouldRotate(int64(len(p))) {
+		if err = self.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err = self.file.Write(p)
+	self.size += int64(n)
+	return
+}
+
+// 判断写入指定字节数前是否需要滚动
+func (self *File) shouldRotate(size int64) bool {
+	if self.options.MaxSize > 0 && self.size+size > self.options.MaxSize {
+		return true
+	}
+	return self.options.MaxAge > 0 && self.now().Sub(self.openedAt) >= self.options.MaxAge
+}
+
+// 立即滚动文件
+func (self *File) Rotate() error {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	if self.file == nil {
+		return os.ErrClosed
+	}
+	return self.rotate()
+}
+
+// 滚动文件
+func (self *File) rotate() error {
+	if err := self.file.Close(); err != nil {
+		return err
+	}
+	self.file = nil
+	if self.options.MaxBackups > 0 {
+		self.removeBackup(self.options.MaxBackups)
+		for k := self.options.MaxBackups - 1; k > 0; k-- {
+			_ = os.Rename(self.backup(k), self.backup(k+1))
+			_ = os.Rename(self.backup(k)+compressSuffix, self.backup(k+1)+compressSuffix)
+		}
+		if err := os.Rename(self.path, self.backup(1)); err != nil {
+			return err
+		}
+		if self.options.Compress {
+			if err := compress(self.backup(1), self.options.Mode); err != nil {
+				return err
+			}
+		}
+		self.removeExpired()
+	} else if err := os.Remove(self.path); err != nil {
+		return err
+	}
+	return self.open()
+}
+
+// 删除超过保留时间的历史文件
+func (self *File) removeExpired() {
+	if self.options.Retention <= 0 {
+		return
+	}
+	deadline := self.now().Add(-self.options.Retention)
+	for k := 1; k <= self.options.MaxBackups; k++ {
+		for _, path := range []string{self.backup(k), self.backup(k) + compressSuffix} {
+			if info, err := os.Stat(path); err == nil && info.ModTime().Before(deadline) {
+				_ = os.Remove(path)
+			}
+		}
+	}
+}
+
+// 删除历史文件，包括压缩后的文件
+func (self *File) removeBackup(k int) {
+	_ = os.Remove(self.backup(k))
+	_ = os.Remove(self.backup(k) + compressSuffix)
+}
+
+// 历史文件的路径，不含压缩文件的扩展名
+func (self *File) backup(k int) string {
+	return self.path + "." + strconv.Itoa(k)
+}
+
+// 将文件压缩为path.gz并删除原文件，保留原文件的修改时间以便按保留时间删除
+func compress(path string, mode os.FileMode) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = src.Close()
+	}()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(path+compressSuffix, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	writer := gzip.NewWriter(dst)
+	if _, err = io.Copy(writer, src); err == nil {
+		err = writer.Close()
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(path + compressSuffix)
+		return err
+	}
+	_ = os.Chtimes(path+compressSuffix, info.ModTime(), info.ModTime())
+	return os.Remove(path)
+}
+
+// 获取现有的文件路径，按从旧到新排序，最后一个为当前文件，压缩的历史文件以.gz结尾
+func (self *File) Files() (files []string) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	for k := self.options.MaxBackups; k > 0; k-- {
+		for _, path := range []string{self.backup(k) + compressSuffix, self.backup(k)} {
+			if _, err := os.Stat(path); err == nil {
+				files = append(files, path)
+				break
+			}
+		}
+	}
+	return append(files, self.path)
+}
+
+// 关闭文件
+func (self *File) Close() error {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	if self.file == nil {
+		return nil
+	}
+	err := self.file.Close()
+	self.file = nil
+	return err
+}
@@ -0,0 +1,110 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 创建临时目录，返回其中的文件路径及清理函数
+func tempPath(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "rotate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(dir, "test.log"), func() {
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func TestRotateBySize(t *testing.T) {
+	path, cleanup := tempPath(t)
+	defer cleanup()
+	file, err := Open(path, 0, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	for _, data := range []string{"aaaaaaaa", "bbbbbbbb", "cccccccc", "dddddddd"} {
+		if _, err = file.Write([]byte(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := file.Files()
+	if len(files) != 3 || files[0] != path+".2" || files[2] != path {
+		t.Fatalf("文件列表不正确：%v", files)
+	}
+	if data, _ := ioutil.ReadFile(path + ".2"); string(data) != "bbbbbbbb" {
+		t.Fatalf("最旧的文件内容应为bbbbbbbb，实际为%s", data)
+	}
+}
+
+func TestRotateByAge(t *testing.T) {
+	path, cleanup := tempPath(t)
+	defer cleanup()
+	now := time.Now()
+	file, err := OpenOptions(path, Options{MaxAge: time.Hour, MaxBackups: 3, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	file.now = func() time.Time { return now }
+	file.openedAt = now
+
+	if _, err = file.Write([]byte("old")); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(time.Hour)
+	if _, err = file.Write([]byte("new")); err != nil {
+		t.Fatal(err)
+	}
+	files := file.Files()
+	if len(files) != 2 || files[0] != path+".1"+compressSuffix {
+		t.Fatalf("文件列表不正确：%v", files)
+	}
+	src, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	reader, err := gzip.NewReader(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadAll(reader); string(data) != "old" {
+		t.Fatalf("压缩文件的内容应为old，实际为%s", data)
+	}
+}
+
+func TestRetention(t *testing.T) {
+	path, cleanup := tempPath(t)
+	defer cleanup()
+	file, err := OpenOptions(path, Options{MaxBackups: 3, Retention: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err = file.Write([]byte("a")); err != nil {
+		t.Fatal(err)
+	}
+	if err = file.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * time.Hour)
+	if err = os.Chtimes(path+".1", old, old); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = file.Write([]byte("b")); err != nil {
+		t.Fatal(err)
+	}
+	if err = file.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	// 原path.1滚动为path.2后已超过保留时间
+	if files := file.Files(); len(files) != 2 || files[0] != path+".1" {
+		t.Fatalf("文件列表不正确：%v", files)
+	}
+}
@@ -9,7 +9,7 @@ import (
 	"local/storage/etcd"
 )
 
-// 构建存储器实例
+// 构建存储器实例，返回的存储器会记录每个操作的耗时及错误
 // key为存储器的名称，value为存储器的参数json字符串
 func Build(name, config string) (global.StorageType, error) {
 	switch name {
@@ -19,7 +19,7 @@ func Build(name, config string) (global.StorageType, error) {
 			log.Err(err).Caller().Send()
 			return nil, err
 		}
-		return sa, nil
+		return Instrument(sa), nil
 	}
 	return nil, errors.New("不支持的存储器")
 }
@@ -0,0 +1,72 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"local/engine"
+	"local/global"
+)
+
+// 从存储器加载ACL令牌到本地
+func (self *Etcd) LoadToken(data []byte) (err error) {
+	var token global.ACLToken
+	if err = token.UnmarshalJSON(data); err != nil {
+		log.Err(err).Caller().Send()
+		return
+	}
+	return engine.SetToken(token)
+}
+
+// 将ACL令牌保存到存储器中
+func (self *Etcd) SaveToken(token global.ACLToken) error {
+	if token.SecretHash == "" {
+		return errors.New("secret_hash不能为空")
+	}
+	value, err := token.MarshalJSON()
+	if err != nil {
+		log.Err(err).Caller().Send()
+		return err
+	}
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer ctxCancel()
+	if _, err = self.client.Put(ctx, self.tokenKey(token.SecretHash), global.BytesToStr(value)); err != nil {
+		log.Err(err).Caller().Send()
+		return err
+	}
+	return nil
+}
+
+// 删除本地的ACL令牌
+func (self *Etcd) DeleteLocalToken(key string) error {
+	return engine.DelToken(path.Base(key))
+}
+
+// 删除存储器中的ACL令牌
+func (self *Etcd) DeleteStorageToken(secretHash string) error {
+	if secretHash == "" {
+		return errors.New("secret_hash不能为空")
+	}
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer ctxCancel()
+	if _, err := self.client.Delete(ctx, self.tokenKey(secretHash)); err != nil {
+		log.Err(err).Caller().Send()
+		return err
+	}
+	return nil
+}
+
+// 生成ACL令牌在存储器中的key
+// key=prefix/acl/tokens/sha256(secret)
+func (self *Etcd) tokenKey(secretHash string) string {
+	var key strings.Builder
+	key.WriteString(self.KeyPrefix)
+	key.WriteString("/acl/tokens/")
+	key.WriteString(secretHash)
+	return key.String()
+}
@@ -3,13 +3,13 @@ package etcd
 import (
 	"context"
 	"strings"
-	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
 	"github.com/rs/zerolog/log"
 
+	"local/engine"
 	"local/global"
 )
 
@@ -17,44 +17,66 @@ import (
 // todo 加载所有数据要加分布式锁，防止加载的不是最新的数据
 func (self *Etcd) LoadAll() error {
 	// 清空本地数据
-	global.Services.Range(func(key, _ interface{}) bool {
-		global.Services.Delete(key)
+	engine.Reset()
+	global.ACLTokens.Range(func(key, _ interface{}) bool {
+		global.ACLTokens.Delete(key)
 		return true
 	})
-	global.Services = sync.Map{}
-	atomic.StoreUint32(&global.TotalServices, 0)
 
 	ctx, ctxCancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer ctxCancel()
 
-	// 从远程加载所有服务列表
-	var key strings.Builder
-	key.WriteString(self.KeyPrefix)
-	key.WriteString("/services/")
-	resp, err := self.client.Get(ctx, key.String(), clientv3.WithPrefix())
-	if err != nil {
-		log.Err(err).Caller().Send()
+	// 迁移命名空间功能之前的数据及旧格式的节点地址
+	if err := self.migrateLegacy(ctx); err != nil {
 		return err
 	}
-	for k := range resp.Kvs {
-		err = self.LoadService(resp.Kvs[k].Value)
+	if err := self.migrateNodeAddresses(ctx); err != nil {
+		return err
+	}
+
+	// 从远程加载所有命名空间的服务及节点，跳过可能很大的审计记录
+	var nodes []*mvccpb.KeyValue
+	for _, r := range self.dataRanges() {
+		resp, err := self.client.Get(ctx, r[0], clientv3.WithRange(r[1]))
 		if err != nil {
 			log.Err(err).Caller().Send()
 			return err
 		}
+		for k := range resp.Kvs {
+			keyStr := global.BytesToStr(resp.Kvs[k].Key)
+			_, kind, _, ok := self.parseKey(keyStr)
+			if !ok {
+				continue
+			}
+			// 节点依赖所属的服务，在加载所有服务后加载
+			if kind == kindNodes {
+				nodes = append(nodes, resp.Kvs[k])
+				continue
+			}
+			if err = self.LoadService(keyStr, resp.Kvs[k].Value, resp.Kvs[k].ModRevision); err != nil {
+				log.Err(err).Caller().Send()
+				return err
+			}
+		}
+	}
+	for k := range nodes {
+		if err := self.LoadNode(global.BytesToStr(nodes[k].Key), nodes[k].Value, nodes[k].ModRevision); err != nil {
+			log.Err(err).Caller().Send()
+			return err
+		}
 	}
 
-	// 从远程加载所有节点列表
-	key.Reset()
+	// 从远程加载所有ACL令牌
+	var key strings.Builder
 	key.WriteString(self.KeyPrefix)
-	key.WriteString("/nodes/")
-	resp, err = self.client.Get(ctx, key.String(), clientv3.WithPrefix())
+	key.WriteString("/acl/tokens/")
+	resp, err := self.client.Get(ctx, key.String(), clientv3.WithPrefix())
 	if err != nil {
 		log.Err(err).Caller().Send()
 		return err
 	}
 	for k := range resp.Kvs {
-		err = self.LoadNode(global.BytesToStr(resp.Kvs[k].Key), resp.Kvs[k].Value)
+		err = self.LoadToken(resp.Kvs[k].Value)
 		if err != nil {
 			log.Err(err).Caller().Send()
 			return err
@@ -78,13 +100,7 @@ func (self *Etcd) SaveAll() (err error) {
 		}
 		nodes := ci.Nodes()
 		for k := range nodes {
-			if err = self.SaveNode(ci.Config().ServiceID, global.Node{
-				IP:      nodes[k].IP,
-				Port:    nodes[k].Port,
-				Weight:  nodes[k].Weight,
-				TTL:     nodes[k].TTL,
-				Expires: nodes[k].Expires,
-			}); err != nil {
+			if err = self.SaveNode(ci.Config().Namespace, ci.Config().ServiceID, nodes[k]); err != nil {
 				log.Err(err).Caller().Send()
 				return false
 			}
@@ -0,0 +1,100 @@
+package etcd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/rs/zerolog/log"
+
+	"local/global"
+)
+
+// 审计记录的租约在此时间内复用，避免每条记录都申请租约
+const auditLeaseReuse = time.Minute
+
+// 审计记录当前复用的租约及停止复用的时间
+var auditLease struct {
+	sync.Mutex
+	id      clientv3.LeaseID
+	expires time.Time
+}
+
+// 写入审计记录
+// key=prefix/audit/unix纳秒(19位)-clientID，配置了保留时间时通过租约自动过期
+func (self *Etcd) WriteAudit(timestamp int64, data []byte) error {
+	var opts []clientv3.OpOption
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer ctxCancel()
+	if global.Config().Audit.Retention > 0 {
+		leaseID, err := self.auditLeaseID(ctx)
+		if err != nil {
+			log.Err(err).Caller().Send()
+			return err
+		}
+		opts = append(opts, clientv3.WithLease(leaseID))
+	}
+	if _, err := self.client.Put(ctx, self.auditKey(timestamp)+"-"+self.ClientID, global.BytesToStr(data), opts...); err != nil {
+		log.Err(err).Caller().Send()
+		return err
+	}
+	return nil
+}
+
+// 获取审计记录的租约，租约的有效期为保留时间加上复用时间
+func (self *Etcd) auditLeaseID(ctx context.Context) (clientv3.LeaseID, error) {
+	auditLease.Lock()
+	defer auditLease.Unlock()
+	if auditLease.id != 0 && time.Now().Before(auditLease.expires) {
+		return auditLease.id, nil
+	}
+	resp, err := self.client.Grant(ctx, int64((global.Config().Audit.Retention+auditLeaseReuse)/time.Second))
+	if err != nil {
+		return 0, err
+	}
+	auditLease.id = resp.ID
+	auditLease.expires = time.Now().Add(auditLeaseReuse)
+	return resp.ID, nil
+}
+
+// 按时间倒序读取指定时间及之后的审计记录，每页最多limit条
+// cursor为上一页最后一条记录的key，下一页从该key之前开始读取，返回的cursor为空表示没有更多记录
+func (self *Etcd) ReadAudit(since int64, cursor string, limit int) (list [][]byte, next string, err error) {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer ctxCancel()
+	end := cursor
+	if end == "" {
+		var prefix strings.Builder
+		prefix.WriteString(self.KeyPrefix)
+		prefix.WriteString("/audit/")
+		end = clientv3.GetPrefixRangeEnd(prefix.String())
+	}
+	resp, err := self.client.Get(ctx, self.auditKey(since),
+		clientv3.WithRange(end),
+		clientv3.WithSort(clientv3.SortByKey, clientv3.SortDescend),
+		clientv3.WithLimit(int64(limit)),
+	)
+	if err != nil {
+		log.Err(err).Caller().Send()
+		return nil, "", err
+	}
+	list = make([][]byte, len(resp.Kvs))
+	for k := range resp.Kvs {
+		list[k] = resp.Kvs[k].Value
+	}
+	if resp.More && len(resp.Kvs) > 0 {
+		next = global.BytesToStr(resp.Kvs[len(resp.Kvs)-1].Key)
+	}
+	return list, next, nil
+}
+
+// 生成审计记录的key，时间补齐位数以便按key排序
+func (self *Etcd) auditKey(timestamp int64) string {
+	if timestamp < 0 {
+		timestamp = 0
+	}
+	return fmt.Sprintf("%s/audit/%019d", self.KeyPrefix, timestamp)
+}
@@ -0,0 +1,73 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/rs/zerolog/log"
+
+	"local/global"
+)
+
+// 批量写入或删除存储器中的节点
+// 操作会被合并到尽量少的事务中提交，同一事务内的操作要么全部成功，要么全部失败
+func (self *Etcd) BatchNodes(operations []global.NodeOperation) []error {
+	var (
+		errs  = make([]error, len(operations))
+		ops   []clientv3.Op
+		index []int               // ops中每个操作对应的operations下标
+		keys  = map[string]bool{} // 当前事务中已存在的key，etcd不允许同一事务中出现重复的key
+	)
+
+	for k := range operations {
+		if operations[k].ServiceID == "" || operations[k].Node.IP == "" || operations[k].Node.Port == 0 {
+			errs[k] = errors.New("serviceID、ip和port不能为空")
+			continue
+		}
+		key := self.nodeKey(operations[k].Namespace, operations[k].ServiceID, operations[k].Node.IP, operations[k].Node.Port)
+		var op clientv3.Op
+		switch operations[k].Action {
+		case global.NodeOperationSet:
+			value, err := marshalNode(operations[k].Node)
+			if err != nil {
+				log.Err(err).Caller().Send()
+				errs[k] = err
+				continue
+			}
+			op = clientv3.OpPut(key, global.BytesToStr(value))
+		case global.NodeOperationDelete:
+			op = clientv3.OpDelete(key)
+		default:
+			errs[k] = errors.New("不支持的操作类型")
+			continue
+		}
+
+		// 当前事务已满或出现重复的key时，先提交当前事务
+		if uint(len(ops)) >= self.MaxTxnOps || keys[key] {
+			self.commitTxn(ops, index, errs)
+			ops, index, keys = nil, nil, map[string]bool{}
+		}
+		ops = append(ops, op)
+		index = append(index, k)
+		keys[key] = true
+	}
+	self.commitTxn(ops, index, errs)
+	return errs
+}
+
+// 提交事务，失败时将错误写入事务中每个操作对应的位置
+func (self *Etcd) commitTxn(ops []clientv3.Op, index []int, errs []error) {
+	if len(ops) == 0 {
+		return
+	}
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer ctxCancel()
+	if _, err := self.client.Txn(ctx).Then(ops...).Commit(); err != nil {
+		log.Err(err).Caller().Send()
+		for _, k := range index {
+			errs[k] = err
+		}
+	}
+}
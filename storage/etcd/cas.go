@@ -0,0 +1,54 @@
+package etcd
+
+import (
+	"context"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/rs/zerolog/log"
+
+	"local/global"
+)
+
+// 按期望的修订版本号生成事务的比较条件
+// etcd中不存在的key，其ModRevision为0
+func revisionCompare(key string, revision int64) []clientv3.Cmp {
+	switch {
+	case revision == global.AnyRevision:
+		return nil
+	case revision == global.ExistsRevision:
+		return []clientv3.Cmp{clientv3.Compare(clientv3.ModRevision(key), ">", 0)}
+	default:
+		return []clientv3.Cmp{clientv3.Compare(clientv3.ModRevision(key), "=", revision)}
+	}
+}
+
+// 比较修订版本号后写入key，返回写入后的修订版本号
+func (self *Etcd) putCAS(key, value string, revision int64) (int64, error) {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer ctxCancel()
+	resp, err := self.client.Txn(ctx).If(revisionCompare(key, revision)...).Then(clientv3.OpPut(key, value)).Commit()
+	if err != nil {
+		log.Err(err).Caller().Send()
+		return 0, err
+	}
+	if !resp.Succeeded {
+		return 0, global.ErrRevisionMismatch
+	}
+	return resp.Header.Revision, nil
+}
+
+// 比较修订版本号后删除key
+func (self *Etcd) deleteCAS(key string, revision int64) error {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer ctxCancel()
+	resp, err := self.client.Txn(ctx).If(revisionCompare(key, revision)...).Then(clientv3.OpDelete(key)).Commit()
+	if err != nil {
+		log.Err(err).Caller().Send()
+		return err
+	}
+	if !resp.Succeeded {
+		return global.ErrRevisionMismatch
+	}
+	return nil
+}
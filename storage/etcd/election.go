@@ -0,0 +1,79 @@
+package etcd
+
+import (
+	"context"
+	"sync"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/clientv3/concurrency"
+	"github.com/rs/zerolog/log"
+)
+
+// 当前实例参与选举使用的会话
+var election struct {
+	sync.Mutex
+	session  *concurrency.Session
+	election *concurrency.Election
+}
+
+// 选举的key前缀，每个候选者在其下创建一个绑定会话租约的key，创建版本号最小的为领导者
+func (self *Etcd) electionKey() string {
+	return self.KeyPrefix + "/election"
+}
+
+// 参与选举，阻塞直到当选或上下文被取消，返回失去领导权(会话租约失效)时关闭的通道
+// 会话的租约由etcd客户端自动续约，进程异常退出时在ttl秒后由etcd删除，其它候选者随之当选
+func (self *Etcd) Campaign(ctx context.Context, ttl int64) (<-chan struct{}, error) {
+	session, err := concurrency.NewSession(self.client, concurrency.WithTTL(int(ttl)))
+	if err != nil {
+		log.Err(err).Caller().Send()
+		return nil, err
+	}
+	candidate := concurrency.NewElection(session, self.electionKey())
+	if err = candidate.Campaign(ctx, self.ClientID); err != nil {
+		// 撤销租约以删除候选key，避免取消后仍在队列中
+		if closeErr := session.Close(); closeErr != nil {
+			log.Err(closeErr).Caller().Send()
+		}
+		return nil, err
+	}
+
+	election.Lock()
+	election.session = session
+	election.election = candidate
+	election.Unlock()
+	return session.Done(), nil
+}
+
+// 放弃领导权并结束会话
+func (self *Etcd) Resign(ctx context.Context) error {
+	election.Lock()
+	defer election.Unlock()
+	if election.session == nil {
+		return nil
+	}
+	err := election.election.Resign(ctx)
+	if err != nil {
+		log.Err(err).Caller().Send()
+	}
+	// 会话可能已失效，撤销租约失败不影响结果
+	if closeErr := election.session.Close(); closeErr != nil && err == nil {
+		log.Warn().Err(closeErr).Caller().Msg("结束选举会话失败")
+	}
+	election.session = nil
+	election.election = nil
+	return err
+}
+
+// 获取当前领导者的成员ID，没有领导者时返回空字符串
+func (self *Etcd) Leader(ctx context.Context) (string, error) {
+	resp, err := self.client.Get(ctx, self.electionKey()+"/", clientv3.WithFirstCreate()...)
+	if err != nil {
+		log.Err(err).Caller().Send()
+		return "", err
+	}
+	if len(resp.Kvs) == 0 {
+		return "", nil
+	}
+	return string(resp.Kvs[0].Value), nil
+}
@@ -0,0 +1,24 @@
+package etcd
+
+import (
+	"context"
+
+	"github.com/coreos/etcd/clientv3"
+)
+
+// 检查存储器是否可用，返回监听的key范围内数据的最新修订版本号
+// 只比较监听的key范围，避免其它应用写入同一个etcd或审计记录、集群成员的变更时误判监听落后
+func (self *Etcd) Ping(ctx context.Context) (int64, error) {
+	var revision int64
+	for _, r := range self.dataRanges() {
+		opts := append([]clientv3.OpOption{clientv3.WithRange(r[1]), clientv3.WithKeysOnly()}, clientv3.WithLastRev()...)
+		resp, err := self.client.Get(ctx, r[0], opts...)
+		if err != nil {
+			return 0, err
+		}
+		if len(resp.Kvs) > 0 && resp.Kvs[0].ModRevision > revision {
+			revision = resp.Kvs[0].ModRevision
+		}
+	}
+	return revision, nil
+}
@@ -0,0 +1,106 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/rs/zerolog/log"
+
+	"local/global"
+)
+
+// 当前实例的成员信息使用的租约
+var memberLease struct {
+	sync.Mutex
+	id  clientv3.LeaseID
+	ttl int64
+}
+
+// 当前实例的成员ID
+func (self *Etcd) MemberID() string {
+	return self.ClientID
+}
+
+// 成员的key
+func (self *Etcd) memberKey(id string) string {
+	return self.KeyPrefix + "/members/" + id
+}
+
+// 注册或更新当前实例的成员信息
+// key=prefix/members/clientID，每次更新时续约，租约失效(例如与etcd断开超过TTL)时重新申请
+func (self *Etcd) SaveMember(member global.Member, ttl int64) error {
+	member.ID = self.ClientID
+	data, err := json.Marshal(&member)
+	if err != nil {
+		log.Err(err).Caller().Send()
+		return err
+	}
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer ctxCancel()
+
+	memberLease.Lock()
+	defer memberLease.Unlock()
+	if memberLease.id != 0 && memberLease.ttl == ttl {
+		if _, err = self.client.KeepAliveOnce(ctx, memberLease.id); err != nil {
+			log.Warn().Err(err).Caller().Msg("成员租约续约失败，重新申请租约")
+			memberLease.id = 0
+		}
+	}
+	if memberLease.id == 0 || memberLease.ttl != ttl {
+		resp, err := self.client.Grant(ctx, ttl)
+		if err != nil {
+			log.Err(err).Caller().Send()
+			return err
+		}
+		memberLease.id = resp.ID
+		memberLease.ttl = ttl
+	}
+	if _, err = self.client.Put(ctx, self.memberKey(self.ClientID), global.BytesToStr(data), clientv3.WithLease(memberLease.id)); err != nil {
+		log.Err(err).Caller().Send()
+		return err
+	}
+	return nil
+}
+
+// 注销当前实例，撤销租约后成员信息随之删除
+func (self *Etcd) DeleteMember() (err error) {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer ctxCancel()
+
+	memberLease.Lock()
+	defer memberLease.Unlock()
+	if memberLease.id != 0 {
+		_, err = self.client.Revoke(ctx, memberLease.id)
+		memberLease.id = 0
+	} else {
+		_, err = self.client.Delete(ctx, self.memberKey(self.ClientID))
+	}
+	if err != nil {
+		log.Err(err).Caller().Send()
+	}
+	return
+}
+
+// 获取所有存活的成员，租约到期的成员已被etcd删除
+func (self *Etcd) Members() ([]global.Member, error) {
+	ctx, ctxCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer ctxCancel()
+	resp, err := self.client.Get(ctx, self.memberKey(""), clientv3.WithPrefix())
+	if err != nil {
+		log.Err(err).Caller().Send()
+		return nil, err
+	}
+	members := make([]global.Member, 0, len(resp.Kvs))
+	for k := range resp.Kvs {
+		var member global.Member
+		if err = json.Unmarshal(resp.Kvs[k].Value, &member); err != nil {
+			log.Err(err).Caller().Str("key", global.BytesToStr(resp.Kvs[k].Key)).Send()
+			continue
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
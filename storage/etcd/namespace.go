@@ -0,0 +1,193 @@
+package etcd
+
+import (
+	"context"
+	"strings"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"github.com/rs/zerolog/log"
+
+	"local/global"
+)
+
+// 命名空间下的数据类型
+const (
+	kindServices = "services"
+	kindNodes    = "nodes"
+)
+
+// 生成命名空间下某类数据在存储器中的key前缀
+// key=prefix/namespace/kind/
+func (self *Etcd) namespaceKey(namespace, kind string) string {
+	var key strings.Builder
+	key.WriteString(self.KeyPrefix)
+	key.WriteString("/")
+	key.WriteString(namespace)
+	key.WriteString("/")
+	key.WriteString(kind)
+	key.WriteString("/")
+	return key.String()
+}
+
+// 从存储器的key中解析命名空间、数据类型及之后的部分
+// key=prefix/namespace/kind/rest，不属于任何命名空间的key返回ok=false
+func (self *Etcd) parseKey(key string) (namespace, kind, rest string, ok bool) {
+	if !strings.HasPrefix(key, self.KeyPrefix+"/") {
+		return
+	}
+	parts := strings.SplitN(key[len(self.KeyPrefix)+1:], "/", 3)
+	if len(parts) != 3 || !global.ValidNamespace(parts[0]) {
+		return
+	}
+	if parts[1] != kindServices && parts[1] != kindNodes {
+		return
+	}
+	return parts[0], parts[1], parts[2], true
+}
+
+// 将命名空间功能之前的服务及节点迁移到默认命名空间
+// 旧的key=prefix/services/...及prefix/nodes/...，迁移后为prefix/default/services/...及prefix/default/nodes/...
+func (self *Etcd) migrateLegacy(ctx context.Context) error {
+	var total int
+	for _, kind := range []string{kindServices, kindNodes} {
+		oldPrefix := self.KeyPrefix + "/" + kind + "/"
+		resp, err := self.client.Get(ctx, oldPrefix, clientv3.WithPrefix())
+		if err != nil {
+			log.Err(err).Caller().Send()
+			return err
+		}
+		for _, kv := range resp.Kvs {
+			newKey := self.namespaceKey(global.DefaultNamespace, kind) + strings.TrimPrefix(global.BytesToStr(kv.Key), oldPrefix)
+			moved, err := self.moveKey(ctx, kv, newKey)
+			if err != nil {
+				return err
+			}
+			if moved {
+				total++
+			}
+		}
+	}
+	if total > 0 {
+		log.Info().Int("keys", total).Str("namespace", global.DefaultNamespace).Msg("已迁移旧的服务及节点数据")
+	}
+	return self.migrateLegacyTokens(ctx)
+}
+
+// 将命名空间功能之前创建的ACL令牌规则明确命名空间，旧的全局规则改为所有命名空间
+func (self *Etcd) migrateLegacyTokens(ctx context.Context) error {
+	resp, err := self.client.Get(ctx, self.tokenKey(""), clientv3.WithPrefix())
+	if err != nil {
+		log.Err(err).Caller().Send()
+		return err
+	}
+	var total int
+	for _, kv := range resp.Kvs {
+		var token global.ACLToken
+		if err = token.UnmarshalJSON(kv.Value); err != nil {
+			// 无法解析的令牌在加载时报错
+			continue
+		}
+		if !global.MigrateLegacyRules(token.Rules) {
+			continue
+		}
+		value, err := token.MarshalJSON()
+		if err != nil {
+			log.Err(err).Caller().Send()
+			return err
+		}
+		// 令牌已被修改或删除时跳过，由修改者写入的规则为准
+		if _, err = self.putCAS(global.BytesToStr(kv.Key), global.BytesToStr(value), kv.ModRevision); err != nil && err != global.ErrRevisionMismatch {
+			return err
+		}
+		if err == nil {
+			total++
+		}
+	}
+	if total > 0 {
+		log.Info().Int("tokens", total).Msg("已迁移旧的ACL令牌规则")
+	}
+	return nil
+}
+
+// 将节点的key迁移到规范的地址格式，IPv6地址加上[]且使用标准格式
+// 旧的key=prefix/namespace/nodes/base64(serviceID)/base64(fe80::1:80)，迁移后为.../base64([fe80::1]:80)
+func (self *Etcd) migrateNodeAddresses(ctx context.Context) error {
+	var total int
+	for _, r := range self.dataRanges() {
+		resp, err := self.client.Get(ctx, r[0], clientv3.WithRange(r[1]))
+		if err != nil {
+			log.Err(err).Caller().Send()
+			return err
+		}
+		for _, kv := range resp.Kvs {
+			newKey, ok := self.canonicalNodeKey(global.BytesToStr(kv.Key))
+			if !ok {
+				continue
+			}
+			moved, err := self.moveKey(ctx, kv, newKey)
+			if err != nil {
+				return err
+			}
+			if moved {
+				total++
+			}
+		}
+	}
+	if total > 0 {
+		log.Info().Int("keys", total).Msg("已迁移旧格式的节点地址")
+	}
+	return nil
+}
+
+// 获取节点key的规范格式，只在key是节点且不是规范格式时返回ok=true
+func (self *Etcd) canonicalNodeKey(key string) (string, bool) {
+	_, kind, _, ok := self.parseKey(key)
+	if !ok || kind != kindNodes {
+		return "", false
+	}
+	namespace, serviceID, ip, port, err := self.ParseNode(key)
+	if err != nil {
+		// 无法解析的key在加载时报错
+		return "", false
+	}
+	newKey := self.nodeKey(namespace, serviceID, ip, port)
+	return newKey, newKey != key
+}
+
+// 将key移动到newKey，使用单独的事务，只在旧key未被修改且新key不存在时写入，多个实例同时迁移也不会覆盖新数据
+// 新key已存在时删除未被修改的旧key，返回是否写入了新key
+func (self *Etcd) moveKey(ctx context.Context, kv *mvccpb.KeyValue, newKey string) (bool, error) {
+	oldKey := global.BytesToStr(kv.Key)
+	txnResp, err := self.client.Txn(ctx).If(
+		clientv3.Compare(clientv3.ModRevision(oldKey), "=", kv.ModRevision),
+		clientv3.Compare(clientv3.CreateRevision(newKey), "=", 0),
+	).Then(
+		clientv3.OpPut(newKey, global.BytesToStr(kv.Value)),
+		clientv3.OpDelete(oldKey),
+	).Commit()
+	if err != nil {
+		log.Err(err).Caller().Send()
+		return false, err
+	}
+	if txnResp.Succeeded {
+		return true, nil
+	}
+	// 新key已存在，旧key未被修改时视为过期数据删除
+	if err = self.deleteCAS(oldKey, kv.ModRevision); err != nil && err != global.ErrRevisionMismatch {
+		return false, err
+	}
+	return false, nil
+}
+
+// 所有命名空间的服务、节点及ACL令牌所在的key范围
+// 跳过可能很大的审计记录，以及频繁更新且无需同步到本地的集群成员及领导者选举的key
+// 保留名称之后的0是/之后的字符，[name/, name0)正好是name/下的所有key
+func (self *Etcd) dataRanges() [][2]string {
+	return [][2]string{
+		{self.KeyPrefix + "/", self.KeyPrefix + "/audit/"},
+		{self.KeyPrefix + "/audit0", self.KeyPrefix + "/election/"},
+		{self.KeyPrefix + "/election0", self.KeyPrefix + "/members/"},
+		{self.KeyPrefix + "/members0", self.KeyPrefix + "0"},
+	}
+}
@@ -0,0 +1,41 @@
+package etcd
+
+import "testing"
+
+func TestDataRanges(t *testing.T) {
+	storage := &Etcd{KeyPrefix: "/tsing"}
+	inRanges := func(key string) bool {
+		for _, r := range storage.dataRanges() {
+			if key >= r[0] && key < r[1] {
+				return true
+			}
+		}
+		return false
+	}
+	for _, key := range []string{
+		"/tsing/default/services/b3JkZXJz",
+		"/tsing/default/nodes/b3JkZXJz/MTAuMC4wLjE6ODA",
+		"/tsing/acl/tokens/YQ",
+		// 与保留名称前缀相同的命名空间
+		"/tsing/audit-x/services/b3JkZXJz",
+		"/tsing/audit_x/services/b3JkZXJz",
+		"/tsing/election-x/nodes/b3JkZXJz/MTAuMC4wLjE6ODA",
+		"/tsing/members.x/services/b3JkZXJz",
+		"/tsing/zzz/services/b3JkZXJz",
+	} {
+		if !inRanges(key) {
+			t.Fatalf("%s应在数据的key范围内", key)
+		}
+	}
+	for _, key := range []string{
+		"/tsing/audit/0000000000000001",
+		"/tsing/members/a1b2c3",
+		"/tsing/election/694d7a1b2c3",
+		"/tsing0",
+		"/other/default/services/b3JkZXJz",
+	} {
+		if inRanges(key) {
+			t.Fatalf("%s不应在数据的key范围内", key)
+		}
+	}
+}
@@ -24,8 +24,12 @@ type Etcd struct {
 	MaxCallRecvMsgSize   uint     `json:"max_call_recv_msg_size"`
 	RejectOldCluster     bool     `json:"reject_old_cluster"`
 	PermitWithoutStream  bool     `json:"permit_without_stream"`
+	MaxTxnOps            uint     `json:"max_txn_ops"` // 单个事务的最大操作数，需与etcd服务端的--max-txn-ops一致
 }
 
+// etcd服务端--max-txn-ops参数的默认值
+const defaultMaxTxnOps = 128
+
 func New(config string) (*Etcd, error) {
 	var instance Etcd
 	instance.ClientID = global.SnowflakeNode.Generate().String()
@@ -35,6 +39,9 @@ func New(config string) (*Etcd, error) {
 		log.Err(err).Caller().Send()
 		return nil, err
 	}
+	if instance.MaxTxnOps == 0 {
+		instance.MaxTxnOps = defaultMaxTxnOps
+	}
 
 	instance.client, err = clientv3.New(clientv3.Config{
 		Endpoints:            instance.Endpoints,
@@ -55,3 +62,8 @@ func New(config string) (*Etcd, error) {
 	}
 	return &instance, nil
 }
+
+// 关闭etcd客户端
+func (self *Etcd) Close() error {
+	return self.client.Close()
+}
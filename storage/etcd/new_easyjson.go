@@ -28,7 +28,7 @@ func easyjson16d7ec28DecodeLocalStorageEtcd(in *jlexer.Lexer, out *Etcd) {
 	}
 	in.Delim('{')
 	for !in.IsDelim('}') {
-		key := in.UnsafeString()
+		key := in.UnsafeFieldName(false)
 		in.WantColon()
 		if in.IsNull() {
 			in.Skip()
@@ -81,6 +81,8 @@ func easyjson16d7ec28DecodeLocalStorageEtcd(in *jlexer.Lexer, out *Etcd) {
 			out.RejectOldCluster = bool(in.Bool())
 		case "permit_without_stream":
 			out.PermitWithoutStream = bool(in.Bool())
+		case "max_txn_ops":
+			out.MaxTxnOps = uint(in.Uint())
 		default:
 			in.SkipRecursive()
 		}
@@ -171,6 +173,11 @@ func easyjson16d7ec28EncodeLocalStorageEtcd(out *jwriter.Writer, in Etcd) {
 		out.RawString(prefix)
 		out.Bool(bool(in.PermitWithoutStream))
 	}
+	{
+		const prefix string = ",\"max_txn_ops\":"
+		out.RawString(prefix)
+		out.Uint(uint(in.MaxTxnOps))
+	}
 	out.RawByte('}')
 }
 
@@ -1,12 +1,10 @@
 package etcd
 
 import (
-	"context"
 	"errors"
+	"net"
 	"path"
-	"strconv"
 	"strings"
-	"time"
 
 	"local/engine"
 	"local/global"
@@ -16,20 +14,17 @@ import (
 
 // 节点数据
 type NodeData struct {
-	TTL     uint   `json:"ttl,omitempty"`     // 生命周期(秒)
-	Expires int64  `json:"expires,omitempty"` // 生命周期截止时间(unix时间戳)
-	Weight  int    `json:"weight,omitempty"`  // 权重值
-	Meta    string `json:"meta,omitempty"`
+	TTL     uint     `json:"ttl,omitempty"`     // 生命周期(秒)
+	Expires int64    `json:"expires,omitempty"` // 生命周期截止时间(unix时间戳)
+	Weight  int      `json:"weight,omitempty"`  // 权重值
+	Meta    string   `json:"meta,omitempty"`
+	Tags    []string `json:"tags,omitempty"` // 标签
 }
 
 // 从存储器加载节点到本地，如果不存在则创建
-func (self *Etcd) LoadNode(key string, data []byte) error {
-	var keyPrefix strings.Builder
-	keyPrefix.WriteString(self.KeyPrefix)
-	keyPrefix.WriteString("/nodes/")
-
-	// 从key中解析serviceID, ip, port
-	serviceID, ip, port, err := self.ParseNode(key, keyPrefix.String())
+func (self *Etcd) LoadNode(key string, data []byte, revision int64) error {
+	// 从key中解析namespace, serviceID, ip, port
+	namespace, serviceID, ip, port, err := self.ParseNode(key)
 	if err != nil {
 		log.Err(err).Caller().Send()
 		return err
@@ -44,69 +39,51 @@ func (self *Etcd) LoadNode(key string, data []byte) error {
 	}
 
 	// 写入节点到本地
-	return engine.SetNode(serviceID, global.Node{
-		IP:      ip,
-		Port:    port,
-		TTL:     value.TTL,
-		Weight:  value.Weight,
-		Expires: value.Expires,
-		Mete:    value.Meta,
+	return engine.SetNode(namespace, serviceID, global.Node{
+		IP:       ip,
+		Port:     port,
+		TTL:      value.TTL,
+		Weight:   value.Weight,
+		Expires:  value.Expires,
+		Mete:     value.Meta,
+		Tags:     value.Tags,
+		Revision: revision,
 	})
 }
 
 // 将本地节点数据保存到存储器中，如果不存在则创建
-func (self *Etcd) SaveNode(serviceID string, node global.Node) (err error) {
-	var key strings.Builder
-	key.WriteString(node.IP)
-	key.WriteString(":")
-	key.WriteString(strconv.FormatUint(uint64(node.Port), 10))
-	nodeKey := global.EncodeKey(key.String())
-
-	key.Reset()
-	key.WriteString(self.KeyPrefix)
-	key.WriteString("/nodes/")
-	key.WriteString(global.EncodeKey(serviceID))
-	key.WriteString("/")
-	key.WriteString(nodeKey)
+func (self *Etcd) SaveNode(namespace, serviceID string, node global.Node) (err error) {
+	_, err = self.SaveNodeCAS(namespace, serviceID, node, global.AnyRevision)
+	return
+}
 
-	var (
-		value      NodeData
-		valueBytes []byte
-	)
-	value.Weight = node.Weight
-	value.TTL = node.TTL
-	value.Expires = node.Expires
-	value.Meta = node.Mete
-	valueBytes, err = value.MarshalJSON()
+// 比较修订版本号后将节点数据保存到存储器中，返回写入后的修订版本号
+func (self *Etcd) SaveNodeCAS(namespace, serviceID string, node global.Node, revision int64) (int64, error) {
+	valueBytes, err := marshalNode(node)
 	if err != nil {
 		log.Err(err).Caller().Send()
-		return
-	}
-
-	ctx, ctxCancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer ctxCancel()
-	if _, err = self.client.Put(ctx, key.String(), global.BytesToStr(valueBytes)); err != nil {
-		log.Err(err).Caller().Send()
-		return err
+		return 0, err
 	}
-	return nil
+	return self.putCAS(self.nodeKey(namespace, serviceID, node.IP, node.Port), global.BytesToStr(valueBytes), revision)
 }
 
 // 删除本地的节点
 func (self *Etcd) DeleteLocalNode(key string) error {
-	var keyPrefix strings.Builder
-	keyPrefix.WriteString(self.KeyPrefix)
-	keyPrefix.WriteString("/nodes/")
-	serviceID, ip, port, err := self.ParseNode(key, keyPrefix.String())
+	namespace, serviceID, ip, port, err := self.ParseNode(key)
 	if err != nil {
 		log.Err(err).Caller().Send()
 		return err
 	}
-	return engine.DelNode(serviceID, ip, port)
+	return engine.DelNode(namespace, serviceID, ip, port)
 }
 
 // 删除存储器的节点
-func (self *Etcd) DeleteStorageNode(serviceID, ip string, port uint16) error {
+func (self *Etcd) DeleteStorageNode(namespace, serviceID, ip string, port uint16) error {
+	return self.DeleteStorageNodeCAS(namespace, serviceID, ip, port, global.AnyRevision)
+}
+
+// 比较修订版本号后删除存储器的节点
+func (self *Etcd) DeleteStorageNodeCAS(namespace, serviceID, ip string, port uint16, revision int64) error {
 	if serviceID == "" {
 		return errors.New("serviceID不能为空")
 	}
@@ -116,34 +93,44 @@ func (self *Etcd) DeleteStorageNode(serviceID, ip string, port uint16) error {
 	if port == 0 {
 		return errors.New("port不能为空")
 	}
+	return self.deleteCAS(self.nodeKey(namespace, serviceID, ip, port), revision)
+}
+
+// 生成节点在存储器中的key
+// key=prefix/namespace/nodes/base64(serviceID)/base64(host:port)，IPv6地址加上[]
+func (self *Etcd) nodeKey(namespace, serviceID, ip string, port uint16) string {
+	if namespace == "" {
+		namespace = global.DefaultNamespace
+	}
 	var key strings.Builder
-	key.WriteString(ip)
-	key.WriteString(":")
-	key.WriteString(strconv.FormatUint(uint64(port), 10))
-	node := global.EncodeKey(key.String())
-
-	key.Reset()
-	key.WriteString(self.KeyPrefix)
-	key.WriteString("/nodes/")
+	key.WriteString(self.namespaceKey(namespace, kindNodes))
 	key.WriteString(global.EncodeKey(serviceID))
 	key.WriteString("/")
-	key.WriteString(node)
+	key.WriteString(global.EncodeKey(global.NodeAddress(ip, port)))
+	return key.String()
+}
 
-	ctx, ctxCancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer ctxCancel()
-	_, err := self.client.Delete(ctx, key.String())
-	if err != nil {
-		log.Err(err).Caller().Send()
-		return err
-	}
-	return nil
+// 将节点编码成存储器中的value
+func marshalNode(node global.Node) ([]byte, error) {
+	var value NodeData
+	value.Weight = node.Weight
+	value.TTL = node.TTL
+	value.Expires = node.Expires
+	value.Meta = node.Mete
+	value.Tags = node.Tags
+	return value.MarshalJSON()
 }
 
 // 从key字符串中解析节点信息
-// key=prefix/nodes/base64(serviceID)/base64(ip:port)
-func (self *Etcd) ParseNode(key, prefix string) (serviceID, ip string, port uint16, err error) {
-	if prefix != "" {
-		key = strings.TrimPrefix(key, prefix)
+// key=prefix/namespace/nodes/base64(serviceID)/base64(host:port)
+func (self *Etcd) ParseNode(key string) (namespace, serviceID, ip string, port uint16, err error) {
+	var kind string
+	var ok bool
+	namespace, kind, key, ok = self.parseKey(key)
+	if !ok || kind != kindNodes {
+		err = errors.New("解析节点的命名空间失败")
+		log.Err(err).Caller().Send()
+		return
 	}
 
 	var nodePart string
@@ -152,23 +139,12 @@ func (self *Etcd) ParseNode(key, prefix string) (serviceID, ip string, port uint
 		log.Err(err).Caller().Send()
 		return
 	}
-	pos := strings.Index(nodePart, ":")
-	if pos == -1 {
-		err = errors.New("解析节点信息失败")
-		log.Err(err).Caller().Send()
-		return
-	}
-	ip = nodePart[0:pos]
-	p, er := strconv.Atoi(nodePart[pos+1:])
-	if er != nil {
-		err = er
+	if ip, port, err = parseNodeAddress(nodePart); err != nil {
 		log.Err(err).Caller().Send()
 		return
 	}
-	port = uint16(p)
 
-	pos = -1
-	pos = strings.Index(key, "/")
+	pos := strings.Index(key, "/")
 	if pos == -1 {
 		err = errors.New("解析服务ID信息失败")
 		log.Err(err).Caller().Send()
@@ -178,3 +154,16 @@ func (self *Etcd) ParseNode(key, prefix string) (serviceID, ip string, port uint
 	serviceID, err = global.DecodeKey(key[0:pos])
 	return
 }
+
+// 解析key中的节点地址，兼容迁移前未给IPv6地址加上[]的ip:port
+func parseNodeAddress(address string) (string, uint16, error) {
+	ip, port, err := global.ParseNodeAddress(address)
+	if err == nil {
+		return ip, port, nil
+	}
+	pos := strings.LastIndex(address, ":")
+	if pos == -1 || net.ParseIP(address[:pos]) == nil {
+		return "", 0, errors.New("解析节点信息失败")
+	}
+	return global.ParseNodeAddress("[" + address[:pos] + "]" + address[pos:])
+}
@@ -0,0 +1,62 @@
+package etcd
+
+import (
+	"testing"
+
+	"local/global"
+)
+
+func TestNodeKey(t *testing.T) {
+	storage := &Etcd{KeyPrefix: "/tsing"}
+	for _, c := range []struct {
+		host string
+		port uint16
+	}{
+		{"10.0.0.1", 80},
+		{"fe80::1", 8080},
+		{"node-1.example.com", 443},
+	} {
+		key := storage.nodeKey("prod", "orders", c.host, c.port)
+		namespace, serviceID, host, port, err := storage.ParseNode(key)
+		if err != nil || namespace != "prod" || serviceID != "orders" || host != c.host || port != c.port {
+			t.Fatalf("%s应能解析为原节点，实际为%s %s %s %d %v", key, namespace, serviceID, host, port, err)
+		}
+		if _, ok := storage.canonicalNodeKey(key); ok {
+			t.Fatalf("规范格式的key不应迁移：%s", key)
+		}
+	}
+	if key := storage.nodeKey("prod", "orders", "fe80::1", 80); key != "/tsing/prod/nodes/"+global.EncodeKey("orders")+"/"+global.EncodeKey("[fe80::1]:80") {
+		t.Fatalf("IPv6地址在key中应加上[]：%s", key)
+	}
+}
+
+func TestCanonicalNodeKey(t *testing.T) {
+	storage := &Etcd{KeyPrefix: "/tsing"}
+	prefix := "/tsing/prod/nodes/" + global.EncodeKey("orders") + "/"
+	cases := map[string]string{
+		"fe80::1:80":            "[fe80::1]:80", // 旧格式的IPv6地址未加[]
+		"FE80::0001:80":         "[fe80::1]:80",
+		"[FE80::0001]:80":       "[fe80::1]:80",
+		"::ffff:10.0.0.1:80":    "10.0.0.1:80",
+		"Node-1.Example.com:80": "node-1.example.com:80",
+	}
+	for legacy, canonical := range cases {
+		newKey, ok := storage.canonicalNodeKey(prefix + global.EncodeKey(legacy))
+		if !ok || newKey != prefix+global.EncodeKey(canonical) {
+			t.Fatalf("%s应迁移为%s，实际为%s %v", legacy, canonical, newKey, ok)
+		}
+	}
+
+	for _, key := range []string{
+		prefix + global.EncodeKey("10.0.0.1:80"),     // 已是规范格式
+		prefix + global.EncodeKey("[fe80::1]:80"),    // 已迁移
+		prefix + global.EncodeKey("fe80::1%eth0:80"), // 无法解析的key在加载时报错
+		prefix + global.EncodeKey("10.0.0.1:0"),
+		"/tsing/prod/services/" + global.EncodeKey("orders"),
+		"/tsing/acl/tokens/abc",
+	} {
+		if newKey, ok := storage.canonicalNodeKey(key); ok {
+			t.Fatalf("%s不应迁移，实际迁移为%s", key, newKey)
+		}
+	}
+}
@@ -1,11 +1,7 @@
 package etcd
 
 import (
-	"context"
 	"errors"
-	"path"
-	"strings"
-	"time"
 
 	"local/engine"
 	"local/global"
@@ -14,63 +10,73 @@ import (
 )
 
 // 从存储器加载服务到本地，如果不存在则创建
-func (self *Etcd) LoadService(data []byte) (err error) {
+func (self *Etcd) LoadService(key string, data []byte, revision int64) (err error) {
+	namespace, kind, _, ok := self.parseKey(key)
+	if !ok || kind != kindServices {
+		err = errors.New("解析服务的命名空间失败")
+		log.Err(err).Str("key", key).Caller().Send()
+		return
+	}
 	var service global.ServiceConfig
 	if err = service.UnmarshalJSON(data); err != nil {
 		log.Err(err).Caller().Send()
 		return
 	}
+	service.Namespace = namespace
+	service.Revision = revision
 	return engine.SetService(service)
 }
 
 // 将本地服务数据保存到存储器中，如果不存在则创建
 func (self *Etcd) SaveService(config global.ServiceConfig) (err error) {
-	var configBytes []byte
-	configBytes, err = config.MarshalJSON()
-	if err != nil {
-		log.Err(err).Caller().Send()
-		return
-	}
+	_, err = self.SaveServiceCAS(config, global.AnyRevision)
+	return
+}
 
-	var key strings.Builder
-	key.WriteString(self.KeyPrefix)
-	key.WriteString("/services/")
-	key.WriteString(global.EncodeKey(config.ServiceID))
-	ctx, ctxCancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer ctxCancel()
-	if _, err = self.client.Put(ctx, key.String(), global.BytesToStr(configBytes)); err != nil {
+// 比较修订版本号后将服务数据保存到存储器中，返回写入后的修订版本号
+func (self *Etcd) SaveServiceCAS(config global.ServiceConfig, revision int64) (int64, error) {
+	configBytes, err := config.MarshalJSON()
+	if err != nil {
 		log.Err(err).Caller().Send()
-		return err
+		return 0, err
 	}
-	return nil
+	return self.putCAS(self.serviceKey(config.Namespace, global.EncodeKey(config.ServiceID)), global.BytesToStr(configBytes), revision)
 }
 
 // 删除本地服务数据
 func (self *Etcd) DeleteLocalService(key string) error {
-	serviceID, err := global.DecodeKey(path.Base(key))
+	namespace, kind, rest, ok := self.parseKey(key)
+	if !ok || kind != kindServices {
+		err := errors.New("解析服务的命名空间失败")
+		log.Err(err).Str("key", key).Caller().Send()
+		return err
+	}
+	serviceID, err := global.DecodeKey(rest)
 	if err != nil {
 		log.Err(err).Caller().Send()
 		return err
 	}
-	return engine.DelService(serviceID)
+	return engine.DelService(namespace, serviceID)
 }
 
 // 删除存储器中服务数据
-func (self *Etcd) DeleteStorageService(serviceID string) error {
+func (self *Etcd) DeleteStorageService(namespace, serviceID string) error {
+	return self.DeleteStorageServiceCAS(namespace, serviceID, global.AnyRevision)
+}
+
+// 比较修订版本号后删除存储器中服务数据
+func (self *Etcd) DeleteStorageServiceCAS(namespace, serviceID string, revision int64) error {
 	if serviceID == "" {
 		return errors.New("服务ID不能为空")
 	}
+	return self.deleteCAS(self.serviceKey(namespace, serviceID), revision)
+}
 
-	var key strings.Builder
-	key.WriteString(self.KeyPrefix)
-	key.WriteString("/services/")
-	key.WriteString(serviceID)
-	ctx, ctxCancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer ctxCancel()
-	_, err := self.client.Delete(ctx, key.String())
-	if err != nil {
-		log.Err(err).Caller().Send()
-		return err
+// 生成服务在存储器中的key，入参为命名空间及编码后的服务ID
+// key=prefix/namespace/services/base64(serviceID)
+func (self *Etcd) serviceKey(namespace, serviceID string) string {
+	if namespace == "" {
+		namespace = global.DefaultNamespace
 	}
-	return nil
+	return self.namespaceKey(namespace, kindServices) + serviceID
 }
@@ -3,44 +3,78 @@ package etcd
 import (
 	"context"
 	"strings"
+	"sync"
 
 	"github.com/coreos/etcd/clientv3"
 	"github.com/rs/zerolog/log"
 
 	"local/global"
+	"local/health"
+	"local/metrics"
 )
 
-// 监听变更
-func (self *Etcd) Watch() {
-	ch := self.client.Watch(context.Background(), self.KeyPrefix+"/", clientv3.WithPrefix())
+// 监听变更，直到上下文被取消
+// 只监听服务、节点及ACL令牌所在的key范围，审计记录、集群成员及领导者选举的变更不会推送到各实例
+func (self *Etcd) Watch(ctx context.Context) {
+	health.WatchStarted()
+	defer health.WatchStopped()
+	var wg sync.WaitGroup
+	for _, r := range self.dataRanges() {
+		wg.Add(1)
+		go func(r [2]string) {
+			defer wg.Done()
+			self.watchRange(ctx, r[0], r[1])
+		}(r)
+	}
+	wg.Wait()
+}
+
+// 监听一个key范围的变更，直到上下文被取消
+func (self *Etcd) watchRange(ctx context.Context, key, end string) {
+	ch := self.client.Watch(ctx, key, clientv3.WithRange(end), clientv3.WithCreatedNotify())
 	for resp := range ch {
+		if resp.Canceled && ctx.Err() != nil {
+			break
+		}
+		if err := resp.Err(); err != nil {
+			log.Err(err).Caller().Msg("监听中断")
+			continue
+		}
 		for _, event := range resp.Events {
 			switch event.Type {
 			// 更新事件
 			case clientv3.EventTypePut:
-				if err := self.watchLoadData(event.Kv.Key, event.Kv.Value); err != nil {
+				err := self.watchLoadData(event.Kv.Key, event.Kv.Value, event.Kv.ModRevision)
+				if err != nil {
 					log.Err(err).Caller().Send()
 				}
+				metrics.ObserveWatchEvent("put", err)
 			// 删除事件
 			case clientv3.EventTypeDelete:
-				if err := self.watchDeleteData(event.Kv.Key); err != nil {
+				err := self.watchDeleteData(event.Kv.Key)
+				if err != nil {
 					log.Err(err).Caller().Send()
 				}
+				metrics.ObserveWatchEvent("delete", err)
 			}
 		}
+		health.WatchProgress(resp.Header.Revision)
 	}
 }
 
 // 监听存储器数据更新，同步本地数据
-func (self *Etcd) watchLoadData(key, value []byte) error {
+func (self *Etcd) watchLoadData(key, value []byte, revision int64) error {
 	keyStr := global.BytesToStr(key)
-	// 加载服务
-	if strings.HasPrefix(keyStr, self.KeyPrefix+"/services/") {
-		return self.LoadService(value)
+	// 加载服务及节点
+	if _, kind, _, ok := self.parseKey(keyStr); ok {
+		if kind == kindServices {
+			return self.LoadService(keyStr, value, revision)
+		}
+		return self.LoadNode(keyStr, value, revision)
 	}
-	// 加载节点
-	if strings.HasPrefix(keyStr, self.KeyPrefix+"/nodes/") {
-		return self.LoadNode(keyStr, value)
+	// 加载ACL令牌
+	if strings.HasPrefix(keyStr, self.KeyPrefix+"/acl/tokens/") {
+		return self.LoadToken(value)
 	}
 	return nil
 }
@@ -48,11 +82,14 @@ func (self *Etcd) watchLoadData(key, value []byte) error {
 // 监听存储器数据删除，同步本地数据
 func (self *Etcd) watchDeleteData(key []byte) error {
 	keyStr := global.BytesToStr(key)
-	if strings.HasPrefix(keyStr, self.KeyPrefix+"/services/") {
-		return self.DeleteLocalService(keyStr)
-	}
-	if strings.HasPrefix(keyStr, self.KeyPrefix+"/nodes/") {
+	if _, kind, _, ok := self.parseKey(keyStr); ok {
+		if kind == kindServices {
+			return self.DeleteLocalService(keyStr)
+		}
 		return self.DeleteLocalNode(keyStr)
 	}
+	if strings.HasPrefix(keyStr, self.KeyPrefix+"/acl/tokens/") {
+		return self.DeleteLocalToken(keyStr)
+	}
 	return nil
 }
@@ -0,0 +1,252 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"local/global"
+	"local/metrics"
+	"local/tracing"
+)
+
+// 记录每个操作的耗时、错误及追踪span的存储器
+type instrumented struct {
+	storage global.StorageType
+	ctx     context.Context // 操作所属的上下文，其中的span作为存储器操作的父span
+}
+
+// 包装存储器，记录每个操作的耗时、错误及追踪span
+func Instrument(storage global.StorageType) global.StorageType {
+	return &instrumented{storage: storage, ctx: context.Background()}
+}
+
+// 返回绑定了上下文的存储器，存储器操作的span作为上下文中span的子span
+// 未经Instrument包装的存储器原样返回
+func WithContext(storage global.StorageType, ctx context.Context) global.StorageType {
+	if s, ok := storage.(*instrumented); ok {
+		return &instrumented{storage: s.storage, ctx: ctx}
+	}
+	return storage
+}
+
+// 获取存储器的选举功能，存储器不支持选举时返回false
+func Elector(storage global.StorageType) (global.Elector, bool) {
+	if s, ok := storage.(*instrumented); ok {
+		storage = s.storage
+	}
+	elector, ok := storage.(global.Elector)
+	return elector, ok
+}
+
+// 开始记录操作，返回结束记录的函数
+func (self *instrumented) observe(operation string) func(error) {
+	start := time.Now()
+	_, span := tracing.Start(self.ctx, "storage."+operation)
+	return func(err error) {
+		metrics.ObserveStorage(operation, start, err)
+		span.SetError(err)
+		span.End()
+	}
+}
+
+func (self *instrumented) LoadAll() error {
+	done := self.observe("LoadAll")
+	err := self.storage.LoadAll()
+	done(err)
+	return err
+}
+
+func (self *instrumented) SaveAll() error {
+	done := self.observe("SaveAll")
+	err := self.storage.SaveAll()
+	done(err)
+	return err
+}
+
+func (self *instrumented) LoadService(key string, data []byte, revision int64) error {
+	done := self.observe("LoadService")
+	err := self.storage.LoadService(key, data, revision)
+	done(err)
+	return err
+}
+
+func (self *instrumented) SaveService(config global.ServiceConfig) error {
+	done := self.observe("SaveService")
+	err := self.storage.SaveService(config)
+	done(err)
+	return err
+}
+
+func (self *instrumented) SaveServiceCAS(config global.ServiceConfig, revision int64) (int64, error) {
+	done := self.observe("SaveServiceCAS")
+	result, err := self.storage.SaveServiceCAS(config, revision)
+	done(err)
+	return result, err
+}
+
+func (self *instrumented) DeleteLocalService(key string) error {
+	done := self.observe("DeleteLocalService")
+	err := self.storage.DeleteLocalService(key)
+	done(err)
+	return err
+}
+
+func (self *instrumented) DeleteStorageService(namespace, serviceID string) error {
+	done := self.observe("DeleteStorageService")
+	err := self.storage.DeleteStorageService(namespace, serviceID)
+	done(err)
+	return err
+}
+
+func (self *instrumented) DeleteStorageServiceCAS(namespace, serviceID string, revision int64) error {
+	done := self.observe("DeleteStorageServiceCAS")
+	err := self.storage.DeleteStorageServiceCAS(namespace, serviceID, revision)
+	done(err)
+	return err
+}
+
+func (self *instrumented) LoadNode(key string, data []byte, revision int64) error {
+	done := self.observe("LoadNode")
+	err := self.storage.LoadNode(key, data, revision)
+	done(err)
+	return err
+}
+
+func (self *instrumented) SaveNode(namespace, serviceID string, node global.Node) error {
+	done := self.observe("SaveNode")
+	err := self.storage.SaveNode(namespace, serviceID, node)
+	done(err)
+	return err
+}
+
+func (self *instrumented) SaveNodeCAS(namespace, serviceID string, node global.Node, revision int64) (int64, error) {
+	done := self.observe("SaveNodeCAS")
+	result, err := self.storage.SaveNodeCAS(namespace, serviceID, node, revision)
+	done(err)
+	return result, err
+}
+
+func (self *instrumented) DeleteLocalNode(key string) error {
+	done := self.observe("DeleteLocalNode")
+	err := self.storage.DeleteLocalNode(key)
+	done(err)
+	return err
+}
+
+func (self *instrumented) DeleteStorageNode(namespace, serviceID, ip string, port uint16) error {
+	done := self.observe("DeleteStorageNode")
+	err := self.storage.DeleteStorageNode(namespace, serviceID, ip, port)
+	done(err)
+	return err
+}
+
+func (self *instrumented) DeleteStorageNodeCAS(namespace, serviceID, ip string, port uint16, revision int64) error {
+	done := self.observe("DeleteStorageNodeCAS")
+	err := self.storage.DeleteStorageNodeCAS(namespace, serviceID, ip, port, revision)
+	done(err)
+	return err
+}
+
+func (self *instrumented) BatchNodes(operations []global.NodeOperation) []error {
+	done := self.observe("BatchNodes")
+	errs := self.storage.BatchNodes(operations)
+	var err error
+	for k := range errs {
+		if errs[k] != nil {
+			err = errs[k]
+			break
+		}
+	}
+	done(err)
+	return errs
+}
+
+func (self *instrumented) LoadToken(data []byte) error {
+	done := self.observe("LoadToken")
+	err := self.storage.LoadToken(data)
+	done(err)
+	return err
+}
+
+func (self *instrumented) SaveToken(token global.ACLToken) error {
+	done := self.observe("SaveToken")
+	err := self.storage.SaveToken(token)
+	done(err)
+	return err
+}
+
+func (self *instrumented) DeleteLocalToken(key string) error {
+	done := self.observe("DeleteLocalToken")
+	err := self.storage.DeleteLocalToken(key)
+	done(err)
+	return err
+}
+
+func (self *instrumented) DeleteStorageToken(secretHash string) error {
+	done := self.observe("DeleteStorageToken")
+	err := self.storage.DeleteStorageToken(secretHash)
+	done(err)
+	return err
+}
+
+func (self *instrumented) WriteAudit(timestamp int64, data []byte) error {
+	done := self.observe("WriteAudit")
+	err := self.storage.WriteAudit(timestamp, data)
+	done(err)
+	return err
+}
+
+func (self *instrumented) ReadAudit(since int64, cursor string, limit int) ([][]byte, string, error) {
+	done := self.observe("ReadAudit")
+	list, next, err := self.storage.ReadAudit(since, cursor, limit)
+	done(err)
+	return list, next, err
+}
+
+func (self *instrumented) Clean(namespace, serviceID string, nodes []global.Node) error {
+	done := self.observe("Clean")
+	err := self.storage.Clean(namespace, serviceID, nodes)
+	done(err)
+	return err
+}
+
+func (self *instrumented) Ping(ctx context.Context) (int64, error) {
+	done := self.observe("Ping")
+	revision, err := self.storage.Ping(ctx)
+	done(err)
+	return revision, err
+}
+
+func (self *instrumented) MemberID() string {
+	return self.storage.MemberID()
+}
+
+func (self *instrumented) SaveMember(member global.Member, ttl int64) error {
+	done := self.observe("SaveMember")
+	err := self.storage.SaveMember(member, ttl)
+	done(err)
+	return err
+}
+
+func (self *instrumented) DeleteMember() error {
+	done := self.observe("DeleteMember")
+	err := self.storage.DeleteMember()
+	done(err)
+	return err
+}
+
+func (self *instrumented) Members() ([]global.Member, error) {
+	done := self.observe("Members")
+	members, err := self.storage.Members()
+	done(err)
+	return members, err
+}
+
+// 监听会一直阻塞，不记录耗时
+func (self *instrumented) Watch(ctx context.Context) {
+	self.storage.Watch(ctx)
+}
+
+func (self *instrumented) Close() error {
+	return self.storage.Close()
+}
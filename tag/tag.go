@@ -0,0 +1,195 @@
+package tag
+
+import (
+	"errors"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// 单个服务或节点最多允许的标签数
+const MaxTags = 32
+
+// 标签的格式，只允许字母、数字及._-，不包含分隔符,
+var pattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,62}$`)
+
+// 匹配方式
+const (
+	ModeAll = "all" // 包含所有标签
+	ModeAny = "any" // 包含任一标签
+)
+
+// 校验标签，返回去重并排序后的标签，没有标签时返回nil
+func Normalize(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+	if len(tags) > MaxTags {
+		return nil, errors.New("标签数量不能超过32个")
+	}
+	set := make(map[string]struct{}, len(tags))
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if !pattern.MatchString(tag) {
+			return nil, errors.New("标签" + tag + "无效，只允许字母、数字及._-，不能超过63个字符")
+		}
+		if _, exist := set[tag]; exist {
+			continue
+		}
+		set[tag] = struct{}{}
+		result = append(result, tag)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// 将以,分隔的标签字符串解析成标签列表，空字符串返回nil
+func Split(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// 标签过滤条件
+type Filter struct {
+	Tags []string // 为空表示不过滤
+	Any  bool     // 为true时包含任一标签即匹配，否则须包含所有标签
+}
+
+// 解析过滤条件，values为tag参数的值(可重复传入，每个值可以用,分隔多个标签)，mode为all或any，为空时为all
+func ParseFilter(values []string, mode string) (filter Filter, err error) {
+	var tags []string
+	for k := range values {
+		tags = append(tags, Split(values[k])...)
+	}
+	if filter.Tags, err = Normalize(tags); err != nil {
+		return
+	}
+	switch mode {
+	case "", ModeAll:
+	case ModeAny:
+		filter.Any = true
+	default:
+		err = errors.New("tag_mode参数只支持all|any")
+	}
+	return
+}
+
+// 是否需要过滤
+func (self Filter) Empty() bool {
+	return len(self.Tags) == 0
+}
+
+// 判断标签列表是否满足过滤条件
+func (self Filter) Match(tags []string) bool {
+	if self.Empty() {
+		return true
+	}
+	for _, want := range self.Tags {
+		found := false
+		for k := range tags {
+			if tags[k] == want {
+				found = true
+				break
+			}
+		}
+		if found && self.Any {
+			return true
+		}
+		if !found && !self.Any {
+			return false
+		}
+	}
+	return !self.Any
+}
+
+// 标签的倒排索引，key为服务或节点的标识
+type Index struct {
+	mutex sync.RWMutex
+	byTag map[string]map[string]struct{} // key=标签, value=带有该标签的key
+	byKey map[string][]string            // key=服务或节点的标识, value=标签
+}
+
+func NewIndex() *Index {
+	return &Index{
+		byTag: make(map[string]map[string]struct{}),
+		byKey: make(map[string][]string),
+	}
+}
+
+// 设置key的标签，替换之前的标签，tags为空时相当于删除
+func (self *Index) Set(key string, tags []string) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	self.remove(key)
+	if len(tags) == 0 {
+		return
+	}
+	self.byKey[key] = tags
+	for _, tag := range tags {
+		keys, exist := self.byTag[tag]
+		if !exist {
+			keys = make(map[string]struct{})
+			self.byTag[tag] = keys
+		}
+		keys[key] = struct{}{}
+	}
+}
+
+// 删除key的标签
+func (self *Index) Delete(key string) {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	self.remove(key)
+}
+
+// 清空所有key的标签
+func (self *Index) Reset() {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	self.byTag = make(map[string]map[string]struct{})
+	self.byKey = make(map[string][]string)
+}
+
+func (self *Index) remove(key string) {
+	for _, tag := range self.byKey[key] {
+		delete(self.byTag[tag], key)
+		if len(self.byTag[tag]) == 0 {
+			delete(self.byTag, tag)
+		}
+	}
+	delete(self.byKey, key)
+}
+
+// 获取满足过滤条件的所有key，过滤条件为空时返回nil
+func (self *Index) Match(filter Filter) map[string]struct{} {
+	if filter.Empty() {
+		return nil
+	}
+	self.mutex.RLock()
+	defer self.mutex.RUnlock()
+	result := make(map[string]struct{})
+	if filter.Any {
+		for _, tag := range filter.Tags {
+			for key := range self.byTag[tag] {
+				result[key] = struct{}{}
+			}
+		}
+		return result
+	}
+	// 从带有该标签的key最少的标签开始求交集
+	smallest := self.byTag[filter.Tags[0]]
+	for _, tag := range filter.Tags[1:] {
+		if len(self.byTag[tag]) < len(smallest) {
+			smallest = self.byTag[tag]
+		}
+	}
+	for key := range smallest {
+		if filter.Match(self.byKey[key]) {
+			result[key] = struct{}{}
+		}
+	}
+	return result
+}
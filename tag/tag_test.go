@@ -0,0 +1,92 @@
+package tag
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tags, err := Normalize([]string{"grpc", "canary", "grpc"})
+	if err != nil || !reflect.DeepEqual(tags, []string{"canary", "grpc"}) {
+		t.Fatalf("应去重并排序：%v %v", tags, err)
+	}
+	for _, invalid := range []string{"", "a,b", "-a", "a b"} {
+		if _, err = Normalize([]string{invalid}); err == nil {
+			t.Fatalf("标签%q应无效", invalid)
+		}
+	}
+	if tags, err = Normalize(nil); tags != nil || err != nil {
+		t.Fatal("没有标签时应返回nil")
+	}
+}
+
+func TestParseFilter(t *testing.T) {
+	filter, err := ParseFilter([]string{"grpc,canary", "v2"}, "")
+	if err != nil || filter.Any || !reflect.DeepEqual(filter.Tags, []string{"canary", "grpc", "v2"}) {
+		t.Fatalf("应合并多个tag参数：%+v %v", filter, err)
+	}
+	if filter, err = ParseFilter([]string{"grpc"}, ModeAny); err != nil || !filter.Any {
+		t.Fatal("mode为any时应匹配任一标签")
+	}
+	if _, err = ParseFilter([]string{"grpc"}, "none"); err == nil {
+		t.Fatal("不支持的mode应返回错误")
+	}
+	if filter, err = ParseFilter(nil, ""); err != nil || !filter.Empty() {
+		t.Fatal("未传入标签时不过滤")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	all := Filter{Tags: []string{"canary", "grpc"}}
+	any := Filter{Tags: []string{"canary", "grpc"}, Any: true}
+	cases := []struct {
+		tags     []string
+		all, any bool
+	}{
+		{[]string{"canary", "grpc", "v2"}, true, true},
+		{[]string{"grpc"}, false, true},
+		{nil, false, false},
+	}
+	for _, c := range cases {
+		if all.Match(c.tags) != c.all || any.Match(c.tags) != c.any {
+			t.Fatalf("%v的匹配结果错误", c.tags)
+		}
+	}
+	if !(Filter{}).Match(nil) {
+		t.Fatal("空的过滤条件应匹配所有")
+	}
+}
+
+func TestIndex(t *testing.T) {
+	index := NewIndex()
+	index.Set("a", []string{"canary", "grpc"})
+	index.Set("b", []string{"grpc"})
+	index.Set("c", []string{"http"})
+
+	if keys := index.Match(Filter{Tags: []string{"canary", "grpc"}}); !reflect.DeepEqual(keys, map[string]struct{}{"a": {}}) {
+		t.Fatalf("all应求交集：%v", keys)
+	}
+	if keys := index.Match(Filter{Tags: []string{"canary", "http"}, Any: true}); len(keys) != 2 {
+		t.Fatalf("any应求并集：%v", keys)
+	}
+	if index.Match(Filter{}) != nil {
+		t.Fatal("空的过滤条件应返回nil")
+	}
+
+	// 替换及删除标签
+	index.Set("a", []string{"http"})
+	if keys := index.Match(Filter{Tags: []string{"canary"}}); len(keys) != 0 {
+		t.Fatalf("替换后不应匹配旧标签：%v", keys)
+	}
+	index.Delete("c")
+	if keys := index.Match(Filter{Tags: []string{"http"}}); !reflect.DeepEqual(keys, map[string]struct{}{"a": {}}) {
+		t.Fatalf("删除后不应匹配：%v", keys)
+	}
+	if len(index.byTag["canary"]) != 0 || len(index.byKey) != 2 {
+		t.Fatal("不再使用的标签应从索引中删除")
+	}
+	index.Reset()
+	if keys := index.Match(Filter{Tags: []string{"http"}}); len(keys) != 0 || len(index.byTag) != 0 {
+		t.Fatalf("清空后不应匹配任何key：%v", keys)
+	}
+}
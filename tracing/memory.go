@@ -0,0 +1,39 @@
+package tracing
+
+import "sync"
+
+// 将span保存在内存中的导出器，用于测试
+type MemoryExporter struct {
+	mutex sync.Mutex
+	spans []SpanData
+}
+
+func NewMemoryExporter() *MemoryExporter {
+	return &MemoryExporter{}
+}
+
+func (self *MemoryExporter) Export(span SpanData) {
+	self.mutex.Lock()
+	self.spans = append(self.spans, span)
+	self.mutex.Unlock()
+}
+
+// 获取已输出的span，按结束时间排序
+func (self *MemoryExporter) Spans() []SpanData {
+	self.mutex.Lock()
+	defer self.mutex.Unlock()
+	spans := make([]SpanData, len(self.spans))
+	copy(spans, self.spans)
+	return spans
+}
+
+// 清空已输出的span
+func (self *MemoryExporter) Reset() {
+	self.mutex.Lock()
+	self.spans = nil
+	self.mutex.Unlock()
+}
+
+func (self *MemoryExporter) Shutdown() error {
+	return nil
+}
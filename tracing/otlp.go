@@ -0,0 +1,209 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	otlpBatchSize = 512  // 缓存的span达到该数量时立即发送
+	otlpMaxQueue  = 4096 // 缓存的span的最大数量，超过时丢弃新的span，避免收集器不可用时占用过多内存
+)
+
+// 以OTLP/HTTP(JSON编码)协议发送span的导出器
+type OTLPExporter struct {
+	endpoint    string
+	serviceName string
+	client      *http.Client
+	mutex       sync.Mutex
+	queue       []SpanData
+	dropped     int
+	flush       chan struct{}
+	done        chan struct{}
+	closeOnce   sync.Once
+	wg          sync.WaitGroup
+}
+
+// 新建OTLP导出器，endpoint为收集器的地址(例如http://127.0.0.1:4318/v1/traces)，每隔interval批量发送一次
+func NewOTLPExporter(endpoint, serviceName string, interval time.Duration) *OTLPExporter {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	exporter := &OTLPExporter{
+		endpoint:    endpoint,
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		flush:       make(chan struct{}, 1),
+		done:        make(chan struct{}),
+	}
+	exporter.wg.Add(1)
+	go exporter.loop(interval)
+	return exporter
+}
+
+func (self *OTLPExporter) Export(span SpanData) {
+	self.mutex.Lock()
+	if len(self.queue) >= otlpMaxQueue {
+		self.dropped++
+		self.mutex.Unlock()
+		return
+	}
+	self.queue = append(self.queue, span)
+	full := len(self.queue) >= otlpBatchSize
+	self.mutex.Unlock()
+	if full {
+		select {
+		case self.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// 定时或缓存满时发送
+func (self *OTLPExporter) loop(interval time.Duration) {
+	defer self.wg.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-self.flush:
+		case <-self.done:
+			return
+		}
+		if err := self.send(); err != nil {
+			log.Err(err).Caller().Msg("发送追踪数据失败")
+		}
+	}
+}
+
+// 发送缓存中的span，发送失败的span被丢弃
+func (self *OTLPExporter) send() error {
+	self.mutex.Lock()
+	spans := self.queue
+	dropped := self.dropped
+	self.queue = nil
+	self.dropped = 0
+	self.mutex.Unlock()
+	if dropped > 0 {
+		log.Warn().Int("dropped", dropped).Msg("追踪数据的缓存已满，丢弃了部分span")
+	}
+	if len(spans) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(otlpRequest(self.serviceName, spans))
+	if err != nil {
+		return err
+	}
+	resp, err := self.client.Post(self.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if err = resp.Body.Close(); err != nil {
+		log.Err(err).Caller().Send()
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New("收集器响应了状态码" + strconv.Itoa(resp.StatusCode))
+	}
+	return nil
+}
+
+// 停止定时发送，并发送缓存中的span
+func (self *OTLPExporter) Shutdown() error {
+	self.closeOnce.Do(func() {
+		close(self.done)
+	})
+	self.wg.Wait()
+	return self.send()
+}
+
+// OTLP JSON编码中的键值对
+type otlpKeyValue struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	} `json:"status"`
+}
+
+// OTLP的span类型
+const (
+	otlpKindInternal = 1
+	otlpKindServer   = 2
+)
+
+// OTLP的状态码
+const otlpStatusError = 2
+
+func otlpAttribute(key, value string) (kv otlpKeyValue) {
+	kv.Key = key
+	kv.Value.StringValue = value
+	return
+}
+
+// 生成OTLP/HTTP的请求体
+func otlpRequest(serviceName string, spans []SpanData) interface{} {
+	list := make([]otlpSpan, len(spans))
+	for k := range spans {
+		span := &list[k]
+		span.TraceID = spans[k].TraceID.String()
+		span.SpanID = spans[k].SpanID.String()
+		if spans[k].ParentSpanID.IsValid() {
+			span.ParentSpanID = spans[k].ParentSpanID.String()
+		}
+		span.Name = spans[k].Name
+		span.Kind = otlpKindInternal
+		if spans[k].Server {
+			span.Kind = otlpKindServer
+		}
+		span.StartTimeUnixNano = strconv.FormatInt(spans[k].Start.UnixNano(), 10)
+		span.EndTimeUnixNano = strconv.FormatInt(spans[k].End.UnixNano(), 10)
+		for _, attr := range spans[k].Attributes {
+			span.Attributes = append(span.Attributes, otlpAttribute(attr.Key, attr.Value))
+		}
+		if spans[k].Error != "" {
+			span.Status.Code = otlpStatusError
+			span.Status.Message = spans[k].Error
+		}
+	}
+	type scopeSpans struct {
+		Scope struct {
+			Name string `json:"name"`
+		} `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	type resourceSpans struct {
+		Resource struct {
+			Attributes []otlpKeyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeSpans []scopeSpans `json:"scopeSpans"`
+	}
+	var rs resourceSpans
+	rs.Resource.Attributes = []otlpKeyValue{otlpAttribute("service.name", serviceName)}
+	var ss scopeSpans
+	ss.Scope.Name = "tsing-center"
+	ss.Spans = list
+	rs.ScopeSpans = []scopeSpans{ss}
+	return map[string][]resourceSpans{"resourceSpans": {rs}}
+}
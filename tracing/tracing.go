@@ -0,0 +1,286 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 追踪ID
+type TraceID [16]byte
+
+func (self TraceID) IsValid() bool {
+	return self != TraceID{}
+}
+
+func (self TraceID) String() string {
+	return hex.EncodeToString(self[:])
+}
+
+// span ID
+type SpanID [8]byte
+
+func (self SpanID) IsValid() bool {
+	return self != SpanID{}
+}
+
+func (self SpanID) String() string {
+	return hex.EncodeToString(self[:])
+}
+
+// 在服务之间传递的span信息
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+func (self SpanContext) IsValid() bool {
+	return self.TraceID.IsValid() && self.SpanID.IsValid()
+}
+
+// 解析W3C Trace Context的traceparent头信息，格式为version-traceID-spanID-flags
+func ParseTraceParent(value string) (sc SpanContext, ok bool) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+	// 版本00只有4个部分，更高的版本允许在后面追加
+	if parts[0] == "00" && len(parts) != 4 {
+		return sc, false
+	}
+	var flags [1]byte
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, false
+	}
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return sc, false
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, sc.IsValid()
+}
+
+// 生成traceparent头信息
+func (self SpanContext) TraceParent() string {
+	flags := "00"
+	if self.Sampled {
+		flags = "01"
+	}
+	return "00-" + self.TraceID.String() + "-" + self.SpanID.String() + "-" + flags
+}
+
+// span的属性
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// 已结束的span，交给导出器输出
+type SpanData struct {
+	Name         string
+	TraceID      TraceID
+	SpanID       SpanID
+	ParentSpanID SpanID // 为空表示根span
+	Server       bool   // 是否为处理请求的服务端span
+	Start        time.Time
+	End          time.Time
+	Attributes   []Attribute
+	Error        string // 错误信息，为空表示成功
+}
+
+// span，nil值的span可以安全调用所有方法，用于未启用追踪时
+type Span struct {
+	mutex    sync.Mutex
+	data     SpanData
+	sampled  bool
+	ended    bool
+	exporter Exporter
+}
+
+// 获取用于传递的span信息
+func (self *Span) Context() SpanContext {
+	if self == nil {
+		return SpanContext{}
+	}
+	return SpanContext{TraceID: self.data.TraceID, SpanID: self.data.SpanID, Sampled: self.sampled}
+}
+
+// 修改span的名称，例如在匹配到路由之后
+func (self *Span) SetName(name string) {
+	if self == nil {
+		return
+	}
+	self.mutex.Lock()
+	self.data.Name = name
+	self.mutex.Unlock()
+}
+
+// 设置span的属性
+func (self *Span) SetAttribute(key, value string) {
+	if self == nil || !self.sampled {
+		return
+	}
+	self.mutex.Lock()
+	self.data.Attributes = append(self.data.Attributes, Attribute{Key: key, Value: value})
+	self.mutex.Unlock()
+}
+
+// 记录span的错误，err为nil时忽略
+func (self *Span) SetError(err error) {
+	if self == nil || err == nil {
+		return
+	}
+	self.mutex.Lock()
+	self.data.Error = err.Error()
+	self.mutex.Unlock()
+}
+
+// 结束span，采样的span交给导出器输出，重复调用时忽略
+func (self *Span) End() {
+	if self == nil {
+		return
+	}
+	self.mutex.Lock()
+	if self.ended {
+		self.mutex.Unlock()
+		return
+	}
+	self.ended = true
+	self.data.End = time.Now()
+	data := self.data
+	self.mutex.Unlock()
+	if self.sampled {
+		self.exporter.Export(data)
+	}
+}
+
+// span的导出器
+type Exporter interface {
+	Export(SpanData) // 输出已结束的span，不能阻塞
+	Shutdown() error // 输出缓存中的span后关闭
+}
+
+// 当前的导出器及采样率
+var tracer struct {
+	sync.RWMutex
+	exporter    Exporter
+	sampleRatio float64
+}
+
+// 设置导出器及根span的采样率(0~1)，exporter为nil表示禁用追踪，之前的导出器会被关闭
+func Setup(exporter Exporter, sampleRatio float64) error {
+	tracer.Lock()
+	previous := tracer.exporter
+	tracer.exporter = exporter
+	tracer.sampleRatio = sampleRatio
+	tracer.Unlock()
+	if previous != nil && previous != exporter {
+		return previous.Shutdown()
+	}
+	return nil
+}
+
+// 关闭当前的导出器
+func Shutdown() error {
+	return Setup(nil, 0)
+}
+
+// 上下文中保存span的key
+type spanKey struct{}
+
+// 上下文中保存远程span信息的key
+type remoteKey struct{}
+
+// 将span写入上下文
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// 获取上下文中的span
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// 将从请求中解析出的远程span信息写入上下文，作为之后创建的span的父span
+func ContextWithRemote(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, sc)
+}
+
+// 创建span，上下文中有span时作为其子span并沿用其采样结果，否则按采样率决定是否采样
+// 未启用追踪时返回nil
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	return start(ctx, name, false)
+}
+
+// 创建处理请求的服务端span
+func StartServer(ctx context.Context, name string) (context.Context, *Span) {
+	return start(ctx, name, true)
+}
+
+func start(ctx context.Context, name string, server bool) (context.Context, *Span) {
+	tracer.RLock()
+	exporter := tracer.exporter
+	sampleRatio := tracer.sampleRatio
+	tracer.RUnlock()
+	if exporter == nil {
+		return ctx, nil
+	}
+	var parent SpanContext
+	if span := SpanFromContext(ctx); span != nil {
+		parent = span.Context()
+	} else if sc, ok := ctx.Value(remoteKey{}).(SpanContext); ok {
+		parent = sc
+	}
+	span := &Span{
+		exporter: exporter,
+		data: SpanData{
+			Name:   name,
+			Server: server,
+			Start:  time.Now(),
+		},
+	}
+	if parent.IsValid() {
+		span.data.TraceID = parent.TraceID
+		span.data.ParentSpanID = parent.SpanID
+		span.sampled = parent.Sampled
+	} else {
+		span.data.TraceID = newTraceID()
+		span.sampled = sample(span.data.TraceID, sampleRatio)
+	}
+	span.data.SpanID = newSpanID()
+	return ContextWithSpan(ctx, span), span
+}
+
+// 根据追踪ID的后8个字节决定是否采样，同一个追踪在不同的实例中得到相同的结果
+func sample(traceID TraceID, sampleRatio float64) bool {
+	if sampleRatio >= 1 {
+		return true
+	}
+	if sampleRatio <= 0 {
+		return false
+	}
+	return binary.BigEndian.Uint64(traceID[8:]) < uint64(sampleRatio*math.MaxUint64)
+}
+
+func newTraceID() (id TraceID) {
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return
+}
+
+func newSpanID() (id SpanID) {
+	for !id.IsValid() {
+		_, _ = rand.Read(id[:])
+	}
+	return
+}
@@ -0,0 +1,137 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	sc, ok := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if !ok || !sc.Sampled {
+		t.Fatal("应能解析有效的traceparent")
+	}
+	if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" {
+		t.Fatal("解析出的ID不正确")
+	}
+	if sc.TraceParent() != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Fatal("生成的traceparent不正确")
+	}
+	for _, value := range []string{
+		"",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00-4bf92f3577b34da6a3ce929d0e0e47zz-00f067aa0ba902b7-01",
+	} {
+		if _, ok = ParseTraceParent(value); ok {
+			t.Fatalf("不应解析无效的traceparent：%s", value)
+		}
+	}
+}
+
+func TestStart(t *testing.T) {
+	exporter := NewMemoryExporter()
+	if err := Setup(exporter, 1); err != nil {
+		t.Fatal(err)
+	}
+	defer Shutdown()
+
+	remote, _ := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx, root := StartServer(ContextWithRemote(context.Background(), remote), "GET /select")
+	_, child := Start(ctx, "storage.SaveNode")
+	child.SetAttribute("service_id", "demo")
+	child.SetError(errors.New("timeout"))
+	child.End()
+	root.End()
+	root.End()
+
+	spans := exporter.Spans()
+	if len(spans) != 2 {
+		t.Fatalf("应输出2个span，实际为%d个", len(spans))
+	}
+	if spans[0].TraceID != remote.TraceID || spans[1].TraceID != remote.TraceID {
+		t.Fatal("应沿用请求中的追踪ID")
+	}
+	if spans[1].ParentSpanID != remote.SpanID || !spans[1].Server {
+		t.Fatal("服务端span的父span应为请求中的span")
+	}
+	if spans[0].ParentSpanID != spans[1].SpanID {
+		t.Fatal("子span的父span不正确")
+	}
+	if spans[0].Error != "timeout" || len(spans[0].Attributes) != 1 {
+		t.Fatal("应记录span的错误及属性")
+	}
+}
+
+func TestSample(t *testing.T) {
+	exporter := NewMemoryExporter()
+	if err := Setup(exporter, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer Shutdown()
+
+	// 未采样的根span不输出，其子span沿用采样结果
+	ctx, root := Start(context.Background(), "root")
+	_, child := Start(ctx, "child")
+	child.End()
+	root.End()
+	if len(exporter.Spans()) != 0 {
+		t.Fatal("未采样的span不应输出")
+	}
+	if root.Context().TraceParent()[53:] != "00" {
+		t.Fatal("未采样的span信息应传递未采样的标记")
+	}
+
+	// 请求中标记为已采样时沿用其结果
+	remote, _ := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	_, span := Start(ContextWithRemote(context.Background(), remote), "sampled")
+	span.End()
+	if len(exporter.Spans()) != 1 {
+		t.Fatal("应沿用请求中的采样结果")
+	}
+
+	// 未启用追踪时返回nil，调用其方法不会出错
+	_ = Shutdown()
+	_, span = Start(context.Background(), "disabled")
+	span.SetAttribute("key", "value")
+	span.End()
+	if span != nil {
+		t.Fatal("未启用追踪时应返回nil")
+	}
+}
+
+func TestOTLPExporter(t *testing.T) {
+	received := make(chan map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		var data map[string]interface{}
+		if err := json.Unmarshal(body, &data); err != nil {
+			t.Error(err)
+		}
+		received <- data
+	}))
+	defer server.Close()
+
+	exporter := NewOTLPExporter(server.URL, "tsing-center", time.Hour)
+	if err := Setup(exporter, 1); err != nil {
+		t.Fatal(err)
+	}
+	_, span := StartServer(context.Background(), "GET /v1/services")
+	span.End()
+	if err := Shutdown(); err != nil {
+		t.Fatal(err)
+	}
+
+	data := <-received
+	spans := data["resourceSpans"].([]interface{})[0].(map[string]interface{})["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+	if len(spans) != 1 || spans[0].(map[string]interface{})["name"] != "GET /v1/services" {
+		t.Fatalf("收集器收到的span不正确：%v", spans)
+	}
+}